@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=simulator.mini-kube-scheduler.io
+
+// Package v1alpha1 has the API types which the simulator serves on the API server in addition to Kubernetes' ones.
+package v1alpha1
@@ -0,0 +1,105 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SchedulingResult has the scheduling result of a pod.
+// It is created in the same namespace and with the same name as the pod.
+type SchedulingResult struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SchedulingResultSpec `json:"spec"`
+}
+
+// SchedulingResultSpec is the content of SchedulingResult.
+type SchedulingResultSpec struct {
+	// PodRef refers to the pod which this result belongs to.
+	PodRef PodReference `json:"podRef"`
+
+	// FilterPlugins is the names of filter plugins in the order they ran.
+	FilterPlugins []string `json:"filterPlugins,omitempty"`
+
+	// ScorePlugins is the names of score plugins in the order they ran.
+	ScorePlugins []string `json:"scorePlugins,omitempty"`
+
+	// Nodes has the results on each node.
+	// It may have only the top-N nodes by final score, or nothing, when the results are compacted.
+	Nodes []NodeResult `json:"nodes,omitempty"`
+
+	// Summary has the filtering failures on all nodes aggregated by reason.
+	// It's recorded only when the results are compacted.
+	Summary string `json:"summary,omitempty"`
+
+	// Permit has the allows and the reject of the pod waiting in the permit phase in chronological order.
+	Permit []PermitDecision `json:"permit,omitempty"`
+}
+
+// PodReference refers to a pod.
+// The pod is identified by UID because a pod with the same name may be re-created.
+type PodReference struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid"`
+}
+
+// NodeResult has the results of plugins on a node.
+type NodeResult struct {
+	NodeName string `json:"nodeName"`
+
+	// Filter has the filtering results in the order of FilterPlugins.
+	Filter []FilterResult `json:"filter,omitempty"`
+
+	// Score has the scoring results in the order of ScorePlugins.
+	Score []ScoreResult `json:"score,omitempty"`
+}
+
+// FilterResult is the result of a filter plugin on a node.
+type FilterResult struct {
+	Plugin string `json:"plugin"`
+
+	// Passed is true when the node passed the filter plugin.
+	Passed bool `json:"passed"`
+
+	// Reason is the reason why the node is blocked by the filter plugin.
+	// It is empty when the node passed the filter plugin.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ScoreResult is the result of a score plugin on a node.
+type ScoreResult struct {
+	Plugin string `json:"plugin"`
+
+	// Score is the score that the plugin returned.
+	Score int64 `json:"score"`
+
+	// FinalScore is the score normalized and applied score plugin weight.
+	FinalScore int64 `json:"finalScore"`
+}
+
+// PermitDecision is the allow or the reject of the waiting pod by a permit plugin.
+type PermitDecision struct {
+	Plugin string `json:"plugin"`
+
+	// Allowed is true when the plugin allowed the pod, and false when it rejected the pod.
+	Allowed bool `json:"allowed"`
+
+	// Message is the reason why the plugin rejected the pod.
+	Message string `json:"message,omitempty"`
+
+	Time metav1.Time `json:"time"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SchedulingResultList is a list of SchedulingResult.
+type SchedulingResultList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []SchedulingResult `json:"items"`
+}
@@ -1,75 +1,259 @@
 package config
 
 import (
-	"errors"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"golang.org/x/xerrors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kube-scheduler/config/v1beta2"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/defaultconfig"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
+	"github.com/sanposhiho/mini-kube-scheduler/workloadcontroller"
 )
 
-// ErrEmptyEnv represents the required environment variable don't exist.
-var ErrEmptyEnv = errors.New("env is needed, but empty")
+const (
+	// defaultPort is the port of the HTTP server when it isn't configured.
+	defaultPort = 1212
+	// defaultResultTopN is the number of nodes whose results are recorded with resultstore.VerbosityTopN when it isn't configured.
+	defaultResultTopN = 10
+)
+
+// The outputs of the scheduling results.
+const (
+	// ResultOutputAnnotation records the scheduling results on the pod annotations.
+	ResultOutputAnnotation = "Annotation"
+	// ResultOutputSchedulingResult records the scheduling results as v1alpha1.SchedulingResult objects.
+	ResultOutputSchedulingResult = "SchedulingResult"
+)
 
 // Config is configuration for simulator.
 type Config struct {
-	Port        int
-	EtcdURL     string
+	Port int
+	// EtcdURL is the URL of etcd. If it's empty, the API server starts an embedded etcd.
+	EtcdURL string
+	// FrontendURL is the URL of the frontend, whose requests are allowed by CORS.
+	// If it's empty, no cross-origin requests are allowed.
 	FrontendURL string
+	// APIServerAddress is the address which the API server listens on, e.g. "127.0.0.1:6443".
+	// It must be loopback unless TokenFile is set.
+	// If it's empty, the API server listens on a random port of localhost, and only this process can access it.
+	APIServerAddress string
+	// KubeconfigPath is the path of the kubeconfig to access the API server, written when APIServerAddress is set.
+	KubeconfigPath string
+	// TokenFile is the CSV file of static tokens to access the API server.
+	// If it's set, the API server serves HTTPS, and authenticates and authorizes requests with the tokens and RBAC.
+	TokenFile string
+	// AdmissionPlugins is the names of the admission plugins enabled on the API server.
+	// If it's nil, the default admission plugins are enabled.
+	AdmissionPlugins []string
+	// FakeKubelet is whether to run the fake kubelet, which reports the nodes as Ready and makes the bound pods Running.
+	FakeKubelet bool
+	// NodeLifecycle is whether to run the node lifecycle controller, which taints the nodes whose heartbeats stop
+	// and evicts the pods on them.
+	NodeLifecycle bool
+	// ProvisionerTopologyKey is the label of the nodes which the fake provisioner uses as the topology of the volumes.
+	// If it's empty, the zone label is used.
+	ProvisionerTopologyKey string
+	// Controllers is the names of the workload controllers to run.
+	// If it's nil, all workload controllers run.
+	Controllers []string
+	// SchedulerConfig is the configuration which the scheduler starts with.
+	// It's loaded from the scheduler config file, or the default configuration if the file isn't given.
+	SchedulerConfig *v1beta2.KubeSchedulerConfiguration
+	// LogVerbosity is the verbosity of klog.
+	LogVerbosity int
+	// ResultOutput is where the scheduling results are recorded, ResultOutputAnnotation or ResultOutputSchedulingResult.
+	ResultOutput string
+	// ResultVerbosity is how much of the scheduling results are recorded.
+	ResultVerbosity resultstore.Verbosity
+	// ResultTopN is the number of nodes whose results are recorded with resultstore.VerbosityTopN.
+	ResultTopN int
 }
 
-// NewConfig gets some settings from environment variables.
-func NewConfig() (*Config, error) {
-	port, err := getPort()
-	if err != nil {
-		return nil, xerrors.Errorf("get port: %w", err)
-	}
+// NewConfig gets the settings from the command-line arguments, the environment variables and the config file.
+// The flags take precedence over the environment variables, and the environment variables over the config file.
+// args don't include the program name.
+//
+// It validates all settings and returns the error which lists every problem found.
+// It returns pflag.ErrHelp if args have -h or --help.
+func NewConfig(args []string) (*Config, error) {
+	return newConfig(args, os.LookupEnv)
+}
 
-	etcdurl, err := getEtcdURL()
-	if err != nil {
-		return nil, xerrors.Errorf("get etcd URL: %w", err)
+func newConfig(args []string, lookupEnv func(key string) (string, bool)) (*Config, error) {
+	fs, flagOpts := newFlagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, xerrors.Errorf("parse flags: %w", err)
 	}
 
-	frontendurl, err := getFrontendURL()
-	if err != nil {
-		return nil, xerrors.Errorf("get frontend URL: %w", err)
+	var errs []error
+	opts := &Options{}
+	configPath := flagOpts.config
+	if !fs.Changed(configFlag) {
+		configPath, _ = lookupEnv(configEnv)
+	}
+	if configPath != "" {
+		if err := opts.loadFile(configPath); err != nil {
+			errs = append(errs, xerrors.Errorf("load config file %s: %w", configPath, err))
+		}
 	}
+	errs = append(errs, opts.loadEnv(lookupEnv)...)
+	opts.loadFlags(fs, flagOpts)
 
-	return &Config{
-		Port:        port,
-		EtcdURL:     etcdurl,
-		FrontendURL: frontendurl,
-	}, nil
+	cfg, validateErrs := opts.complete()
+	errs = append(errs, validateErrs...)
+	if len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return cfg, nil
 }
 
-// getPort gets Port from the environment variable named PORT.
-func getPort() (int, error) {
-	p := os.Getenv("PORT")
-	if p == "" {
-		return 0, xerrors.Errorf("get PORT from env: %w", ErrEmptyEnv)
+// complete fills the unset options with the default values, and converts them to Config.
+// It returns all errors found in the options.
+func (o *Options) complete() (*Config, []error) {
+	var errs []error
+	cfg := &Config{
+		Port:                   defaultPort,
+		EtcdURL:                stringValue(o.Storage.EtcdURL),
+		FrontendURL:            stringValue(o.FrontendURL),
+		APIServerAddress:       stringValue(o.APIServer.Address),
+		KubeconfigPath:         filepath.Join(os.TempDir(), "kube-scheduler-simulator.kubeconfig"),
+		TokenFile:              stringValue(o.APIServer.TokenFile),
+		FakeKubelet:            o.Controllers.FakeKubelet != nil && *o.Controllers.FakeKubelet,
+		NodeLifecycle:          o.Controllers.NodeLifecycle != nil && *o.Controllers.NodeLifecycle,
+		ProvisionerTopologyKey: stringValue(o.Storage.ProvisionerTopologyKey),
+		ResultOutput:           ResultOutputAnnotation,
+		ResultVerbosity:        resultstore.VerbosityFull,
+		ResultTopN:             defaultResultTopN,
 	}
 
-	port, err := strconv.Atoi(p)
-	if err != nil {
-		return 0, xerrors.Errorf("convert PORT of string to int: %w", err)
+	if o.Port != nil {
+		cfg.Port = *o.Port
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs = append(errs, xerrors.Errorf("port must be between 1 and 65535, got %d", cfg.Port))
+	}
+
+	if cfg.FrontendURL != "" {
+		if u, err := url.Parse(cfg.FrontendURL); err != nil {
+			errs = append(errs, xerrors.Errorf("parse frontend URL: %w", err))
+		} else if u.Scheme == "" || u.Host == "" {
+			errs = append(errs, xerrors.Errorf("frontend URL must have the scheme and the host, got %q", cfg.FrontendURL))
+		}
+	}
+
+	if cfg.APIServerAddress != "" {
+		if host, port, err := net.SplitHostPort(cfg.APIServerAddress); err != nil {
+			errs = append(errs, xerrors.Errorf("parse API server address: %w", err))
+		} else {
+			if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 {
+				errs = append(errs, xerrors.Errorf("API server port must be between 0 and 65535, got %q", port))
+			}
+			// without the token file, the API server serves plain HTTP and allows all requests.
+			if cfg.TokenFile == "" && !isLoopback(host) {
+				errs = append(errs, xerrors.Errorf("API server address must be loopback without the token file, got %q", cfg.APIServerAddress))
+			}
+		}
+	}
+
+	if o.APIServer.Kubeconfig != nil && *o.APIServer.Kubeconfig != "" {
+		cfg.KubeconfigPath = *o.APIServer.Kubeconfig
+	}
+
+	if cfg.TokenFile != "" {
+		if _, err := os.Stat(cfg.TokenFile); err != nil {
+			errs = append(errs, xerrors.Errorf("check token file: %w", err))
+		}
+	}
+
+	if cfg.NodeLifecycle && !cfg.FakeKubelet {
+		errs = append(errs, xerrors.New("node lifecycle controller requires the fake kubelet, or it marks all nodes as unreachable"))
+	}
+
+	if o.APIServer.AdmissionPlugins != nil {
+		cfg.AdmissionPlugins = *o.APIServer.AdmissionPlugins
+		if err := k8sapiserver.ValidateAdmissionPlugins(cfg.AdmissionPlugins); err != nil {
+			errs = append(errs, xerrors.Errorf("check admission plugins: %w", err))
+		}
+	}
+
+	if o.Controllers.Workload != nil {
+		cfg.Controllers = *o.Controllers.Workload
+		for _, name := range cfg.Controllers {
+			if !contains(workloadcontroller.AllControllers, name) {
+				errs = append(errs, xerrors.Errorf("unknown workload controller %q, must be one of %v", name, workloadcontroller.AllControllers))
+			}
+		}
 	}
-	return port, nil
-}
 
-func getEtcdURL() (string, error) {
-	e := os.Getenv("KUBE_SCHEDULER_SIMULATOR_ETCD_URL")
-	if e == "" {
-		return "", xerrors.Errorf("get KUBE_SCHEDULER_SIMULATOR_ETCD_URL from env: %w", ErrEmptyEnv)
+	if p := stringValue(o.Scheduler.ConfigPath); p != "" {
+		sc, err := defaultconfig.LoadSchedulerConfig(p)
+		if err != nil {
+			errs = append(errs, xerrors.Errorf("load scheduler config: %w", err))
+		}
+		cfg.SchedulerConfig = sc
+	} else {
+		sc, err := defaultconfig.DefaultSchedulerConfig()
+		if err != nil {
+			errs = append(errs, xerrors.Errorf("create default scheduler config: %w", err))
+		}
+		cfg.SchedulerConfig = sc
 	}
 
-	return e, nil
+	if o.Logging.Verbosity != nil {
+		cfg.LogVerbosity = *o.Logging.Verbosity
+	}
+	if cfg.LogVerbosity < 0 {
+		errs = append(errs, xerrors.Errorf("log verbosity must not be negative, got %d", cfg.LogVerbosity))
+	}
+
+	if o.Results.Output != nil {
+		cfg.ResultOutput = *o.Results.Output
+	}
+	if cfg.ResultOutput != ResultOutputAnnotation && cfg.ResultOutput != ResultOutputSchedulingResult {
+		errs = append(errs, xerrors.Errorf("unknown result output %q, must be one of %v", cfg.ResultOutput, []string{ResultOutputAnnotation, ResultOutputSchedulingResult}))
+	}
+	if o.Results.Verbosity != nil {
+		cfg.ResultVerbosity = resultstore.Verbosity(*o.Results.Verbosity)
+	}
+	if o.Results.TopN != nil {
+		cfg.ResultTopN = *o.Results.TopN
+	}
+	if _, err := resultstore.WithVerbosity(cfg.ResultVerbosity, cfg.ResultTopN); err != nil {
+		errs = append(errs, xerrors.Errorf("check result verbosity: %w", err))
+	}
+
+	return cfg, errs
 }
 
-func getFrontendURL() (string, error) {
-	e := os.Getenv("FRONTEND_URL")
-	if e == "" {
-		return "", xerrors.Errorf("get FRONTEND_URL from env: %w", ErrEmptyEnv)
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
 	}
+	return *s
+}
 
-	return e, nil
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback returns true if host is localhost or a loopback IP address.
+// The empty host, which means all addresses, isn't loopback.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
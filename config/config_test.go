@@ -0,0 +1,230 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	configFile := writeFile("config.yaml", `
+port: 8080
+frontendURL: http://localhost:3000
+apiServer:
+  address: 127.0.0.1:6443
+  admissionPlugins: [Priority]
+controllers:
+  workload: [replicaset]
+  fakeKubelet: true
+logging:
+  verbosity: 2
+results:
+  output: SchedulingResult
+  verbosity: TopN
+  topN: 3
+`)
+	unknownFieldFile := writeFile("unknown.yaml", "prot: 8080\n")
+	tokenFile := writeFile("admin-tokens.csv", "admin-token,admin,1,system:masters\n")
+	schedulerConfigFile := writeFile("scheduler.yaml", `
+apiVersion: kubescheduler.config.k8s.io/v1beta2
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: simulator-scheduler
+`)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		// check checks the config if the error isn't expected.
+		check func(t *testing.T, cfg *Config)
+		// wantErrs is the messages which the error must contain.
+		wantErrs []string
+	}{
+		{
+			name: "nothing is required",
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 1212, cfg.Port)
+				assert.Empty(t, cfg.FrontendURL)
+				assert.Equal(t, filepath.Join(os.TempDir(), "kube-scheduler-simulator.kubeconfig"), cfg.KubeconfigPath)
+				assert.Nil(t, cfg.AdmissionPlugins)
+				assert.Nil(t, cfg.Controllers)
+				require.NotNil(t, cfg.SchedulerConfig)
+				assert.Equal(t, "default-scheduler", *cfg.SchedulerConfig.Profiles[0].SchedulerName)
+				assert.Equal(t, ResultOutputAnnotation, cfg.ResultOutput)
+				assert.Equal(t, resultstore.VerbosityFull, cfg.ResultVerbosity)
+			},
+		},
+		{
+			name: "environment variables as before",
+			env: map[string]string{
+				"PORT":                                    "1213",
+				"FRONTEND_URL":                            "http://localhost:3000",
+				"KUBE_SCHEDULER_SIMULATOR_ETCD_URL":       "http://localhost:2379",
+				"KUBE_SCHEDULER_SIMULATOR_FAKE_KUBELET":   "true",
+				"KUBE_SCHEDULER_SIMULATOR_NODE_LIFECYCLE": "",
+				"KUBE_SCHEDULER_SIMULATOR_CONTROLLERS":    "",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 1213, cfg.Port)
+				assert.Equal(t, "http://localhost:3000", cfg.FrontendURL)
+				assert.Equal(t, "http://localhost:2379", cfg.EtcdURL)
+				assert.True(t, cfg.FakeKubelet)
+				assert.False(t, cfg.NodeLifecycle)
+				// the empty list disables all workload controllers.
+				assert.Equal(t, []string{}, cfg.Controllers)
+			},
+		},
+		{
+			name: "config file",
+			args: []string{"--config", configFile},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 8080, cfg.Port)
+				assert.Equal(t, "http://localhost:3000", cfg.FrontendURL)
+				assert.Equal(t, "127.0.0.1:6443", cfg.APIServerAddress)
+				assert.Equal(t, []string{"Priority"}, cfg.AdmissionPlugins)
+				assert.Equal(t, []string{"replicaset"}, cfg.Controllers)
+				assert.True(t, cfg.FakeKubelet)
+				assert.Equal(t, 2, cfg.LogVerbosity)
+				assert.Equal(t, ResultOutputSchedulingResult, cfg.ResultOutput)
+				assert.Equal(t, resultstore.VerbosityTopN, cfg.ResultVerbosity)
+				assert.Equal(t, 3, cfg.ResultTopN)
+			},
+		},
+		{
+			name: "flags override environment variables, which override config file",
+			args: []string{"--port=9090", "--controllers=job,deployment", "-v", "4", "--result-output=Annotation"},
+			env: map[string]string{
+				"KUBE_SCHEDULER_SIMULATOR_CONFIG":           configFile,
+				"PORT":                                      "8081",
+				"FRONTEND_URL":                              "http://localhost:3001",
+				"KUBE_SCHEDULER_SIMULATOR_CONTROLLERS":      "daemonset",
+				"KUBE_SCHEDULER_SIMULATOR_FAKE_KUBELET":     "false",
+				"KUBE_SCHEDULER_SIMULATOR_RESULT_VERBOSITY": "Summary",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, 9090, cfg.Port)
+				assert.Equal(t, "http://localhost:3001", cfg.FrontendURL)
+				assert.Equal(t, "127.0.0.1:6443", cfg.APIServerAddress)
+				assert.Equal(t, []string{"job", "deployment"}, cfg.Controllers)
+				assert.False(t, cfg.FakeKubelet)
+				assert.Equal(t, 4, cfg.LogVerbosity)
+				assert.Equal(t, ResultOutputAnnotation, cfg.ResultOutput)
+				assert.Equal(t, resultstore.VerbositySummary, cfg.ResultVerbosity)
+				assert.Equal(t, 3, cfg.ResultTopN)
+			},
+		},
+		{
+			name: "scheduler config file",
+			args: []string{"--scheduler-config", schedulerConfigFile},
+			check: func(t *testing.T, cfg *Config) {
+				require.Len(t, cfg.SchedulerConfig.Profiles, 1)
+				assert.Equal(t, "simulator-scheduler", *cfg.SchedulerConfig.Profiles[0].SchedulerName)
+				// the default values are set.
+				assert.NotEmpty(t, cfg.SchedulerConfig.Profiles[0].Plugins.Filter.Enabled)
+			},
+		},
+		{
+			name: "every problem is listed",
+			args: []string{"--frontend-url=localhost", "--controllers=replicaset,cronjob", "--apiserver-address=6443", "--admission-plugins=Priority,Unknown", "--token-file", filepath.Join(dir, "tokens.csv"), "--result-output=Event", "--result-verbosity=All"},
+			env: map[string]string{
+				"PORT": "0",
+				"KUBE_SCHEDULER_SIMULATOR_NODE_LIFECYCLE":   "maybe",
+				"KUBE_SCHEDULER_SIMULATOR_LOG_VERBOSITY":    "-1",
+				"KUBE_SCHEDULER_SIMULATOR_SCHEDULER_CONFIG": configFile,
+			},
+			wantErrs: []string{
+				"KUBE_SCHEDULER_SIMULATOR_NODE_LIFECYCLE",
+				"port must be between 1 and 65535, got 0",
+				`frontend URL must have the scheme and the host, got "localhost"`,
+				"tokens.csv",
+				`unknown workload controller "cronjob"`,
+				"load scheduler config",
+				"log verbosity must not be negative, got -1",
+				"parse API server address: address 6443: missing port in address",
+				`admission-control plugin "Unknown" is unknown`,
+				`unknown result output "Event"`,
+				`unknown verbosity "All"`,
+			},
+		},
+		{
+			name: "API server on all addresses with the token file",
+			args: []string{"--apiserver-address=:6443", "--token-file", tokenFile},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, ":6443", cfg.APIServerAddress)
+			},
+		},
+		{
+			name:     "API server on all addresses without the token file",
+			args:     []string{"--apiserver-address=:6443"},
+			wantErrs: []string{`API server address must be loopback without the token file, got ":6443"`},
+		},
+		{
+			name:     "API server on a non-loopback address without the token file",
+			args:     []string{"--apiserver-address=192.168.0.1:6443"},
+			wantErrs: []string{`API server address must be loopback without the token file, got "192.168.0.1:6443"`},
+		},
+		{
+			name: "API server on localhost without the token file",
+			args: []string{"--apiserver-address=localhost:6443"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "localhost:6443", cfg.APIServerAddress)
+			},
+		},
+		{
+			name:     "node lifecycle controller without fake kubelet",
+			args:     []string{"--node-lifecycle"},
+			wantErrs: []string{"node lifecycle controller requires the fake kubelet"},
+		},
+		{
+			name:     "unknown field in config file",
+			args:     []string{"--config", unknownFieldFile},
+			wantErrs: []string{`unknown field "prot"`},
+		},
+		{
+			name:     "config file which doesn't exist",
+			args:     []string{"--config", filepath.Join(dir, "notfound.yaml")},
+			wantErrs: []string{"notfound.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			cfg, err := newConfig(tt.args, lookupEnv)
+			if len(tt.wantErrs) != 0 {
+				require.Error(t, err)
+				for _, want := range tt.wantErrs {
+					assert.Contains(t, err.Error(), want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestNewConfig_help(t *testing.T) {
+	t.Parallel()
+	_, err := newConfig([]string{"--help"}, func(string) (string, bool) { return "", false })
+	assert.ErrorIs(t, err, pflag.ErrHelp)
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
+)
+
+// configFlag and configEnv give the path of the config file.
+const (
+	configFlag = "config"
+	configEnv  = "KUBE_SCHEDULER_SIMULATOR_CONFIG"
+)
+
+// Options is the settings given from one source: the config file, the environment variables or the flags.
+// nil means that the source doesn't have the setting.
+// The config file is YAML in the same structure as Options.
+type Options struct {
+	// Port is the port of the HTTP server.
+	Port *int `json:"port,omitempty"`
+	// FrontendURL is the URL of the frontend, whose requests are allowed by CORS.
+	FrontendURL *string           `json:"frontendURL,omitempty"`
+	APIServer   APIServerOptions  `json:"apiServer,omitempty"`
+	Storage     StorageOptions    `json:"storage,omitempty"`
+	Scheduler   SchedulerOptions  `json:"scheduler,omitempty"`
+	Controllers ControllerOptions `json:"controllers,omitempty"`
+	Logging     LoggingOptions    `json:"logging,omitempty"`
+	Results     ResultOptions     `json:"results,omitempty"`
+}
+
+// APIServerOptions is the settings of the API server.
+type APIServerOptions struct {
+	// Address is the address which the API server listens on.
+	Address *string `json:"address,omitempty"`
+	// Kubeconfig is the path of the kubeconfig written when Address is set.
+	Kubeconfig *string `json:"kubeconfig,omitempty"`
+	// TokenFile is the CSV file of static tokens to access the API server.
+	TokenFile *string `json:"tokenFile,omitempty"`
+	// AdmissionPlugins is the names of the admission plugins. The empty list disables all admission plugins.
+	AdmissionPlugins *[]string `json:"admissionPlugins,omitempty"`
+}
+
+// StorageOptions is the settings of etcd and the volumes.
+type StorageOptions struct {
+	// EtcdURL is the URL of etcd.
+	EtcdURL *string `json:"etcdURL,omitempty"`
+	// ProvisionerTopologyKey is the label of the nodes which the fake provisioner uses as the topology of the volumes.
+	ProvisionerTopologyKey *string `json:"provisionerTopologyKey,omitempty"`
+}
+
+// SchedulerOptions is the settings of the scheduler.
+type SchedulerOptions struct {
+	// ConfigPath is the path of the KubeSchedulerConfiguration file.
+	ConfigPath *string `json:"configPath,omitempty"`
+}
+
+// ControllerOptions is the settings of the controllers.
+type ControllerOptions struct {
+	// Workload is the names of the workload controllers to run. The empty list disables all workload controllers.
+	Workload *[]string `json:"workload,omitempty"`
+	// FakeKubelet is whether to run the fake kubelet.
+	FakeKubelet *bool `json:"fakeKubelet,omitempty"`
+	// NodeLifecycle is whether to run the node lifecycle controller.
+	NodeLifecycle *bool `json:"nodeLifecycle,omitempty"`
+}
+
+// LoggingOptions is the settings of logging.
+type LoggingOptions struct {
+	// Verbosity is the verbosity of klog.
+	Verbosity *int `json:"verbosity,omitempty"`
+}
+
+// ResultOptions is the settings of the scheduling results recorded by the scheduler.
+type ResultOptions struct {
+	// Output is where the results are recorded: "Annotation" or "SchedulingResult".
+	Output *string `json:"output,omitempty"`
+	// Verbosity is how much of the results are recorded: "Full", "TopN" or "Summary".
+	Verbosity *string `json:"verbosity,omitempty"`
+	// TopN is the number of nodes whose results are recorded with "TopN" verbosity.
+	TopN *int `json:"topN,omitempty"`
+}
+
+// loadFile loads the options from the YAML file. Unknown fields are errors.
+func (o *Options) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return xerrors.Errorf("read file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, o); err != nil {
+		return xerrors.Errorf("unmarshal file: %w", err)
+	}
+	return nil
+}
+
+// loadEnv overrides the options with the environment variables.
+// The empty variables are ignored except for the lists, whose empty value means the empty list.
+// It returns all errors of the variables which can't be parsed.
+func (o *Options) loadEnv(lookupEnv func(key string) (string, bool)) []error {
+	var errs []error
+	appendErr := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	appendErr(envInt(lookupEnv, "PORT", &o.Port))
+	envString(lookupEnv, "FRONTEND_URL", &o.FrontendURL)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_APISERVER_ADDRESS", &o.APIServer.Address)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_KUBECONFIG", &o.APIServer.Kubeconfig)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_TOKEN_FILE", &o.APIServer.TokenFile)
+	envList(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_ADMISSION_PLUGINS", &o.APIServer.AdmissionPlugins)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_ETCD_URL", &o.Storage.EtcdURL)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_PROVISIONER_TOPOLOGY_KEY", &o.Storage.ProvisionerTopologyKey)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_SCHEDULER_CONFIG", &o.Scheduler.ConfigPath)
+	envList(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_CONTROLLERS", &o.Controllers.Workload)
+	appendErr(envBool(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_FAKE_KUBELET", &o.Controllers.FakeKubelet))
+	appendErr(envBool(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_NODE_LIFECYCLE", &o.Controllers.NodeLifecycle))
+	appendErr(envInt(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_LOG_VERBOSITY", &o.Logging.Verbosity))
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_RESULT_OUTPUT", &o.Results.Output)
+	envString(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_RESULT_VERBOSITY", &o.Results.Verbosity)
+	appendErr(envInt(lookupEnv, "KUBE_SCHEDULER_SIMULATOR_RESULT_TOP_N", &o.Results.TopN))
+
+	return errs
+}
+
+func envString(lookupEnv func(key string) (string, bool), key string, dst **string) {
+	if e, _ := lookupEnv(key); e != "" {
+		*dst = &e
+	}
+}
+
+func envInt(lookupEnv func(key string) (string, bool), key string, dst **int) error {
+	e, _ := lookupEnv(key)
+	if e == "" {
+		return nil
+	}
+
+	i, err := strconv.Atoi(e)
+	if err != nil {
+		return xerrors.Errorf("convert %s of string to int: %w", key, err)
+	}
+	*dst = &i
+	return nil
+}
+
+func envBool(lookupEnv func(key string) (string, bool), key string, dst **bool) error {
+	e, _ := lookupEnv(key)
+	if e == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(e)
+	if err != nil {
+		return xerrors.Errorf("convert %s of string to bool: %w", key, err)
+	}
+	*dst = &b
+	return nil
+}
+
+// envList gets the comma-separated list. The variable set to empty gives the empty list.
+func envList(lookupEnv func(key string) (string, bool), key string, dst **[]string) {
+	if e, ok := lookupEnv(key); ok {
+		list := splitList(e)
+		*dst = &list
+	}
+}
+
+// splitList splits the comma-separated list, and drops the empty items.
+func splitList(s string) []string {
+	list := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// flagOptions has the values of the flags, which are used only if the flags are given.
+type flagOptions struct {
+	config                 string
+	port                   int
+	frontendURL            string
+	apiServerAddress       string
+	kubeconfig             string
+	tokenFile              string
+	admissionPlugins       string
+	etcdURL                string
+	provisionerTopologyKey string
+	schedulerConfig        string
+	controllers            string
+	fakeKubelet            bool
+	nodeLifecycle          bool
+	verbosity              int
+	resultOutput           string
+	resultVerbosity        string
+	resultTopN             int
+}
+
+func newFlagSet() (*pflag.FlagSet, *flagOptions) {
+	fo := &flagOptions{}
+	fs := pflag.NewFlagSet("sched", pflag.ContinueOnError)
+	fs.StringVar(&fo.config, configFlag, "", "The path of the YAML config file. Env: "+configEnv)
+	fs.IntVar(&fo.port, "port", defaultPort, "The port of the HTTP server. Env: PORT")
+	fs.StringVar(&fo.frontendURL, "frontend-url", "", "The URL of the frontend allowed by CORS. Env: FRONTEND_URL")
+	fs.StringVar(&fo.apiServerAddress, "apiserver-address", "", "The address which the API server listens on. Env: KUBE_SCHEDULER_SIMULATOR_APISERVER_ADDRESS")
+	fs.StringVar(&fo.kubeconfig, "kubeconfig", "", "The path of the kubeconfig written when the API server address is set. Env: KUBE_SCHEDULER_SIMULATOR_KUBECONFIG")
+	fs.StringVar(&fo.tokenFile, "token-file", "", "The CSV file of static tokens to access the API server. Env: KUBE_SCHEDULER_SIMULATOR_TOKEN_FILE")
+	fs.StringVar(&fo.admissionPlugins, "admission-plugins", "", "The comma-separated names of the admission plugins. Env: KUBE_SCHEDULER_SIMULATOR_ADMISSION_PLUGINS")
+	fs.StringVar(&fo.etcdURL, "etcd-url", "", "The URL of etcd. The embedded etcd starts if it's empty. Env: KUBE_SCHEDULER_SIMULATOR_ETCD_URL")
+	fs.StringVar(&fo.provisionerTopologyKey, "provisioner-topology-key", "", "The node label used as the topology of the volumes. Env: KUBE_SCHEDULER_SIMULATOR_PROVISIONER_TOPOLOGY_KEY")
+	fs.StringVar(&fo.schedulerConfig, "scheduler-config", "", "The path of the KubeSchedulerConfiguration file. Env: KUBE_SCHEDULER_SIMULATOR_SCHEDULER_CONFIG")
+	fs.StringVar(&fo.controllers, "controllers", "", "The comma-separated names of the workload controllers to run. Env: KUBE_SCHEDULER_SIMULATOR_CONTROLLERS")
+	fs.BoolVar(&fo.fakeKubelet, "fake-kubelet", false, "Run the fake kubelet. Env: KUBE_SCHEDULER_SIMULATOR_FAKE_KUBELET")
+	fs.BoolVar(&fo.nodeLifecycle, "node-lifecycle", false, "Run the node lifecycle controller. Env: KUBE_SCHEDULER_SIMULATOR_NODE_LIFECYCLE")
+	fs.IntVarP(&fo.verbosity, "v", "v", 0, "The log verbosity. Env: KUBE_SCHEDULER_SIMULATOR_LOG_VERBOSITY")
+	fs.StringVar(&fo.resultOutput, "result-output", ResultOutputAnnotation, "Where the scheduling results are recorded: Annotation or SchedulingResult. Env: KUBE_SCHEDULER_SIMULATOR_RESULT_OUTPUT")
+	fs.StringVar(&fo.resultVerbosity, "result-verbosity", string(resultstore.VerbosityFull), "How much of the scheduling results are recorded: Full, TopN or Summary. Env: KUBE_SCHEDULER_SIMULATOR_RESULT_VERBOSITY")
+	fs.IntVar(&fo.resultTopN, "result-top-n", defaultResultTopN, "The number of nodes whose results are recorded with TopN verbosity. Env: KUBE_SCHEDULER_SIMULATOR_RESULT_TOP_N")
+	return fs, fo
+}
+
+// loadFlags overrides the options with the flags given in fs.
+func (o *Options) loadFlags(fs *pflag.FlagSet, fo *flagOptions) {
+	setString := func(name, v string, dst **string) {
+		if fs.Changed(name) {
+			*dst = &v
+		}
+	}
+	setList := func(name, v string, dst **[]string) {
+		if fs.Changed(name) {
+			list := splitList(v)
+			*dst = &list
+		}
+	}
+	setBool := func(name string, v bool, dst **bool) {
+		if fs.Changed(name) {
+			*dst = &v
+		}
+	}
+	setInt := func(name string, v int, dst **int) {
+		if fs.Changed(name) {
+			*dst = &v
+		}
+	}
+
+	setInt("port", fo.port, &o.Port)
+	setString("frontend-url", fo.frontendURL, &o.FrontendURL)
+	setString("apiserver-address", fo.apiServerAddress, &o.APIServer.Address)
+	setString("kubeconfig", fo.kubeconfig, &o.APIServer.Kubeconfig)
+	setString("token-file", fo.tokenFile, &o.APIServer.TokenFile)
+	setList("admission-plugins", fo.admissionPlugins, &o.APIServer.AdmissionPlugins)
+	setString("etcd-url", fo.etcdURL, &o.Storage.EtcdURL)
+	setString("provisioner-topology-key", fo.provisionerTopologyKey, &o.Storage.ProvisionerTopologyKey)
+	setString("scheduler-config", fo.schedulerConfig, &o.Scheduler.ConfigPath)
+	setList("controllers", fo.controllers, &o.Controllers.Workload)
+	setBool("fake-kubelet", fo.fakeKubelet, &o.Controllers.FakeKubelet)
+	setBool("node-lifecycle", fo.nodeLifecycle, &o.Controllers.NodeLifecycle)
+	setInt("v", fo.verbosity, &o.Logging.Verbosity)
+	setString("result-output", fo.resultOutput, &o.Results.Output)
+	setString("result-verbosity", fo.resultVerbosity, &o.Results.Verbosity)
+	setInt("result-top-n", fo.resultTopN, &o.Results.TopN)
+}
@@ -3,3 +3,6 @@ package errors
 import "errors"
 
 var ErrNotFound = errors.New("resource not found")
+
+// ErrInvalidSchedulerConfig is returned when the scheduler can't be created with the requested configuration.
+var ErrInvalidSchedulerConfig = errors.New("invalid scheduler configuration")
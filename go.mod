@@ -32,21 +32,33 @@ replace (
 )
 
 require (
+	github.com/go-interpreter/wagon v0.6.0
 	github.com/golang/mock v1.4.4
 	github.com/golangci/golangci-lint v1.41.1
+	github.com/google/cel-go v0.9.0
 	github.com/google/uuid v1.1.2
 	github.com/labstack/echo/v4 v4.5.0
 	github.com/labstack/gommon v0.3.0
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
+	go.etcd.io/etcd/server/v3 v3.5.0
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
+	google.golang.org/genproto v0.0.0-20210831024726-fe130286e0e2
+	google.golang.org/grpc v1.40.0
+	google.golang.org/protobuf v1.27.1
 	k8s.io/api v1.22.0
 	k8s.io/apiextensions-apiserver v0.0.0
 	k8s.io/apimachinery v1.22.0
 	k8s.io/apiserver v1.22.0
 	k8s.io/client-go v1.22.0
 	k8s.io/component-base v0.22.0
+	k8s.io/component-helpers v0.22.0
+	k8s.io/controller-manager v0.22.0
 	k8s.io/klog/v2 v2.9.0
 	k8s.io/kube-openapi v0.0.0-20210421082810-95288971da7e
 	k8s.io/kube-scheduler v1.22.0
 	k8s.io/kubernetes v1.22.0
+	k8s.io/utils v0.0.0-20210707171843-4b05e18ac7d9
+	sigs.k8s.io/yaml v1.2.0
 )
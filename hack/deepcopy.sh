@@ -0,0 +1,13 @@
+#!/usr/bin/env bash
+
+# re-generate deepcopy functions for the API types of the simulator.
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+go run k8s.io/code-generator/cmd/deepcopy-gen@v0.22.0 \
+  --input-dirs github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1 \
+  -O zz_generated.deepcopy \
+  --go-header-file /dev/null \
+  --output-base "${OUTPUT_BASE}"
+
+cp "${OUTPUT_BASE}/github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1/zz_generated.deepcopy.go" apis/v1alpha1/
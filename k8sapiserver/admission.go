@@ -0,0 +1,68 @@
+package k8sapiserver
+
+import (
+	"net/http"
+
+	"golang.org/x/xerrors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/apiserver/pkg/util/webhook"
+	"k8s.io/kubernetes/pkg/controlplane"
+	kubeapiserveradmission "k8s.io/kubernetes/pkg/kubeapiserver/admission"
+	kubeoptions "k8s.io/kubernetes/pkg/kubeapiserver/options"
+)
+
+// DefaultAdmissionPlugins is the admission plugins enabled by default.
+// It's the default of kube-apiserver except the plugins which need the controllers or the kubelets
+// that the simulator doesn't run, like ServiceAccount and TaintNodesByCondition.
+var DefaultAdmissionPlugins = []string{
+	"NamespaceLifecycle",
+	"LimitRanger",
+	"Priority",
+	"DefaultTolerationSeconds",
+	"DefaultStorageClass",
+	"PersistentVolumeClaimResize",
+	"RuntimeClass",
+	"MutatingAdmissionWebhook",
+	"ValidatingAdmissionWebhook",
+	"ResourceQuota",
+}
+
+// ValidateAdmissionPlugins checks that kube-apiserver has all admission plugins named pluginNames.
+func ValidateAdmissionPlugins(pluginNames []string) error {
+	opts := kubeoptions.NewAdmissionOptions()
+	opts.PluginNames = pluginNames
+	if errs := opts.Validate(); len(errs) != 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	return nil
+}
+
+// applyAdmission configures the control plane to run the admission plugins named pluginNames, like kube-apiserver's --admission-control.
+// The order of pluginNames doesn't matter. The plugins always run in the order kube-apiserver runs them.
+func applyAdmission(c *controlplane.Config, pluginNames []string) error {
+	if err := ValidateAdmissionPlugins(pluginNames); err != nil {
+		return xerrors.Errorf("validate admission plugins: %w", err)
+	}
+	opts := kubeoptions.NewAdmissionOptions()
+	opts.PluginNames = pluginNames
+
+	loopback := c.GenericConfig.LoopbackClientConfig
+	admissionConfig := &kubeapiserveradmission.Config{
+		ExternalInformers:    c.ExtraConfig.VersionedInformers,
+		LoopbackClientConfig: loopback,
+	}
+	initializers, postStartHook, err := admissionConfig.New(utilnet.SetTransportDefaults(&http.Transport{}), c.GenericConfig.EgressSelector, webhook.NewDefaultServiceResolver(), c.GenericConfig.TracerProvider)
+	if err != nil {
+		return xerrors.Errorf("create admission plugin initializers: %w", err)
+	}
+	if err := c.GenericConfig.AddPostStartHook("start-kube-apiserver-admission-initializer", postStartHook); err != nil {
+		return xerrors.Errorf("add admission post start hook: %w", err)
+	}
+
+	if err := opts.ApplyTo(c.GenericConfig, c.ExtraConfig.VersionedInformers, loopback, utilfeature.DefaultFeatureGate, initializers...); err != nil {
+		return xerrors.Errorf("apply admission plugins: %w", err)
+	}
+	return nil
+}
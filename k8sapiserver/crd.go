@@ -0,0 +1,168 @@
+package k8sapiserver
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1"
+)
+
+// installCRDs registers the API types of the simulator to the API server as CustomResourceDefinitions,
+// and waits for them to be established.
+func installCRDs(cfg *restclient.Config) error {
+	ctx := context.Background()
+
+	client, err := apiextensionsclientset.NewForConfig(cfg)
+	if err != nil {
+		return xerrors.Errorf("create apiextensions clientset: %w", err)
+	}
+
+	crd := schedulingResultCRD()
+	if _, err := client.ApiextensionsV1().CustomResourceDefinitions().Create(ctx, crd, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return xerrors.Errorf("create CustomResourceDefinition %s: %w", crd.Name, err)
+	}
+
+	err = wait.PollImmediate(100*time.Millisecond, 30*time.Second, func() (bool, error) {
+		c, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crd.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, cond := range c.Status.Conditions {
+			if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return xerrors.Errorf("wait for CustomResourceDefinition %s to be established: %w", crd.Name, err)
+	}
+
+	return nil
+}
+
+// schedulingResultCRD returns the CustomResourceDefinition of v1alpha1.SchedulingResult.
+//nolint:funlen
+func schedulingResultCRD() *apiextensionsv1.CustomResourceDefinition {
+	str := apiextensionsv1.JSONSchemaProps{Type: "string"}
+	integer := apiextensionsv1.JSONSchemaProps{Type: "integer", Format: "int64"}
+	boolean := apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	strArray := apiextensionsv1.JSONSchemaProps{
+		Type:  "array",
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &str},
+	}
+
+	filterResult := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"plugin", "passed"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"plugin": str,
+			"passed": boolean,
+			"reason": str,
+		},
+	}
+	scoreResult := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"plugin", "score", "finalScore"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"plugin":     str,
+			"score":      integer,
+			"finalScore": integer,
+		},
+	}
+	nodeResult := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"nodeName"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"nodeName": str,
+			"filter": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &filterResult},
+			},
+			"score": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &scoreResult},
+			},
+		},
+	}
+	permitDecision := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"plugin", "allowed", "time"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"plugin":  str,
+			"allowed": boolean,
+			"message": str,
+			"time":    {Type: "string", Format: "date-time"},
+		},
+	}
+
+	schema := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"spec"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"apiVersion": str,
+			"kind":       str,
+			"metadata":   {Type: "object"},
+			"spec": {
+				Type:     "object",
+				Required: []string{"podRef"},
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"podRef": {
+						Type:     "object",
+						Required: []string{"namespace", "name", "uid"},
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"namespace": str,
+							"name":      str,
+							"uid":       str,
+						},
+					},
+					"filterPlugins": strArray,
+					"scorePlugins":  strArray,
+					"nodes": {
+						Type:  "array",
+						Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &nodeResult},
+					},
+					"summary": str,
+					"permit": {
+						Type:  "array",
+						Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &permitDecision},
+					},
+				},
+			},
+		},
+	}
+
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: v1alpha1.SchedulingResultsResource.Resource + "." + v1alpha1.GroupName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.GroupName,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   v1alpha1.SchedulingResultsResource.Resource,
+				Singular: "schedulingresult",
+				Kind:     "SchedulingResult",
+				ListKind: "SchedulingResultList",
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name:    v1alpha1.SchemeGroupVersion.Version,
+					Served:  true,
+					Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &schema,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package k8sapiserver
+
+import (
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+	"golang.org/x/xerrors"
+	"k8s.io/klog/v2"
+)
+
+// embeddedEtcdStartTimeout is how long to wait for the embedded etcd to be ready.
+const embeddedEtcdStartTimeout = 60 * time.Second
+
+// startEmbeddedEtcd starts etcd in this process with the data in a temporary directory,
+// and returns its URL and the function to stop it and remove the directory.
+// It listens on free ports of localhost, so it doesn't conflict with other etcd.
+func startEmbeddedEtcd() (string, func(), error) {
+	dir, err := ioutil.TempDir("", "simulator-etcd")
+	if err != nil {
+		return "", nil, xerrors.Errorf("create data directory: %w", err)
+	}
+
+	clientURL, err := freeLocalURL()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, xerrors.Errorf("get URL for clients: %w", err)
+	}
+	peerURL, err := freeLocalURL()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, xerrors.Errorf("get URL for peers: %w", err)
+	}
+
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	cfg.LCUrls = []url.URL{*clientURL}
+	cfg.ACUrls = []url.URL{*clientURL}
+	cfg.LPUrls = []url.URL{*peerURL}
+	cfg.APUrls = []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, xerrors.Errorf("start etcd: %w", err)
+	}
+
+	shutdown := func() {
+		klog.Info("destroying embedded etcd")
+		e.Close()
+		if err := os.RemoveAll(dir); err != nil {
+			klog.Errorf("failed to remove the data directory of embedded etcd: %v", err)
+		}
+		klog.Info("destroyed embedded etcd")
+	}
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case err := <-e.Err():
+		shutdown()
+		return "", nil, xerrors.Errorf("run etcd: %w", err)
+	case <-time.After(embeddedEtcdStartTimeout):
+		shutdown()
+		return "", nil, xerrors.New("wait for etcd to be ready: timed out")
+	}
+
+	klog.Infof("started embedded etcd on %s", clientURL)
+	return clientURL.String(), shutdown, nil
+}
+
+// freeLocalURL returns the URL of localhost with a free port.
+// etcd has to know the port before listening on it, since it advertises the port.
+func freeLocalURL() (*url.URL, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, xerrors.Errorf("listen on a free port: %w", err)
+	}
+	defer l.Close()
+
+	return &url.URL{Scheme: "http", Host: l.Addr().String()}, nil
+}
@@ -2,14 +2,21 @@ package k8sapiserver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/xerrors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextensionsapiserver "k8s.io/apiextensions-apiserver/pkg/apiserver"
+	apiextensionsoptions "k8s.io/apiextensions-apiserver/pkg/cmd/server/options"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	authauthenticator "k8s.io/apiserver/pkg/authentication/authenticator"
@@ -39,27 +46,106 @@ import (
 	generated "github.com/sanposhiho/mini-kube-scheduler/k8sapiserver/openapi"
 )
 
+// Options configures the API server started by StartAPIServer.
+type Options struct {
+	// EtcdURL is the URL of etcd. If it's empty, an embedded etcd is started, and stopped and removed with the API server.
+	EtcdURL string
+	// Address is the address which the API server listens on. If it's empty, the API server listens on a random port of localhost.
+	Address string
+	// TokenFile is the CSV file of static tokens in the same format as kube-apiserver's --token-auth-file.
+	// If it's set, the API server is secured: it serves HTTPS with a self-signed certificate,
+	// authenticates requests with the tokens, and authorizes them with RBAC.
+	// Otherwise, it serves HTTP and allows all requests.
+	TokenFile string
+	// AdmissionPlugins is the names of the admission plugins to enable, like kube-apiserver's --admission-control.
+	// If it's nil, DefaultAdmissionPlugins are enabled. If it's empty, no admission plugins are enabled.
+	AdmissionPlugins []string
+}
+
 // StartAPIServer starts API server, and it make panic when a error happen.
-func StartAPIServer(etcdURL string) (*restclient.Config, func(), error) {
+// The returned config has the privileged credentials of the components in this process.
+func StartAPIServer(opts Options) (*restclient.Config, func(), error) {
+	if opts.EtcdURL == "" {
+		url, etcdShutdown, err := startEmbeddedEtcd()
+		if err != nil {
+			return nil, nil, xerrors.Errorf("start embedded etcd: %w", err)
+		}
+		opts.EtcdURL = url
+		cfg, apiShutdown, err := StartAPIServer(opts)
+		if err != nil {
+			etcdShutdown()
+			return nil, nil, err
+		}
+		return cfg, func() {
+			apiShutdown()
+			etcdShutdown()
+		}, nil
+	}
+
 	h := &APIServerHolder{Initialized: make(chan struct{})}
-	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		<-h.Initialized
 		h.M.GenericAPIServer.Handler.ServeHTTP(w, req)
 	}))
+	if opts.Address != "" {
+		l, err := net.Listen("tcp", opts.Address)
+		if err != nil {
+			s.Close()
+			return nil, nil, xerrors.Errorf("listen on %s: %w", opts.Address, err)
+		}
+		s.Listener.Close()
+		s.Listener = l
+	}
 
-	c := NewControlPlaneConfigWithOptions(s.URL, etcdURL)
+	var certPEM []byte
+	if opts.TokenFile != "" {
+		cert, pem, err := selfSignedCert(s.Listener.Addr())
+		if err != nil {
+			s.Close()
+			return nil, nil, xerrors.Errorf("generate serving certificate: %w", err)
+		}
+		certPEM = pem
+		s.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		s.StartTLS()
+	} else {
+		s.Start()
+	}
+	serverURL := localURL(s.Listener.Addr(), opts.TokenFile != "")
+
+	etcdOptions := newEtcdOptions(opts.EtcdURL)
+	c := NewControlPlaneConfigWithOptions(serverURL, etcdOptions)
+	if opts.TokenFile != "" {
+		if err := secure(c, opts.TokenFile, certPEM); err != nil {
+			s.Close()
+			return nil, nil, xerrors.Errorf("secure API server: %w", err)
+		}
+	}
 
-	_, _, closeFn, err := startAPIServer(c, s, h)
+	admissionPlugins := opts.AdmissionPlugins
+	if admissionPlugins == nil {
+		admissionPlugins = DefaultAdmissionPlugins
+	}
+
+	_, _, closeFn, err := startAPIServer(c, etcdOptions, admissionPlugins, s, h)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("start API server: %w", err)
 	}
 
+	if err := installCRDs(c.GenericConfig.LoopbackClientConfig); err != nil {
+		closeFn()
+		return nil, nil, xerrors.Errorf("install CRDs: %w", err)
+	}
+
 	cfg := &restclient.Config{
-		Host:          s.URL,
+		Host:          serverURL,
 		ContentConfig: restclient.ContentConfig{GroupVersion: &schema.GroupVersion{Group: "", Version: "v1"}},
 		QPS:           5000.0,
 		Burst:         5000,
 	}
+	if opts.TokenFile != "" {
+		cfg.BearerToken = c.GenericConfig.LoopbackClientConfig.BearerToken
+		cfg.TLSClientConfig.CAData = certPEM
+	}
 
 	shutdownFunc := func() {
 		klog.Infof("destroying API server")
@@ -70,8 +156,22 @@ func StartAPIServer(etcdURL string) (*restclient.Config, func(), error) {
 	return cfg, shutdownFunc, nil
 }
 
+// localURL returns the URL of the listener address which clients on localhost can access.
+// The unspecified address like "[::]:6443" is replaced with the loopback address.
+func localURL(addr net.Addr, https bool) string {
+	scheme := "http://"
+	if https {
+		scheme = "https://"
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok || !tcpAddr.IP.IsUnspecified() {
+		return scheme + addr.String()
+	}
+	return scheme + net.JoinHostPort("127.0.0.1", strconv.Itoa(tcpAddr.Port))
+}
+
 func defaultOpenAPIConfig() *openapicommon.Config {
-	openAPIConfig := genericapiserver.DefaultOpenAPIConfig(generated.GetOpenAPIDefinitions, openapi.NewDefinitionNamer(legacyscheme.Scheme))
+	openAPIConfig := genericapiserver.DefaultOpenAPIConfig(generated.GetOpenAPIDefinitions, openapi.NewDefinitionNamer(legacyscheme.Scheme, apiextensionsapiserver.Scheme))
 	openAPIConfig.Info = &spec.Info{
 		InfoProps: spec.InfoProps{
 			Title:   "Kubernetes",
@@ -88,11 +188,15 @@ func defaultOpenAPIConfig() *openapicommon.Config {
 	return openAPIConfig
 }
 
-//nolint:funlen
-func NewControlPlaneConfigWithOptions(serverURL, etcdURL string) *controlplane.Config {
+func newEtcdOptions(etcdURL string) *options.EtcdOptions {
 	etcdOptions := options.NewEtcdOptions(storagebackend.NewDefaultConfig(uuid.New().String(), nil))
 	etcdOptions.StorageConfig.Transport.ServerList = []string{etcdURL}
 
+	return etcdOptions
+}
+
+//nolint:funlen
+func NewControlPlaneConfigWithOptions(serverURL string, etcdOptions *options.EtcdOptions) *controlplane.Config {
 	storageConfig := kubeapiserver.NewStorageFactoryConfig()
 	storageConfig.APIResourceConfig = serverstorage.NewResourceConfig()
 	completedStorageConfig, err := storageConfig.Complete(etcdOptions)
@@ -138,14 +242,7 @@ func NewControlPlaneConfigWithOptions(serverURL, etcdURL string) *controlplane.C
 
 	privilegedLoopbackToken := uuid.New().String()
 	// wrap any available authorizer
-	tokens := make(map[string]*user.DefaultInfo)
-	tokens[privilegedLoopbackToken] = &user.DefaultInfo{
-		Name:   user.APIServerUser,
-		UID:    uuid.New().String(),
-		Groups: []string{user.SystemPrivilegedGroup},
-	}
-
-	tokenAuthenticator := authenticatorfactory.NewFromTokens(tokens, cfg.GenericConfig.Authentication.APIAudiences)
+	tokenAuthenticator := loopbackAuthenticator(privilegedLoopbackToken, cfg.GenericConfig.Authentication.APIAudiences)
 	cfg.GenericConfig.Authentication.Authenticator = authenticatorunion.New(tokenAuthenticator, authauthenticator.RequestFunc(alwaysEmpty))
 	tokenAuthorizer := authorizerfactory.NewPrivilegedGroups(user.SystemPrivilegedGroup)
 	cfg.GenericConfig.Authorization.Authorizer = authorizerunion.New(tokenAuthorizer, authorizerfactory.NewAlwaysAllowAuthorizer())
@@ -160,6 +257,44 @@ func NewControlPlaneConfigWithOptions(serverURL, etcdURL string) *controlplane.C
 	return cfg
 }
 
+// loopbackAuthenticator authenticates the privileged loopback token as the API server itself.
+func loopbackAuthenticator(token string, apiAudiences authauthenticator.Audiences) authauthenticator.Request {
+	tokens := map[string]*user.DefaultInfo{
+		token: {
+			Name:   user.APIServerUser,
+			UID:    uuid.New().String(),
+			Groups: []string{user.SystemPrivilegedGroup},
+		},
+	}
+	return authenticatorfactory.NewFromTokens(tokens, apiAudiences)
+}
+
+// newAPIExtensionsConfig creates the configuration for apiextensions-apiserver, which serves CustomResourceDefinitions.
+// It is based on the configuration for the control plane, like kube-apiserver does.
+func newAPIExtensionsConfig(controlPlaneConfig *controlplane.Config, etcdOptions *options.EtcdOptions) *apiextensionsapiserver.Config {
+	// make a shallow copy to let us twiddle a few things.
+	genericConfig := *controlPlaneConfig.GenericConfig
+	genericConfig.PostStartHooks = map[string]genericapiserver.PostStartHookConfigEntry{}
+	genericConfig.MergedResourceConfig = apiextensionsapiserver.DefaultAPIResourceConfigSource()
+
+	// copy the etcd options so we don't mutate originals.
+	apiextensionsEtcdOptions := *etcdOptions
+	apiextensionsEtcdOptions.StorageConfig.Codec = apiextensionsapiserver.Codecs.LegacyCodec(apiextensionsv1beta1.SchemeGroupVersion, apiextensionsv1.SchemeGroupVersion)
+	apiextensionsEtcdOptions.StorageConfig.EncodeVersioner = runtime.NewMultiGroupVersioner(apiextensionsv1beta1.SchemeGroupVersion, schema.GroupKind{Group: apiextensionsv1beta1.GroupName})
+	genericConfig.RESTOptionsGetter = &options.SimpleRestOptionsFactory{Options: apiextensionsEtcdOptions}
+
+	return &apiextensionsapiserver.Config{
+		GenericConfig: &genericapiserver.RecommendedConfig{
+			Config:                genericConfig,
+			SharedInformerFactory: controlPlaneConfig.ExtraConfig.VersionedInformers,
+		},
+		ExtraConfig: apiextensionsapiserver.ExtraConfig{
+			CRDRESTOptionsGetter: apiextensionsoptions.NewCRDRESTOptionsGetter(apiextensionsEtcdOptions),
+			MasterCount:          1,
+		},
+	}
+}
+
 type fakeLocalhost443Listener struct{}
 
 func (fakeLocalhost443Listener) Accept() (net.Conn, error) {
@@ -179,7 +314,7 @@ func (fakeLocalhost443Listener) Addr() net.Addr {
 
 // startAPIServer starts a kubernetes API server and an httpserver to handle api requests.
 //nolint:funlen
-func startAPIServer(controlPlaneConfig *controlplane.Config, s *httptest.Server, apiServerReceiver *APIServerHolder) (*controlplane.Instance, *httptest.Server, func(), error) {
+func startAPIServer(controlPlaneConfig *controlplane.Config, etcdOptions *options.EtcdOptions, admissionPlugins []string, s *httptest.Server, apiServerReceiver *APIServerHolder) (*controlplane.Instance, *httptest.Server, func(), error) {
 	var m *controlplane.Instance
 
 	stopCh := make(chan struct{})
@@ -198,7 +333,15 @@ func startAPIServer(controlPlaneConfig *controlplane.Config, s *httptest.Server,
 		return nil, nil, nil, xerrors.Errorf("create clientset: %w", err)
 	}
 
-	controlPlaneConfig.ExtraConfig.VersionedInformers = informers.NewSharedInformerFactory(clientset, controlPlaneConfig.GenericConfig.LoopbackClientConfig.Timeout)
+	// the informers may be created beforehand for the authorizer.
+	if controlPlaneConfig.ExtraConfig.VersionedInformers == nil {
+		controlPlaneConfig.ExtraConfig.VersionedInformers = informers.NewSharedInformerFactory(clientset, controlPlaneConfig.GenericConfig.LoopbackClientConfig.Timeout)
+	}
+
+	if err := applyAdmission(controlPlaneConfig, admissionPlugins); err != nil {
+		closeFn()
+		return nil, nil, nil, xerrors.Errorf("apply admission: %w", err)
+	}
 
 	controlPlaneConfig.GenericConfig.FlowControl = utilflowcontrol.New(
 		controlPlaneConfig.ExtraConfig.VersionedInformers,
@@ -208,7 +351,16 @@ func startAPIServer(controlPlaneConfig *controlplane.Config, s *httptest.Server,
 	)
 	controlPlaneConfig.ExtraConfig.ServiceIPRange = net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(24, 32)}
 
-	m, err = controlPlaneConfig.Complete().New(genericapiserver.NewEmptyDelegate())
+	// apiextensions-apiserver is delegated the requests which the control plane doesn't handle.
+	apiextensionsConfig := newAPIExtensionsConfig(controlPlaneConfig, etcdOptions)
+	apiextensionsServer, err := apiextensionsConfig.Complete().New(genericapiserver.NewEmptyDelegate())
+	if err != nil {
+		klog.Errorf("error in bringing up the apiextensions-apiserver: %v", err)
+		closeFn()
+		return nil, nil, nil, fmt.Errorf("bringing up the apiextensions-apiserver: %w", err)
+	}
+
+	m, err = controlPlaneConfig.Complete().New(apiextensionsServer.GenericAPIServer)
 	if err != nil {
 		// We log the error first so that even if closeFn crashes, the error is shown
 		klog.Errorf("error in bringing up the apiserver: %v", err)
@@ -0,0 +1,210 @@
+package k8sapiserver
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/sanposhiho/mini-kube-scheduler/resourcequotacontroller"
+)
+
+func TestStartAPIServer(t *testing.T) {
+	t.Parallel()
+	// the empty etcd URL starts the embedded etcd.
+	cfg, shutdown, err := StartAPIServer(Options{Address: "127.0.0.1:0"})
+	require.NoError(t, err)
+	defer shutdown()
+
+	// access the API server with the kubeconfig as kubectl does.
+	kubeconfig, err := Kubeconfig(cfg)
+	require.NoError(t, err)
+	kubeconfigCfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Host, kubeconfigCfg.Host)
+
+	client := clientset.NewForConfigOrDie(kubeconfigCfg)
+	ctx := context.Background()
+	_, err = client.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, nodes.Items, 1)
+	assert.Equal(t, "node1", nodes.Items[0].Name)
+}
+
+func TestStartAPIServer_secured(t *testing.T) {
+	t.Parallel()
+	tokenFile := filepath.Join(t.TempDir(), "tokens.csv")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("admin-token,admin,1,system:masters\nuser-token,user,2\n"), 0o600))
+	cfg, shutdown, err := StartAPIServer(Options{Address: "127.0.0.1:0", TokenFile: tokenFile})
+	require.NoError(t, err)
+	defer shutdown()
+
+	kubeconfig, err := Kubeconfig(cfg)
+	require.NoError(t, err)
+	clientWithToken := func(token string) clientset.Interface {
+		c, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(c.Host, "https://"))
+		c.BearerToken = token
+		return clientset.NewForConfigOrDie(c)
+	}
+	ctx := context.Background()
+
+	_, err = clientWithToken("").CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	assert.True(t, apierrors.IsUnauthorized(err), "want unauthorized, got %v", err)
+
+	_, err = clientWithToken("user-token").CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	assert.True(t, apierrors.IsForbidden(err), "want forbidden, got %v", err)
+
+	// the admin in system:masters allows the user to list nodes with RBAC.
+	admin := clientWithToken("admin-token")
+	_, err = admin.RbacV1().ClusterRoles().Create(ctx, &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-reader"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list"}}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = admin.RbacV1().ClusterRoleBindings().Create(ctx, &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "user-node-reader"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "node-reader"},
+		Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "user"}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := clientWithToken("user-token").CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// the components in this process are privileged.
+	_, err = clientset.NewForConfigOrDie(cfg).CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+}
+
+func TestStartAPIServer_admission(t *testing.T) {
+	t.Parallel()
+	cfg, shutdown, err := StartAPIServer(Options{})
+	require.NoError(t, err)
+	defer shutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	rqshutdown, err := resourcequotacontroller.StartResourceQuotaController(client, cfg)
+	require.NoError(t, err)
+	defer rqshutdown()
+
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "container1", Image: "k8s.gcr.io/pause:3.5"}}},
+		}
+	}
+
+	// Priority resolves the priority of the pod from its PriorityClass.
+	_, err = client.SchedulingV1().PriorityClasses().Create(ctx, &schedulingv1.PriorityClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "high"},
+		Value:      1000,
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	pod := newPod("pod1")
+	pod.Spec.PriorityClassName = "high"
+	pod, err = client.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, pod.Spec.Priority)
+	assert.Equal(t, int32(1000), *pod.Spec.Priority)
+
+	// DefaultTolerationSeconds adds the tolerations for the not-ready and unreachable nodes.
+	assert.Len(t, pod.Spec.Tolerations, 2)
+
+	// ResourceQuota rejects the pods over the quota, once the controller calculates the usage.
+	_, err = client.CoreV1().ResourceQuotas("default").Create(ctx, &v1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "pods"},
+		Spec:       v1.ResourceQuotaSpec{Hard: v1.ResourceList{v1.ResourcePods: resource.MustParse("2")}},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		q, err := client.CoreV1().ResourceQuotas("default").Get(ctx, "pods", metav1.GetOptions{})
+		return err == nil && q.Status.Used.Pods().Value() == 1
+	}, 30*time.Second, 100*time.Millisecond)
+	_, err = client.CoreV1().Pods("default").Create(ctx, newPod("pod2"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.CoreV1().Pods("default").Create(ctx, newPod("pod3"), metav1.CreateOptions{})
+	assert.True(t, apierrors.IsForbidden(err), "want forbidden, got %v", err)
+
+	// NamespaceLifecycle rejects the objects in the namespaces which don't exist.
+	_, err = client.CoreV1().ConfigMaps("not-found").Create(ctx, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}, metav1.CreateOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "want not found, got %v", err)
+}
+
+func TestStartAPIServer_noAdmission(t *testing.T) {
+	t.Parallel()
+	cfg, shutdown, err := StartAPIServer(Options{AdmissionPlugins: []string{}})
+	require.NoError(t, err)
+	defer shutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+
+	// the objects are stored as they are.
+	_, err = client.CoreV1().ConfigMaps("not-found").Create(context.Background(), &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+}
+
+func TestStartAPIServer_unknownAdmissionPlugin(t *testing.T) {
+	t.Parallel()
+	_, _, err := StartAPIServer(Options{AdmissionPlugins: []string{"Priority", "Unknown"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"Unknown"`)
+}
+
+func TestLocalURL(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		addr  net.Addr
+		https bool
+		want  string
+	}{
+		{
+			name: "loopback address",
+			addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6443},
+			want: "http://127.0.0.1:6443",
+		},
+		{
+			name: "unspecified IPv6 address",
+			addr: &net.TCPAddr{IP: net.IPv6unspecified, Port: 6443},
+			want: "http://127.0.0.1:6443",
+		},
+		{
+			name: "unspecified IPv4 address",
+			addr: &net.TCPAddr{IP: net.IPv4zero, Port: 6443},
+			want: "http://127.0.0.1:6443",
+		},
+		{
+			name:  "HTTPS",
+			addr:  &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6443},
+			https: true,
+			want:  "https://127.0.0.1:6443",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, localURL(tt.addr, tt.https))
+		})
+	}
+}
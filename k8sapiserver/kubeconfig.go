@@ -0,0 +1,34 @@
+package k8sapiserver
+
+import (
+	"golang.org/x/xerrors"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeconfigName is the name of the cluster, the user and the context in the kubeconfig.
+const kubeconfigName = "simulator"
+
+// Kubeconfig returns the kubeconfig in YAML to access the API server with cfg, e.g. by kubectl.
+// It doesn't have the credentials in cfg, which are privileged. Users give their tokens to kubectl with --token.
+func Kubeconfig(cfg *restclient.Config) ([]byte, error) {
+	c := clientcmdapi.NewConfig()
+	c.Clusters[kubeconfigName] = &clientcmdapi.Cluster{
+		Server:                   cfg.Host,
+		CertificateAuthority:     cfg.CAFile,
+		CertificateAuthorityData: cfg.CAData,
+	}
+	c.AuthInfos[kubeconfigName] = &clientcmdapi.AuthInfo{}
+	c.Contexts[kubeconfigName] = &clientcmdapi.Context{
+		Cluster:  kubeconfigName,
+		AuthInfo: kubeconfigName,
+	}
+	c.CurrentContext = kubeconfigName
+
+	b, err := clientcmd.Write(*c)
+	if err != nil {
+		return nil, xerrors.Errorf("encode kubeconfig: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,96 @@
+package k8sapiserver
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+
+	"golang.org/x/xerrors"
+	"k8s.io/apiserver/pkg/authentication/group"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	authenticatorunion "k8s.io/apiserver/pkg/authentication/request/union"
+	"k8s.io/apiserver/pkg/authentication/token/tokenfile"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
+	authorizerunion "k8s.io/apiserver/pkg/authorization/union"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/kubernetes/pkg/controlplane"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+)
+
+// secure configures the control plane to authenticate requests with the static tokens in tokenFile
+// and authorize them with RBAC, instead of allowing all requests.
+// The loopback client trusts caData, and keeps being authenticated as the privileged user.
+func secure(c *controlplane.Config, tokenFile string, caData []byte) error {
+	tokenAuthenticator, err := tokenfile.NewCSV(tokenFile)
+	if err != nil {
+		return xerrors.Errorf("read token file: %w", err)
+	}
+
+	loopback := c.GenericConfig.LoopbackClientConfig
+	loopback.TLSClientConfig.CAData = caData
+
+	// the requests without valid tokens are rejected, because there is no authenticator for anonymous requests.
+	c.GenericConfig.Authentication.Authenticator = group.NewAuthenticatedGroupAdder(authenticatorunion.New(
+		loopbackAuthenticator(loopback.BearerToken, c.GenericConfig.Authentication.APIAudiences),
+		bearertoken.New(tokenAuthenticator),
+	))
+
+	client, err := clientset.NewForConfig(loopback)
+	if err != nil {
+		return xerrors.Errorf("create clientset: %w", err)
+	}
+	// the informers are started with the API server.
+	c.ExtraConfig.VersionedInformers = informers.NewSharedInformerFactory(client, loopback.Timeout)
+	rbacInformers := c.ExtraConfig.VersionedInformers.Rbac().V1()
+	rbacAuthorizer := rbac.New(
+		&rbac.RoleGetter{Lister: rbacInformers.Roles().Lister()},
+		&rbac.RoleBindingLister{Lister: rbacInformers.RoleBindings().Lister()},
+		&rbac.ClusterRoleGetter{Lister: rbacInformers.ClusterRoles().Lister()},
+		&rbac.ClusterRoleBindingLister{Lister: rbacInformers.ClusterRoleBindings().Lister()},
+	)
+	c.GenericConfig.Authorization.Authorizer = authorizerunion.New(authorizerfactory.NewPrivilegedGroups(user.SystemPrivilegedGroup), rbacAuthorizer)
+	c.GenericConfig.RuleResolver = rbacAuthorizer
+
+	return nil
+}
+
+// selfSignedCert generates the self-signed serving certificate for the listener address, and returns it with its PEM.
+// The certificate is valid for localhost and the hostname of this machine,
+// and for all addresses of this machine if the listener address is unspecified.
+func selfSignedCert(addr net.Addr) (tls.Certificate, []byte, error) {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	dnsNames := []string{"localhost"}
+	host := "localhost"
+	if hostname, err := os.Hostname(); err == nil {
+		host = hostname
+	}
+
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		if !tcpAddr.IP.IsUnspecified() {
+			ips = append(ips, tcpAddr.IP)
+		} else {
+			addrs, err := net.InterfaceAddrs()
+			if err != nil {
+				return tls.Certificate{}, nil, xerrors.Errorf("get addresses of this machine: %w", err)
+			}
+			for _, a := range addrs {
+				if ipNet, ok := a.(*net.IPNet); ok {
+					ips = append(ips, ipNet.IP)
+				}
+			}
+		}
+	}
+
+	certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey(host, ips, dnsNames)
+	if err != nil {
+		return tls.Certificate{}, nil, xerrors.Errorf("generate self-signed certificate: %w", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, nil, xerrors.Errorf("load self-signed certificate: %w", err)
+	}
+	return cert, certPEM, nil
+}
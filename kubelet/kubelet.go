@@ -0,0 +1,504 @@
+package kubelet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// RunDurationAnnotation is the annotation of how long the pod runs before it succeeds, in the format of time.ParseDuration,
+	// e.g. `scheduler-simulator/run-duration: "30s"`.
+	// The pods without it keep running until they are deleted.
+	RunDurationAnnotation = "scheduler-simulator/run-duration"
+	// KubeletStoppedAnnotation is the annotation of the node to simulate its kubelet stopping, e.g. by a zone outage.
+	// While it's "true", the node doesn't renew its lease nor report its status, and its pods aren't updated.
+	KubeletStoppedAnnotation = "scheduler-simulator/kubelet-stopped"
+
+	// DefaultStartupDelay is the default of Options.StartupDelay.
+	DefaultStartupDelay = time.Second
+	// DefaultHeartbeatInterval is the default of Options.HeartbeatInterval, the same as the kubelet's default lease renewal.
+	DefaultHeartbeatInterval = 10 * time.Second
+
+	// leaseDurationSeconds is the duration of the node leases, the same as the kubelet's default.
+	leaseDurationSeconds = 40
+	workers              = 5
+)
+
+// Options configures the fake kubelet.
+type Options struct {
+	// StartupDelay is how long the containers of the pods take to start.
+	// The pods are ContainerCreating during it.
+	StartupDelay time.Duration
+	// HeartbeatInterval is how often the nodes renew their leases and report their status.
+	HeartbeatInterval time.Duration
+}
+
+// fakeKubelet plays the kubelets of all nodes.
+// It reports the nodes as Ready, and runs the pods bound to them without running any containers.
+type fakeKubelet struct {
+	client     clientset.Interface
+	opts       Options
+	nodeLister corelisters.NodeLister
+	podLister  corelisters.PodLister
+	nodeQueue  workqueue.RateLimitingInterface
+	podQueue   workqueue.RateLimitingInterface
+	// lastPodIP is the last IP address assigned to the pods.
+	lastPodIP uint32
+}
+
+// StartFakeKubelet starts the fake kubelet which plays the kubelets of all nodes, so that bound pods become Running.
+// The zero values in opts are replaced with the defaults.
+func StartFakeKubelet(client clientset.Interface, opts Options) (
+	func(), // function to shutdown fake kubelet
+	error,
+) {
+	if opts.StartupDelay == 0 {
+		opts.StartupDelay = DefaultStartupDelay
+	}
+	if opts.HeartbeatInterval == 0 {
+		opts.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	nodeInformer := informerFactory.Core().V1().Nodes()
+	podInformer := informerFactory.Core().V1().Pods()
+	k := &fakeKubelet{
+		client:     client,
+		opts:       opts,
+		nodeLister: nodeInformer.Lister(),
+		podLister:  podInformer.Lister(),
+		nodeQueue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "fake-kubelet-node"),
+		podQueue:   workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "fake-kubelet-pod"),
+	}
+
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			node, ok := obj.(*v1.Node)
+			if !ok {
+				return
+			}
+			k.nodeQueue.Add(node.Name)
+			// the pods may be bound before the node is created.
+			k.enqueuePodsOnNode(node.Name)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*v1.Node)
+			if !ok {
+				return
+			}
+			newNode, ok := newObj.(*v1.Node)
+			if !ok {
+				return
+			}
+			if kubeletStopped(oldNode) && !kubeletStopped(newNode) {
+				// the kubelet restarts.
+				k.nodeQueue.Add(newNode.Name)
+				k.enqueuePodsOnNode(newNode.Name)
+			}
+		},
+	})
+	podInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			pod, ok := obj.(*v1.Pod)
+			return ok && pod.Spec.NodeName != ""
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    k.enqueuePod,
+			UpdateFunc: func(_, newObj interface{}) { k.enqueuePod(newObj) },
+		},
+	})
+
+	informerFactory.Start(ctx.Done())
+	for typ, synced := range informerFactory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			cancel()
+			return nil, fmt.Errorf("sync the cache of %v", typ)
+		}
+	}
+
+	go wait.UntilWithContext(ctx, k.heartbeat, opts.HeartbeatInterval)
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for k.processNextNode(ctx) {
+		}
+	}, time.Second)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			for k.processNextPod(ctx) {
+			}
+		}, time.Second)
+	}
+
+	return func() {
+		cancel()
+		k.nodeQueue.ShutDown()
+		k.podQueue.ShutDown()
+	}, nil
+}
+
+func (k *fakeKubelet) enqueuePod(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	k.podQueue.Add(key)
+}
+
+func (k *fakeKubelet) enqueuePodsOnNode(nodeName string) {
+	pods, err := k.podLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName {
+			k.enqueuePod(pod)
+		}
+	}
+}
+
+// heartbeat makes all nodes report their status.
+func (k *fakeKubelet) heartbeat(_ context.Context) {
+	nodes, err := k.nodeLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, node := range nodes {
+		k.nodeQueue.Add(node.Name)
+	}
+}
+
+func (k *fakeKubelet) processNextNode(ctx context.Context) bool {
+	key, quit := k.nodeQueue.Get()
+	if quit {
+		return false
+	}
+	defer k.nodeQueue.Done(key)
+
+	name, ok := key.(string)
+	if !ok {
+		k.nodeQueue.Forget(key)
+		return true
+	}
+	if err := k.syncNode(ctx, name); err != nil {
+		utilruntime.HandleError(fmt.Errorf("sync node %s: %w", name, err))
+		k.nodeQueue.AddRateLimited(key)
+		return true
+	}
+	k.nodeQueue.Forget(key)
+	return true
+}
+
+func (k *fakeKubelet) processNextPod(ctx context.Context) bool {
+	key, quit := k.podQueue.Get()
+	if quit {
+		return false
+	}
+	defer k.podQueue.Done(key)
+
+	podKey, ok := key.(string)
+	if !ok {
+		k.podQueue.Forget(key)
+		return true
+	}
+	if err := k.syncPod(ctx, podKey); err != nil {
+		utilruntime.HandleError(fmt.Errorf("sync pod %s: %w", podKey, err))
+		k.podQueue.AddRateLimited(key)
+		return true
+	}
+	k.podQueue.Forget(key)
+	return true
+}
+
+// syncNode renews the lease of the node, and reports the node as Ready.
+func (k *fakeKubelet) syncNode(ctx context.Context, name string) error {
+	node, err := k.nodeLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get node: %w", err)
+	}
+	if kubeletStopped(node) {
+		return nil
+	}
+
+	if err := k.renewLease(ctx, node); err != nil {
+		return fmt.Errorf("renew lease: %w", err)
+	}
+
+	now := metav1.Now()
+	node = node.DeepCopy()
+	setNodeCondition(node, v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue, Reason: "KubeletReady", Message: "kubelet is posting ready status"}, now)
+	setNodeCondition(node, v1.NodeCondition{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse, Reason: "KubeletHasSufficientMemory", Message: "kubelet has sufficient memory available"}, now)
+	setNodeCondition(node, v1.NodeCondition{Type: v1.NodeDiskPressure, Status: v1.ConditionFalse, Reason: "KubeletHasNoDiskPressure", Message: "kubelet has no disk pressure"}, now)
+	setNodeCondition(node, v1.NodeCondition{Type: v1.NodePIDPressure, Status: v1.ConditionFalse, Reason: "KubeletHasSufficientPID", Message: "kubelet has sufficient PID available"}, now)
+	if _, err := k.client.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update node status: %w", err)
+	}
+	return nil
+}
+
+// kubeletStopped returns whether the kubelet of the node is stopped with KubeletStoppedAnnotation.
+func kubeletStopped(node *v1.Node) bool {
+	return node.Annotations[KubeletStoppedAnnotation] == "true"
+}
+
+// setNodeCondition sets the condition of the node with the heartbeat at now.
+// The transition time is kept if the status doesn't change.
+func setNodeCondition(node *v1.Node, condition v1.NodeCondition, now metav1.Time) {
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	for i, c := range node.Status.Conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		node.Status.Conditions[i] = condition
+		return
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+}
+
+// renewLease renews the lease of the node in the kube-node-lease namespace, or creates it.
+func (k *fakeKubelet) renewLease(ctx context.Context, node *v1.Node) error {
+	leases := k.client.CoordinationV1().Leases(v1.NamespaceNodeLease)
+	now := metav1.NewMicroTime(time.Now())
+	lease, err := leases.Get(ctx, node.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err := leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      node.Name,
+				Namespace: v1.NamespaceNodeLease,
+				// the lease is removed with the node.
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: node.Name, UID: node.UID}},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.StringPtr(node.Name),
+				LeaseDurationSeconds: pointer.Int32Ptr(leaseDurationSeconds),
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+// syncPod moves the pod bound to a node through ContainerCreating to Running,
+// and to Succeeded after the duration in RunDurationAnnotation.
+// The pod being deleted is removed immediately, as if its containers stop at once.
+func (k *fakeKubelet) syncPod(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	pod, err := k.podLister.Pods(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get pod: %w", err)
+	}
+	node, err := k.nodeLister.Get(pod.Spec.NodeName)
+	if apierrors.IsNotFound(err) {
+		// no kubelet runs the pod until the node is created.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get node: %w", err)
+	}
+	if kubeletStopped(node) {
+		// the pod is updated when the kubelet restarts.
+		return nil
+	}
+
+	if pod.DeletionTimestamp != nil {
+		err := k.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{
+			GracePeriodSeconds: pointer.Int64Ptr(0),
+			Preconditions:      metav1.NewUIDPreconditions(string(pod.UID)),
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete pod: %w", err)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	switch {
+	case pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed:
+		return nil
+	case pod.Status.StartTime == nil:
+		pod = pod.DeepCopy()
+		setPodCreating(pod, node, metav1.NewTime(now))
+		k.podQueue.AddAfter(key, k.opts.StartupDelay)
+	case pod.Status.Phase != v1.PodRunning:
+		if wait := pod.Status.StartTime.Add(k.opts.StartupDelay).Sub(now); wait > 0 {
+			k.podQueue.AddAfter(key, wait)
+			return nil
+		}
+		pod = pod.DeepCopy()
+		setPodRunning(pod, k.nextPodIP(), metav1.NewTime(now))
+		if d, ok := runDuration(pod); ok {
+			k.podQueue.AddAfter(key, d)
+		}
+	default:
+		d, ok := runDuration(pod)
+		if !ok {
+			return nil
+		}
+		startedAt := pod.Status.StartTime.Time
+		for _, c := range pod.Status.Conditions {
+			if c.Type == v1.ContainersReady {
+				startedAt = c.LastTransitionTime.Time
+			}
+		}
+		if wait := startedAt.Add(d).Sub(now); wait > 0 {
+			k.podQueue.AddAfter(key, wait)
+			return nil
+		}
+		pod = pod.DeepCopy()
+		setPodSucceeded(pod, metav1.NewTime(now))
+	}
+
+	if _, err := k.client.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update pod status: %w", err)
+	}
+	return nil
+}
+
+// runDuration returns the duration in RunDurationAnnotation of the pod.
+func runDuration(pod *v1.Pod) (time.Duration, bool) {
+	v, ok := pod.Annotations[RunDurationAnnotation]
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		klog.Warningf("annotation %s of pod %s/%s must be a duration: %q", RunDurationAnnotation, pod.Namespace, pod.Name, v)
+		return 0, false
+	}
+	return d, true
+}
+
+// nextPodIP assigns an IP address in 10.244.0.0/16 to a pod.
+func (k *fakeKubelet) nextPodIP() string {
+	n := atomic.AddUint32(&k.lastPodIP, 1)
+	return net.IPv4(10, 244, byte(n>>8), byte(n)).String()
+}
+
+func setPodCreating(pod *v1.Pod, node *v1.Node, now metav1.Time) {
+	pod.Status.Phase = v1.PodPending
+	pod.Status.StartTime = &now
+	for _, a := range node.Status.Addresses {
+		if a.Type == v1.NodeInternalIP {
+			pod.Status.HostIP = a.Address
+			break
+		}
+	}
+	initialized := v1.ConditionTrue
+	if len(pod.Spec.InitContainers) != 0 {
+		initialized = v1.ConditionFalse
+	}
+	setPodConditions(pod, initialized, v1.ConditionFalse, "ContainersNotReady", now)
+	pod.Status.InitContainerStatuses = containerStatuses(pod.Spec.InitContainers, v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "PodInitializing"}}, false)
+	pod.Status.ContainerStatuses = containerStatuses(pod.Spec.Containers, v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}, false)
+}
+
+func setPodRunning(pod *v1.Pod, podIP string, now metav1.Time) {
+	pod.Status.Phase = v1.PodRunning
+	pod.Status.PodIP = podIP
+	pod.Status.PodIPs = []v1.PodIP{{IP: podIP}}
+	setPodConditions(pod, v1.ConditionTrue, v1.ConditionTrue, "", now)
+	// the init containers are completed before the containers start.
+	pod.Status.InitContainerStatuses = containerStatuses(pod.Spec.InitContainers, v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed", StartedAt: now, FinishedAt: now}}, false)
+	pod.Status.ContainerStatuses = containerStatuses(pod.Spec.Containers, v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: now}}, true)
+}
+
+func setPodSucceeded(pod *v1.Pod, now metav1.Time) {
+	pod.Status.Phase = v1.PodSucceeded
+	setPodConditions(pod, v1.ConditionTrue, v1.ConditionFalse, "PodCompleted", now)
+	for i := range pod.Status.ContainerStatuses {
+		s := &pod.Status.ContainerStatuses[i]
+		startedAt := now
+		if s.State.Running != nil {
+			startedAt = s.State.Running.StartedAt
+		}
+		s.State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Completed", StartedAt: startedAt, FinishedAt: now}}
+		s.Ready = false
+		s.Started = pointer.BoolPtr(false)
+	}
+}
+
+// setPodConditions sets the conditions of the pod on the initialization and the readiness of its containers.
+func setPodConditions(pod *v1.Pod, initialized, ready v1.ConditionStatus, reason string, now metav1.Time) {
+	conditions := []v1.PodCondition{
+		{Type: v1.PodInitialized, Status: initialized},
+		{Type: v1.PodReady, Status: ready, Reason: reason},
+		{Type: v1.ContainersReady, Status: ready, Reason: reason},
+	}
+	for _, c := range conditions {
+		c.LastTransitionTime = now
+		setPodCondition(pod, c)
+	}
+}
+
+// setPodCondition sets the condition of the pod. The transition time is kept if the status doesn't change.
+func setPodCondition(pod *v1.Pod, condition v1.PodCondition) {
+	for i, c := range pod.Status.Conditions {
+		if c.Type != condition.Type {
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		pod.Status.Conditions[i] = condition
+		return
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+}
+
+func containerStatuses(containers []v1.Container, state v1.ContainerState, ready bool) []v1.ContainerStatus {
+	statuses := make([]v1.ContainerStatus, 0, len(containers))
+	for _, c := range containers {
+		s := v1.ContainerStatus{
+			Name:    c.Name,
+			Image:   c.Image,
+			State:   state,
+			Ready:   ready,
+			Started: pointer.BoolPtr(state.Running != nil),
+		}
+		if state.Waiting == nil {
+			s.ImageID = c.Image
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
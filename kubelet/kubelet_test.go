@@ -0,0 +1,131 @@
+package kubelet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+)
+
+func TestStartFakeKubelet(t *testing.T) {
+	t.Parallel()
+	cfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{})
+	require.NoError(t, err)
+	defer apiShutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	shutdown, err := StartFakeKubelet(client, Options{StartupDelay: 500 * time.Millisecond, HeartbeatInterval: time.Second})
+	require.NoError(t, err)
+	defer shutdown()
+
+	newPod := func(name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName:   "node1",
+				Containers: []v1.Container{{Name: "container1", Image: "k8s.gcr.io/pause:3.5"}},
+			},
+		}
+	}
+	// the pod is bound before the node is created.
+	_, err = client.CoreV1().Pods("default").Create(ctx, newPod("pod1"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = client.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		node, err := client.CoreV1().Nodes().Get(ctx, "node1", metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		for _, c := range node.Status.Conditions {
+			if c.Type == v1.NodeReady {
+				return c.Status == v1.ConditionTrue
+			}
+		}
+		return false
+	}, 10*time.Second, 100*time.Millisecond)
+	lease, err := client.CoordinationV1().Leases(v1.NamespaceNodeLease).Get(ctx, "node1", metav1.GetOptions{})
+	require.NoError(t, err)
+	renewTime := lease.Spec.RenewTime.Time
+	assert.Eventually(t, func() bool {
+		lease, err := client.CoordinationV1().Leases(v1.NamespaceNodeLease).Get(ctx, "node1", metav1.GetOptions{})
+		return err == nil && lease.Spec.RenewTime.After(renewTime)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		pod, err := client.CoreV1().Pods("default").Get(ctx, "pod1", metav1.GetOptions{})
+		return err == nil && pod.Status.Phase == v1.PodRunning && pod.Status.PodIP != ""
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// the pod with RunDurationAnnotation succeeds after the duration.
+	pod := newPod("pod2")
+	pod.Annotations = map[string]string{RunDurationAnnotation: "1s"}
+	_, err = client.CoreV1().Pods("default").Create(ctx, pod, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		pod, err := client.CoreV1().Pods("default").Get(ctx, "pod2", metav1.GetOptions{})
+		return err == nil && pod.Status.Phase == v1.PodSucceeded && pod.Status.ContainerStatuses[0].State.Terminated != nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	// the pod being deleted gracefully is removed.
+	require.NoError(t, client.CoreV1().Pods("default").Delete(ctx, "pod1", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		_, err := client.CoreV1().Pods("default").Get(ctx, "pod1", metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func TestSetNodeCondition(t *testing.T) {
+	t.Parallel()
+	before := metav1.NewTime(time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(time.Date(2021, 10, 1, 0, 1, 0, 0, time.UTC))
+	tests := []struct {
+		name       string
+		conditions []v1.NodeCondition
+		condition  v1.NodeCondition
+		want       []v1.NodeCondition
+	}{
+		{
+			name:      "add the condition",
+			condition: v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			want:      []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, LastHeartbeatTime: now, LastTransitionTime: now}},
+		},
+		{
+			name:       "keep the transition time if the status doesn't change",
+			conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, LastHeartbeatTime: before, LastTransitionTime: before}},
+			condition:  v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			want:       []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue, LastHeartbeatTime: now, LastTransitionTime: before}},
+		},
+		{
+			name: "update the transition time if the status changes",
+			conditions: []v1.NodeCondition{
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse, LastHeartbeatTime: before, LastTransitionTime: before},
+				{Type: v1.NodeReady, Status: v1.ConditionUnknown, LastHeartbeatTime: before, LastTransitionTime: before},
+			},
+			condition: v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			want: []v1.NodeCondition{
+				{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse, LastHeartbeatTime: before, LastTransitionTime: before},
+				{Type: v1.NodeReady, Status: v1.ConditionTrue, LastHeartbeatTime: now, LastTransitionTime: now},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			node := &v1.Node{Status: v1.NodeStatus{Conditions: tt.conditions}}
+			setNodeCondition(node, tt.condition, now)
+			assert.Equal(t, tt.want, node.Status.Conditions)
+		})
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 )
 
+// assignedPodDelete is the event that an assigned pod is deleted.
+var assignedPodDelete = framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete, Label: "AssignedPodDelete"}
+
 func addAllEventHandlers(
 	sched *Scheduler,
 	informerFactory informers.SharedInformerFactory,
@@ -63,12 +67,63 @@ func addAllEventHandlers(
 			informerFactory.Core().V1().Nodes().Informer().AddEventHandler(
 				buildEvtResHandler(at, framework.Node, "Node"),
 			)
-			//case framework.CSINode:
-			//case framework.CSIDriver:
-			//case framework.CSIStorageCapacity:
-			//case framework.PersistentVolume:
-			//case framework.PersistentVolumeClaim:
-			//case framework.StorageClass:
+		case framework.Pod:
+			// Only the deletion of assigned pods is considered, since it may release the volumes the pods use.
+			if at&framework.Delete != 0 {
+				informerFactory.Core().V1().Pods().Informer().AddEventHandler(
+					cache.FilteringResourceEventHandler{
+						FilterFunc: func(obj interface{}) bool {
+							switch t := obj.(type) {
+							case *v1.Pod:
+								return assignedPod(t)
+							case cache.DeletedFinalStateUnknown:
+								pod, ok := t.Obj.(*v1.Pod)
+								return ok && assignedPod(pod)
+							default:
+								return false
+							}
+						},
+						Handler: cache.ResourceEventHandlerFuncs{
+							DeleteFunc: func(_ interface{}) {
+								sched.SchedulingQueue.MoveAllToActiveOrBackoffQueue(assignedPodDelete)
+							},
+						},
+					},
+				)
+			}
+		case framework.CSINode:
+			informerFactory.Storage().V1().CSINodes().Informer().AddEventHandler(
+				buildEvtResHandler(at, framework.CSINode, "CSINode"),
+			)
+		case framework.CSIDriver:
+			informerFactory.Storage().V1().CSIDrivers().Informer().AddEventHandler(
+				buildEvtResHandler(at, framework.CSIDriver, "CSIDriver"),
+			)
+		case framework.CSIStorageCapacity:
+			informerFactory.Storage().V1beta1().CSIStorageCapacities().Informer().AddEventHandler(
+				buildEvtResHandler(at, framework.CSIStorageCapacity, "CSIStorageCapacity"),
+			)
+		case framework.PersistentVolume:
+			informerFactory.Core().V1().PersistentVolumes().Informer().AddEventHandler(
+				buildEvtResHandler(at, framework.PersistentVolume, "Pv"),
+			)
+		case framework.PersistentVolumeClaim:
+			informerFactory.Core().V1().PersistentVolumeClaims().Informer().AddEventHandler(
+				buildEvtResHandler(at, framework.PersistentVolumeClaim, "Pvc"),
+			)
+		case framework.StorageClass:
+			funcs := buildEvtResHandler(at, framework.StorageClass, "StorageClass")
+			if funcs.AddFunc != nil {
+				// Only the new class with WaitForFirstConsumer can make the pods with unbound PVCs schedulable,
+				// since the claims of the class with Immediate binding mode are bound without the scheduler.
+				addFunc := funcs.AddFunc
+				funcs.AddFunc = func(obj interface{}) {
+					if sc, ok := obj.(*storagev1.StorageClass); ok && sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+						addFunc(obj)
+					}
+				}
+			}
+			informerFactory.Storage().V1().StorageClasses().Informer().AddEventHandler(funcs)
 			//case framework.Service:
 			//default:
 		}
@@ -0,0 +1,255 @@
+package extender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	restclient "k8s.io/client-go/rest"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+)
+
+// defaultHTTPTimeout is the timeout of the requests to the extender when HTTPTimeout isn't configured.
+const defaultHTTPTimeout = 5 * time.Second
+
+// HTTPExtender calls the scheduler extender over HTTP with the JSON protocol of the original kube-scheduler.
+// Each verb is POSTed to {urlPrefix}/{verb}, and the empty verb means the extender doesn't support it.
+type HTTPExtender struct {
+	extenderURL      string
+	filterVerb       string
+	prioritizeVerb   string
+	bindVerb         string
+	weight           int64
+	client           *http.Client
+	nodeCacheCapable bool
+	managedResources sets.String
+	ignorable        bool
+}
+
+// NewHTTPExtender creates HTTPExtender from the extender configuration.
+// The extender with PreemptVerb is rejected since this scheduler doesn't preempt pods.
+func NewHTTPExtender(config *v1beta2config.Extender) (*HTTPExtender, error) {
+	if config.PreemptVerb != "" {
+		return nil, fmt.Errorf("extender %s has preemptVerb %q, but preemption isn't supported", config.URLPrefix, config.PreemptVerb)
+	}
+
+	timeout := config.HTTPTimeout.Duration
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	transport, err := makeTransport(config)
+	if err != nil {
+		return nil, fmt.Errorf("make transport for extender %s: %w", config.URLPrefix, err)
+	}
+
+	managedResources := sets.NewString()
+	for _, r := range config.ManagedResources {
+		managedResources.Insert(r.Name)
+	}
+
+	return &HTTPExtender{
+		extenderURL:      config.URLPrefix,
+		filterVerb:       config.FilterVerb,
+		prioritizeVerb:   config.PrioritizeVerb,
+		bindVerb:         config.BindVerb,
+		weight:           config.Weight,
+		client:           &http.Client{Transport: transport, Timeout: timeout},
+		nodeCacheCapable: config.NodeCacheCapable,
+		managedResources: managedResources,
+		ignorable:        config.Ignorable,
+	}, nil
+}
+
+func makeTransport(config *v1beta2config.Extender) (http.RoundTripper, error) {
+	var cfg restclient.Config
+	if config.TLSConfig != nil {
+		cfg.TLSClientConfig.Insecure = config.TLSConfig.Insecure
+		cfg.TLSClientConfig.ServerName = config.TLSConfig.ServerName
+		cfg.TLSClientConfig.CertFile = config.TLSConfig.CertFile
+		cfg.TLSClientConfig.KeyFile = config.TLSConfig.KeyFile
+		cfg.TLSClientConfig.CAFile = config.TLSConfig.CAFile
+		cfg.TLSClientConfig.CertData = config.TLSConfig.CertData
+		cfg.TLSClientConfig.KeyData = config.TLSConfig.KeyData
+		cfg.TLSClientConfig.CAData = config.TLSConfig.CAData
+	}
+	if config.EnableHTTPS {
+		hasCA := len(cfg.CAFile) > 0 || len(cfg.CAData) > 0
+		if !hasCA {
+			cfg.Insecure = true
+		}
+	}
+	return restclient.TransportFor(&cfg)
+}
+
+// Name returns the URL prefix of the extender. It is used in logs, etc.
+func (h *HTTPExtender) Name() string {
+	return h.extenderURL
+}
+
+// IsIgnorable returns true if the scheduling should go on when the extender fails in filtering.
+func (h *HTTPExtender) IsIgnorable() bool {
+	return h.ignorable
+}
+
+// IsBinder returns true if the extender binds pods.
+func (h *HTTPExtender) IsBinder() bool {
+	return h.bindVerb != ""
+}
+
+// IsInterested returns true if the pod requests or limits any resource managed by the extender.
+// The extender without managed resources is interested in all pods.
+func (h *HTTPExtender) IsInterested(pod *v1.Pod) bool {
+	if h.managedResources.Len() == 0 {
+		return true
+	}
+	for _, containers := range [][]v1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, c := range containers {
+			for _, rl := range []v1.ResourceList{c.Resources.Requests, c.Resources.Limits} {
+				for r := range rl {
+					if h.managedResources.Has(string(r)) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Filter asks the extender which of nodes fit the pod.
+// It returns the nodes which fit, and the reasons of the nodes which don't fit.
+func (h *HTTPExtender) Filter(pod *v1.Pod, nodes []*v1.Node) ([]*v1.Node, extenderv1.FailedNodesMap, extenderv1.FailedNodesMap, error) {
+	if h.filterVerb == "" {
+		return nodes, extenderv1.FailedNodesMap{}, extenderv1.FailedNodesMap{}, nil
+	}
+
+	fromNodeName := make(map[string]*v1.Node, len(nodes))
+	for _, n := range nodes {
+		fromNodeName[n.Name] = n
+	}
+
+	var result extenderv1.ExtenderFilterResult
+	if err := h.send(h.filterVerb, h.extenderArgs(pod, nodes), &result); err != nil {
+		return nil, nil, nil, err
+	}
+	if result.Error != "" {
+		return nil, nil, nil, fmt.Errorf("extender %s returned an error: %s", h.extenderURL, result.Error)
+	}
+
+	var filtered []*v1.Node
+	switch {
+	case h.nodeCacheCapable && result.NodeNames != nil:
+		filtered = make([]*v1.Node, 0, len(*result.NodeNames))
+		for _, name := range *result.NodeNames {
+			n, ok := fromNodeName[name]
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("extender %s claims a filtered node %q which is not found in the input node list", h.extenderURL, name)
+			}
+			filtered = append(filtered, n)
+		}
+	case result.Nodes != nil:
+		filtered = make([]*v1.Node, 0, len(result.Nodes.Items))
+		for i := range result.Nodes.Items {
+			filtered = append(filtered, &result.Nodes.Items[i])
+		}
+	}
+
+	return filtered, result.FailedNodes, result.FailedAndUnresolvableNodes, nil
+}
+
+// Prioritize asks the extender for the scores of nodes.
+// It returns the scores and the weight of the extender.
+// The extender without the prioritize verb gives 0 to all nodes.
+func (h *HTTPExtender) Prioritize(pod *v1.Pod, nodes []*v1.Node) (*extenderv1.HostPriorityList, int64, error) {
+	if h.prioritizeVerb == "" {
+		result := make(extenderv1.HostPriorityList, 0, len(nodes))
+		for _, n := range nodes {
+			result = append(result, extenderv1.HostPriority{Host: n.Name, Score: 0})
+		}
+		return &result, 0, nil
+	}
+
+	var result extenderv1.HostPriorityList
+	if err := h.send(h.prioritizeVerb, h.extenderArgs(pod, nodes), &result); err != nil {
+		return nil, 0, err
+	}
+	return &result, h.weight, nil
+}
+
+// Bind asks the extender to bind the pod to the node.
+func (h *HTTPExtender) Bind(binding *v1.Binding) error {
+	if !h.IsBinder() {
+		return fmt.Errorf("extender %s doesn't support bind", h.extenderURL)
+	}
+
+	args := &extenderv1.ExtenderBindingArgs{
+		PodName:      binding.Name,
+		PodNamespace: binding.Namespace,
+		PodUID:       binding.UID,
+		Node:         binding.Target.Name,
+	}
+	var result extenderv1.ExtenderBindingResult
+	if err := h.send(h.bindVerb, args, &result); err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return fmt.Errorf("extender %s returned an error: %s", h.extenderURL, result.Error)
+	}
+	return nil
+}
+
+// extenderArgs returns the args of filter and prioritize.
+// The extender with NodeCacheCapable receives only the names of nodes.
+func (h *HTTPExtender) extenderArgs(pod *v1.Pod, nodes []*v1.Node) *extenderv1.ExtenderArgs {
+	args := &extenderv1.ExtenderArgs{Pod: pod}
+	if h.nodeCacheCapable {
+		names := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			names = append(names, n.Name)
+		}
+		args.NodeNames = &names
+		return args
+	}
+
+	args.Nodes = &v1.NodeList{Items: make([]v1.Node, 0, len(nodes))}
+	for _, n := range nodes {
+		args.Nodes.Items = append(args.Nodes.Items, *n)
+	}
+	return args
+}
+
+// send POSTs args to the verb of the extender, and decodes the response into result.
+func (h *HTTPExtender) send(verb string, args interface{}, result interface{}) error {
+	out, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("marshal args: %w", err)
+	}
+
+	url := strings.TrimRight(h.extenderURL, "/") + "/" + verb
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(out))
+	if err != nil {
+		return fmt.Errorf("create request to %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed %s with extender at URL %s, code %d", verb, url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response from %s: %w", url, err)
+	}
+	return nil
+}
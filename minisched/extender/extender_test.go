@@ -0,0 +1,326 @@
+package extender
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	schedulerv1 "k8s.io/kube-scheduler/config/v1"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+)
+
+// newExtender starts an in-process extender which responds with handler to the verb,
+// and returns HTTPExtender calling it.
+func newExtender(t *testing.T, config v1beta2config.Extender, verb string, handler func(body []byte) (int, interface{})) *HTTPExtender {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scheduler/"+verb || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		code, resp := handler(body)
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	config.URLPrefix = server.URL + "/scheduler"
+	ext, err := NewHTTPExtender(&config)
+	require.NoError(t, err)
+	return ext
+}
+
+func newNodes(names ...string) []*v1.Node {
+	nodes := make([]*v1.Node, 0, len(names))
+	for _, n := range names {
+		nodes = append(nodes, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: n}})
+	}
+	return nodes
+}
+
+func nodeNames(nodes []*v1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+func TestHTTPExtender_Filter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name             string
+		nodeCacheCapable bool
+		// respond returns the response of the extender to the args.
+		respond          func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult)
+		wantNodes        []string
+		wantFailed       extenderv1.FailedNodesMap
+		wantUnresolvable extenderv1.FailedNodesMap
+		wantErr          bool
+	}{
+		{
+			name: "filter nodes with the node objects",
+			respond: func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult) {
+				if args.Nodes == nil || args.NodeNames != nil {
+					return http.StatusBadRequest, nil
+				}
+				return http.StatusOK, &extenderv1.ExtenderFilterResult{
+					Nodes:                      &v1.NodeList{Items: []v1.Node{args.Nodes.Items[0]}},
+					FailedNodes:                extenderv1.FailedNodesMap{"node2": "reason2"},
+					FailedAndUnresolvableNodes: extenderv1.FailedNodesMap{"node3": "reason3"},
+				}
+			},
+			wantNodes:        []string{"node1"},
+			wantFailed:       extenderv1.FailedNodesMap{"node2": "reason2"},
+			wantUnresolvable: extenderv1.FailedNodesMap{"node3": "reason3"},
+		},
+		{
+			name:             "filter nodes with the node names if the extender is node cache capable",
+			nodeCacheCapable: true,
+			respond: func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult) {
+				if args.Nodes != nil || args.NodeNames == nil {
+					return http.StatusBadRequest, nil
+				}
+				names := []string{"node1", "node3"}
+				return http.StatusOK, &extenderv1.ExtenderFilterResult{
+					NodeNames:   &names,
+					FailedNodes: extenderv1.FailedNodesMap{"node2": "reason2"},
+				}
+			},
+			wantNodes:  []string{"node1", "node3"},
+			wantFailed: extenderv1.FailedNodesMap{"node2": "reason2"},
+		},
+		{
+			name:             "fail if the extender returns the unknown node",
+			nodeCacheCapable: true,
+			respond: func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult) {
+				names := []string{"node4"}
+				return http.StatusOK, &extenderv1.ExtenderFilterResult{NodeNames: &names}
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail if the extender returns an error",
+			respond: func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult) {
+				return http.StatusOK, &extenderv1.ExtenderFilterResult{Error: "error"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail if the extender responds with non-200 code",
+			respond: func(args *extenderv1.ExtenderArgs) (int, *extenderv1.ExtenderFilterResult) {
+				return http.StatusInternalServerError, nil
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ext := newExtender(t, v1beta2config.Extender{FilterVerb: "filter", NodeCacheCapable: tt.nodeCacheCapable}, "filter", func(body []byte) (int, interface{}) {
+				args := &extenderv1.ExtenderArgs{}
+				if err := json.Unmarshal(body, args); err != nil {
+					return http.StatusBadRequest, nil
+				}
+				return tt.respond(args)
+			})
+
+			nodes, failed, unresolvable, err := ext.Filter(&v1.Pod{}, newNodes("node1", "node2", "node3"))
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantNodes, nodeNames(nodes))
+			assert.Equal(t, tt.wantFailed, failed)
+			assert.Equal(t, tt.wantUnresolvable, unresolvable)
+		})
+	}
+}
+
+func TestHTTPExtender_Filter_withoutVerb(t *testing.T) {
+	t.Parallel()
+	ext, err := NewHTTPExtender(&v1beta2config.Extender{URLPrefix: "http://127.0.0.1:0"})
+	require.NoError(t, err)
+
+	nodes, failed, unresolvable, err := ext.Filter(&v1.Pod{}, newNodes("node1"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node1"}, nodeNames(nodes))
+	assert.Empty(t, failed)
+	assert.Empty(t, unresolvable)
+}
+
+func TestHTTPExtender_Prioritize(t *testing.T) {
+	t.Parallel()
+	want := extenderv1.HostPriorityList{{Host: "node1", Score: 3}, {Host: "node2", Score: 10}}
+	ext := newExtender(t, v1beta2config.Extender{PrioritizeVerb: "prioritize", Weight: 2}, "prioritize", func(body []byte) (int, interface{}) {
+		return http.StatusOK, want
+	})
+
+	got, weight, err := ext.Prioritize(&v1.Pod{}, newNodes("node1", "node2"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &want, got)
+	assert.Equal(t, int64(2), weight)
+
+	// the extender without the prioritize verb gives 0 to all nodes.
+	ext, err = NewHTTPExtender(&v1beta2config.Extender{URLPrefix: "http://127.0.0.1:0", Weight: 2})
+	require.NoError(t, err)
+	got, weight, err = ext.Prioritize(&v1.Pod{}, newNodes("node1"))
+	assert.NoError(t, err)
+	assert.Equal(t, &extenderv1.HostPriorityList{{Host: "node1", Score: 0}}, got)
+	assert.Equal(t, int64(0), weight)
+}
+
+func TestHTTPExtender_Bind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		result  *extenderv1.ExtenderBindingResult
+		wantErr bool
+	}{
+		{
+			name:   "bind the pod",
+			result: &extenderv1.ExtenderBindingResult{},
+		},
+		{
+			name:    "fail if the extender returns an error",
+			result:  &extenderv1.ExtenderBindingResult{Error: "error"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got extenderv1.ExtenderBindingArgs
+			ext := newExtender(t, v1beta2config.Extender{BindVerb: "bind"}, "bind", func(body []byte) (int, interface{}) {
+				if err := json.Unmarshal(body, &got); err != nil {
+					return http.StatusBadRequest, nil
+				}
+				return http.StatusOK, tt.result
+			})
+			require.True(t, ext.IsBinder())
+
+			err := ext.Bind(&v1.Binding{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"},
+				Target:     v1.ObjectReference{Kind: "Node", Name: "node1"},
+			})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, extenderv1.ExtenderBindingArgs{PodName: "pod1", PodNamespace: "default", PodUID: "uid1", Node: "node1"}, got)
+		})
+	}
+}
+
+func TestNewHTTPExtender(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		config  v1beta2config.Extender
+		wantErr string
+	}{
+		{
+			name:   "success with the verbs this scheduler calls",
+			config: v1beta2config.Extender{URLPrefix: "http://localhost:8888/scheduler", FilterVerb: "filter", PrioritizeVerb: "prioritize", BindVerb: "bind"},
+		},
+		{
+			name:    "fail with preemptVerb",
+			config:  v1beta2config.Extender{URLPrefix: "http://localhost:8888/scheduler", PreemptVerb: "preempt"},
+			wantErr: `extender http://localhost:8888/scheduler has preemptVerb "preempt", but preemption isn't supported`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewHTTPExtender(&tt.config)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHTTPExtender_IsInterested(t *testing.T) {
+	t.Parallel()
+	newPod := func(requests, limits v1.ResourceList, initRequests v1.ResourceList) *v1.Pod {
+		return &v1.Pod{Spec: v1.PodSpec{
+			Containers:     []v1.Container{{Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}},
+			InitContainers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: initRequests}}},
+		}}
+	}
+	managed := v1.ResourceList{"example.com/gpu": resource.MustParse("1")}
+	cpu := v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}
+	tests := []struct {
+		name             string
+		managedResources []string
+		pod              *v1.Pod
+		want             bool
+	}{
+		{
+			name: "the extender without managed resources is interested in all pods",
+			pod:  newPod(cpu, nil, nil),
+			want: true,
+		},
+		{
+			name:             "interested in the pod requesting the managed resource",
+			managedResources: []string{"example.com/gpu"},
+			pod:              newPod(managed, nil, nil),
+			want:             true,
+		},
+		{
+			name:             "interested in the pod limiting the managed resource",
+			managedResources: []string{"example.com/gpu"},
+			pod:              newPod(nil, managed, nil),
+			want:             true,
+		},
+		{
+			name:             "interested in the pod whose init container requests the managed resource",
+			managedResources: []string{"example.com/gpu"},
+			pod:              newPod(nil, nil, managed),
+			want:             true,
+		},
+		{
+			name:             "not interested in the pod without the managed resources",
+			managedResources: []string{"example.com/gpu"},
+			pod:              newPod(cpu, cpu, nil),
+			want:             false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := &v1beta2config.Extender{URLPrefix: "http://127.0.0.1:0"}
+			for _, r := range tt.managedResources {
+				config.ManagedResources = append(config.ManagedResources, schedulerv1.ExtenderManagedResource{Name: r})
+			}
+			ext, err := NewHTTPExtender(config)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, ext.IsInterested(tt.pod))
+		})
+	}
+}
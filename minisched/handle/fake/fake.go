@@ -0,0 +1,54 @@
+package fake
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// Handle is a fake handle.Handle for the unit tests of plugins.
+type Handle struct {
+	// WaitingPods is the waiting pods. It must not be nil.
+	WaitingPods *waitingpod.Map
+	// Client is returned by ClientSet.
+	Client clientset.Interface
+	// InformerFactory is returned by SharedInformerFactory. It's nil unless the test sets it.
+	InformerFactory informers.SharedInformerFactory
+}
+
+var _ handle.Handle = &Handle{}
+
+// NewHandle returns Handle without waiting pods.
+func NewHandle(client clientset.Interface) *Handle {
+	return &Handle{WaitingPods: waitingpod.NewMap(), Client: client}
+}
+
+// IterateOverWaitingPods iterates over WaitingPods.
+func (h *Handle) IterateOverWaitingPods(callback func(*waitingpod.WaitingPod)) {
+	h.WaitingPods.Iterate(callback)
+}
+
+// GetWaitingPod returns the waiting pod in WaitingPods.
+func (h *Handle) GetWaitingPod(uid types.UID) *waitingpod.WaitingPod {
+	return h.WaitingPods.Get(uid)
+}
+
+// RejectWaitingPod rejects the waiting pod in WaitingPods.
+func (h *Handle) RejectWaitingPod(uid types.UID) {
+	if wp := h.WaitingPods.Get(uid); wp != nil {
+		wp.Reject("", "removed")
+	}
+}
+
+// ClientSet returns Client.
+func (h *Handle) ClientSet() clientset.Interface {
+	return h.Client
+}
+
+// SharedInformerFactory returns InformerFactory.
+func (h *Handle) SharedInformerFactory() informers.SharedInformerFactory {
+	return h.InformerFactory
+}
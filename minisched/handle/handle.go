@@ -0,0 +1,20 @@
+package handle
+
+import (
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// Handle provides plugins with the access to the scheduler. It is the subset of framework.Handle.
+type Handle interface {
+	waitingpod.Handle
+
+	// ClientSet returns a kubernetes clientSet.
+	ClientSet() clientset.Interface
+
+	// SharedInformerFactory returns the informer factory of the scheduler.
+	// The informers created by plugins are started with the scheduler.
+	SharedInformerFactory() informers.SharedInformerFactory
+}
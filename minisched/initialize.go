@@ -1,18 +1,23 @@
 package minisched
 
 import (
+	"context"
 	"fmt"
+	"io"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
-	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/score/nodenumber"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/extender"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
 	"github.com/sanposhiho/mini-kube-scheduler/minisched/queue"
 	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
-	"k8s.io/apimachinery/pkg/types"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
-	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/nodeunschedulable"
 )
 
 type Scheduler struct {
@@ -20,140 +25,416 @@ type Scheduler struct {
 
 	client clientset.Interface
 
-	waitingPods map[types.UID]*waitingpod.WaitingPod
+	// informerFactory is shared with the plugins, and podLister lists the pods assigned to nodes from it.
+	informerFactory informers.SharedInformerFactory
+	podLister       corelisters.PodLister
 
-	filterPlugins   []framework.FilterPlugin
-	preScorePlugins []framework.PreScorePlugin
-	scorePlugins    []framework.ScorePlugin
-	permitPlugins   []framework.PermitPlugin
+	waitingPods *waitingpod.Map
+
+	preFilterPlugins  []framework.PreFilterPlugin
+	filterPlugins     []framework.FilterPlugin
+	postFilterPlugins []framework.PostFilterPlugin
+	preScorePlugins   []framework.PreScorePlugin
+	scorePlugins      []framework.ScorePlugin
+	reservePlugins    []framework.ReservePlugin
+	permitPlugins     []framework.PermitPlugin
+	preBindPlugins    []framework.PreBindPlugin
+	bindPlugins       []framework.BindPlugin
+	postBindPlugins   []framework.PostBindPlugin
+
+	// scorePluginWeight is the weight of each score plugin.
+	scorePluginWeight map[string]int64
+
+	// extenders are called after the plugins in filter, score and bind.
+	extenders []*extender.HTTPExtender
+
+	// stopped is closed when Run returns.
+	stopped chan struct{}
+
+	// bindingCycles tracks the binding cycles in flight.
+	bindingCycles *bindingCycles
+	// bindingCtx is used in binding cycles, and cancelBinding cancels it.
+	bindingCtx    context.Context
+	cancelBinding context.CancelFunc
+
+	// recorder records the results of the plugins.
+	recorder ResultRecorder
+
+	// closers are the plugins which implement io.Closer, e.g. to close their connections.
+	// They are closed when the scheduler is shut down or hands over to the next scheduler.
+	closers map[string]io.Closer
+}
+
+// ResultRecorder records the results of the plugins in the scheduling of pods.
+type ResultRecorder interface {
+	// AddFilterResult records the filtering result of the plugin on the node. reason is "passed" if the node passed the plugin.
+	AddFilterResult(namespace, podName, nodeName, pluginName, reason string)
+	// AddScoreResult records the score which the plugin returned.
+	AddScoreResult(namespace, podName, nodeName, pluginName string, score int64)
+	// AddFinalScoreResult records the score normalized and applied the weight of the plugin.
+	AddFinalScoreResult(namespace, podName, nodeName, pluginName string, finalscore int64)
+	// AddPermitResult records the allows and the reject of the pod waiting in the permit phase.
+	AddPermitResult(namespace, podName string, history []waitingpod.Decision)
+	// Flush records the results of the pod now. It's called when the scheduling of the pod fails.
+	Flush(pod *v1.Pod)
+}
+
+// nopRecorder is ResultRecorder which records nothing.
+type nopRecorder struct{}
+
+func (nopRecorder) AddFilterResult(_, _, _, _, _ string)                 {}
+func (nopRecorder) AddScoreResult(_, _, _, _ string, _ int64)            {}
+func (nopRecorder) AddFinalScoreResult(_, _, _, _ string, _ int64)       {}
+func (nopRecorder) AddPermitResult(_, _ string, _ []waitingpod.Decision) {}
+func (nopRecorder) Flush(_ *v1.Pod)                                      {}
+
+// Option configures Scheduler.
+type Option func(*Scheduler)
+
+// WithResultRecorder makes Scheduler record the results of the plugins with recorder.
+func WithResultRecorder(recorder ResultRecorder) Option {
+	return func(sched *Scheduler) {
+		sched.recorder = recorder
+	}
 }
 
 // =======
 // funcs for initialize
 // =======
 
+// New creates minisched with the plugins configured in profile and the extenders.
+// The plugins which minisched doesn't support are ignored, and nil profile means the default plugins.
 func New(
 	client clientset.Interface,
 	informerFactory informers.SharedInformerFactory,
-) (*Scheduler, error) {
+	profile *v1beta2config.KubeSchedulerProfile,
+	extenders []v1beta2config.Extender,
+	opts ...Option,
+) (_ *Scheduler, retErr error) {
 	sched := &Scheduler{
-		client:      client,
-		waitingPods: map[types.UID]*waitingpod.WaitingPod{},
+		client:          client,
+		informerFactory: informerFactory,
+		podLister:       informerFactory.Core().V1().Pods().Lister(),
+		waitingPods:     waitingpod.NewMap(),
+		stopped:         make(chan struct{}),
+		bindingCycles:   newBindingCycles(),
+		recorder:        nopRecorder{},
+	}
+	for _, opt := range opts {
+		opt(sched)
+	}
+
+	registry := NewRegistry()
+	var customPlugins *v1beta2config.Plugins
+	if profile != nil {
+		customPlugins = profile.Plugins
+	}
+	pluginSets := mergePlugins(defaultPlugins(), customPlugins)
+
+	plugins, err := createPlugins(sched, registry, profile, pluginSets)
+	if err != nil {
+		return nil, fmt.Errorf("create plugins: %w", err)
+	}
+	sched.closers = closers(plugins)
+	defer func() {
+		if retErr != nil {
+			if err := sched.closePlugins(); err != nil {
+				klog.ErrorS(err, "Error closing plugins of the scheduler which failed to be created")
+			}
+		}
+	}()
+
+	less, err := createQueueSortPlugin(plugins, filterUnsupportedPlugins(registry, pluginSets.QueueSort))
+	if err != nil {
+		return nil, fmt.Errorf("create queue sort plugin: %w", err)
+	}
+
+	preFilterP, err := createPreFilterPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.PreFilter))
+	if err != nil {
+		return nil, fmt.Errorf("create pre filter plugins: %w", err)
 	}
+	sched.preFilterPlugins = preFilterP
 
-	filterP, err := createFilterPlugins(sched)
+	filterP, err := createFilterPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.Filter))
 	if err != nil {
 		return nil, fmt.Errorf("create filter plugins: %w", err)
 	}
 	sched.filterPlugins = filterP
 
-	preScoreP, err := createPreScorePlugins(sched)
+	postFilterP, err := createPostFilterPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.PostFilter))
+	if err != nil {
+		return nil, fmt.Errorf("create post filter plugins: %w", err)
+	}
+	sched.postFilterPlugins = postFilterP
+
+	preScoreP, err := createPreScorePlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.PreScore))
 	if err != nil {
 		return nil, fmt.Errorf("create pre score plugins: %w", err)
 	}
 	sched.preScorePlugins = preScoreP
 
-	scoreP, err := createScorePlugins(sched)
+	scoreSet := filterUnsupportedPlugins(registry, pluginSets.Score)
+	scoreP, err := createScorePlugins(plugins, scoreSet)
 	if err != nil {
 		return nil, fmt.Errorf("create score plugins: %w", err)
 	}
 	sched.scorePlugins = scoreP
+	sched.scorePluginWeight = scorePluginWeight(scoreSet)
 
-	permitP, err := createPermitPlugins(sched)
+	reserveP, err := createReservePlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.Reserve))
+	if err != nil {
+		return nil, fmt.Errorf("create reserve plugins: %w", err)
+	}
+	sched.reservePlugins = reserveP
+
+	permitP, err := createPermitPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.Permit))
 	if err != nil {
 		return nil, fmt.Errorf("create permit plugins: %w", err)
 	}
 	sched.permitPlugins = permitP
 
-	events, err := eventsToRegister(sched)
+	preBindP, err := createPreBindPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.PreBind))
+	if err != nil {
+		return nil, fmt.Errorf("create pre bind plugins: %w", err)
+	}
+	sched.preBindPlugins = preBindP
+
+	bindP, err := createBindPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.Bind))
 	if err != nil {
-		return nil, fmt.Errorf("create gvks: %w")
+		return nil, fmt.Errorf("create bind plugins: %w", err)
+	}
+	sched.bindPlugins = bindP
+
+	postBindP, err := createPostBindPlugins(plugins, filterUnsupportedPlugins(registry, pluginSets.PostBind))
+	if err != nil {
+		return nil, fmt.Errorf("create post bind plugins: %w", err)
+	}
+	sched.postBindPlugins = postBindP
+
+	for i := range extenders {
+		ext, err := extender.NewHTTPExtender(&extenders[i])
+		if err != nil {
+			return nil, fmt.Errorf("create extender: %w", err)
+		}
+		sched.extenders = append(sched.extenders, ext)
 	}
 
-	sched.SchedulingQueue = queue.New(events)
+	events := eventsToRegister(plugins)
+
+	sched.SchedulingQueue = queue.New(events, less)
 
 	addAllEventHandlers(sched, informerFactory, unionedGVKs(events))
 
+	sched.bindingCtx, sched.cancelBinding = context.WithCancel(context.Background())
+
 	return sched, nil
 }
 
-func createFilterPlugins(h waitingpod.Handle) ([]framework.FilterPlugin, error) {
-	// nodeunschedulable is FilterPlugin.
-	nodeunschedulableplugin, err := createNodeUnschedulablePlugin()
-	if err != nil {
-		return nil, fmt.Errorf("create nodeunschedulable plugin: %w", err)
+// createPlugins creates all plugins enabled in pluginSets.
+// The plugin enabled in several extension points is created only once, and shared among them.
+func createPlugins(h handle.Handle, registry Registry, profile *v1beta2config.KubeSchedulerProfile, pluginSets *v1beta2config.Plugins) (map[string]framework.Plugin, error) {
+	plugins := map[string]framework.Plugin{}
+	for _, set := range []v1beta2config.PluginSet{
+		pluginSets.QueueSort, pluginSets.PreFilter, pluginSets.Filter, pluginSets.PostFilter, pluginSets.PreScore, pluginSets.Score,
+		pluginSets.Reserve, pluginSets.Permit, pluginSets.PreBind, pluginSets.Bind, pluginSets.PostBind,
+	} {
+		for _, p := range filterUnsupportedPlugins(registry, set).Enabled {
+			if _, ok := plugins[p.Name]; ok {
+				continue
+			}
+			pl, err := registry[p.Name](pluginArgs(profile, p.Name), h)
+			if err != nil {
+				return nil, fmt.Errorf("create %s plugin: %w", p.Name, err)
+			}
+			plugins[p.Name] = pl
+		}
+	}
+
+	return plugins, nil
+}
+
+// closers returns the plugins which implement io.Closer.
+func closers(plugins map[string]framework.Plugin) map[string]io.Closer {
+	ret := map[string]io.Closer{}
+	for name, pl := range plugins {
+		if c, ok := pl.(io.Closer); ok {
+			ret[name] = c
+		}
+	}
+	return ret
+}
+
+// createQueueSortPlugin returns Less of the queue sort plugin. It returns nil if no queue sort plugin is enabled.
+func createQueueSortPlugin(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) (framework.LessFunc, error) {
+	if len(set.Enabled) == 0 {
+		return nil, nil
+	}
+	if len(set.Enabled) > 1 {
+		return nil, fmt.Errorf("only one queue sort plugin can be enabled, but got %d", len(set.Enabled))
 	}
 
-	// We use nodeunschedulable plugin only.
-	filterPlugins := []framework.FilterPlugin{
-		nodeunschedulableplugin.(framework.FilterPlugin),
+	name := set.Enabled[0].Name
+	pl, ok := plugins[name].(framework.QueueSortPlugin)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s does not extend queue sort plugin", name)
+	}
+
+	return pl.Less, nil
+}
+
+func createPreFilterPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PreFilterPlugin, error) {
+	preFilterPlugins := make([]framework.PreFilterPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PreFilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend pre filter plugin", p.Name)
+		}
+		preFilterPlugins = append(preFilterPlugins, pl)
+	}
+
+	return preFilterPlugins, nil
+}
+
+func createFilterPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.FilterPlugin, error) {
+	filterPlugins := make([]framework.FilterPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.FilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend filter plugin", p.Name)
+		}
+		filterPlugins = append(filterPlugins, pl)
 	}
 
 	return filterPlugins, nil
 }
 
-func createPreScorePlugins(h waitingpod.Handle) ([]framework.PreScorePlugin, error) {
-	// nodenumber is FilterPlugin.
-	nodenumberplugin, err := createNodeNumberPlugin(h)
-	if err != nil {
-		return nil, fmt.Errorf("create nodenumber plugin: %w", err)
+func createPostFilterPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PostFilterPlugin, error) {
+	postFilterPlugins := make([]framework.PostFilterPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PostFilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend post filter plugin", p.Name)
+		}
+		postFilterPlugins = append(postFilterPlugins, pl)
 	}
 
-	// We use nodenumber plugin only.
-	preScorePlugins := []framework.PreScorePlugin{
-		nodenumberplugin.(framework.PreScorePlugin),
+	return postFilterPlugins, nil
+}
+
+func createPreScorePlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PreScorePlugin, error) {
+	preScorePlugins := make([]framework.PreScorePlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PreScorePlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend pre score plugin", p.Name)
+		}
+		preScorePlugins = append(preScorePlugins, pl)
 	}
 
 	return preScorePlugins, nil
 }
 
-func createScorePlugins(h waitingpod.Handle) ([]framework.ScorePlugin, error) {
-	// nodenumber is FilterPlugin.
-	nodenumberplugin, err := createNodeNumberPlugin(h)
-	if err != nil {
-		return nil, fmt.Errorf("create nodenumber plugin: %w", err)
+func createScorePlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.ScorePlugin, error) {
+	scorePlugins := make([]framework.ScorePlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.ScorePlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend score plugin", p.Name)
+		}
+		scorePlugins = append(scorePlugins, pl)
 	}
 
-	// We use nodenumber plugin only.
-	filterPlugins := []framework.ScorePlugin{
-		nodenumberplugin.(framework.ScorePlugin),
+	return scorePlugins, nil
+}
+
+func createReservePlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.ReservePlugin, error) {
+	reservePlugins := make([]framework.ReservePlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.ReservePlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend reserve plugin", p.Name)
+		}
+		reservePlugins = append(reservePlugins, pl)
 	}
 
-	return filterPlugins, nil
+	return reservePlugins, nil
 }
 
-func createPermitPlugins(h waitingpod.Handle) ([]framework.PermitPlugin, error) {
-	// nodenumber is PermitPlugin.
-	nodenumberplugin, err := createNodeNumberPlugin(h)
-	if err != nil {
-		return nil, fmt.Errorf("create nodenumber plugin: %w", err)
+func createPermitPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PermitPlugin, error) {
+	permitPlugins := make([]framework.PermitPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PermitPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend permit plugin", p.Name)
+		}
+		permitPlugins = append(permitPlugins, pl)
 	}
 
-	// We use nodenumber plugin only.
-	permitPlugins := []framework.PermitPlugin{
-		nodenumberplugin.(framework.PermitPlugin),
+	return permitPlugins, nil
+}
+
+func createPreBindPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PreBindPlugin, error) {
+	preBindPlugins := make([]framework.PreBindPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PreBindPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend pre bind plugin", p.Name)
+		}
+		preBindPlugins = append(preBindPlugins, pl)
 	}
 
-	return permitPlugins, nil
+	return preBindPlugins, nil
 }
 
-func eventsToRegister(h waitingpod.Handle) (map[framework.ClusterEvent]sets.String, error) {
-	nunschedulablePlugin, err := createNodeUnschedulablePlugin()
-	if err != nil {
-		return nil, fmt.Errorf("create node unschedulable plugin: %w", err)
+func createBindPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.BindPlugin, error) {
+	bindPlugins := make([]framework.BindPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.BindPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend bind plugin", p.Name)
+		}
+		bindPlugins = append(bindPlugins, pl)
 	}
-	nnumberPlugin, err := createNodeNumberPlugin(h)
-	if err != nil {
-		return nil, fmt.Errorf("create node number plugin: %w", err)
+
+	return bindPlugins, nil
+}
+
+func createPostBindPlugins(plugins map[string]framework.Plugin, set v1beta2config.PluginSet) ([]framework.PostBindPlugin, error) {
+	postBindPlugins := make([]framework.PostBindPlugin, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		pl, ok := plugins[p.Name].(framework.PostBindPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not extend post bind plugin", p.Name)
+		}
+		postBindPlugins = append(postBindPlugins, pl)
 	}
 
+	return postBindPlugins, nil
+}
+
+// scorePluginWeight returns the weight of each score plugin. The weight defaults to 1.
+func scorePluginWeight(set v1beta2config.PluginSet) map[string]int64 {
+	weight := make(map[string]int64, len(set.Enabled))
+	for _, p := range set.Enabled {
+		weight[p.Name] = 1
+		if p.Weight != nil && *p.Weight != 0 {
+			weight[p.Name] = int64(*p.Weight)
+		}
+	}
+	return weight
+}
+
+func eventsToRegister(plugins map[string]framework.Plugin) map[framework.ClusterEvent]sets.String {
 	clusterEventMap := make(map[framework.ClusterEvent]sets.String)
-	nunschedulablePluginEvents := nunschedulablePlugin.(framework.EnqueueExtensions).EventsToRegister()
-	registerClusterEvents(nunschedulablePlugin.Name(), clusterEventMap, nunschedulablePluginEvents)
-	nnumberPluginEvents := nnumberPlugin.(framework.EnqueueExtensions).EventsToRegister()
-	registerClusterEvents(nunschedulablePlugin.Name(), clusterEventMap, nnumberPluginEvents)
+	for name, pl := range plugins {
+		ext, ok := pl.(framework.EnqueueExtensions)
+		if !ok {
+			continue
+		}
+		registerClusterEvents(name, clusterEventMap, ext.EventsToRegister())
+	}
 
-	return clusterEventMap, nil
+	return clusterEventMap
 }
 
 func registerClusterEvents(name string, eventToPlugins map[framework.ClusterEvent]sets.String, evts []framework.ClusterEvent) {
@@ -177,37 +458,3 @@ func unionedGVKs(m map[framework.ClusterEvent]sets.String) map[framework.GVK]fra
 	}
 	return gvkMap
 }
-
-// =====
-// initialize plugins
-// =====
-//
-// we only use nodeunschedulable and nodenumber
-// Original kube-scheduler is implemented so that we can select which plugins to enable
-
-var (
-	nodeunschedulableplugin framework.Plugin
-	nodenumberplugin        framework.Plugin
-)
-
-func createNodeUnschedulablePlugin() (framework.Plugin, error) {
-	if nodeunschedulableplugin != nil {
-		return nodeunschedulableplugin, nil
-	}
-
-	p, err := nodeunschedulable.New(nil, nil)
-	nodeunschedulableplugin = p
-
-	return p, err
-}
-
-func createNodeNumberPlugin(h waitingpod.Handle) (framework.Plugin, error) {
-	if nodenumberplugin != nil {
-		return nodenumberplugin, nil
-	}
-
-	p, err := nodenumber.New(nil, h)
-	nodenumberplugin = p
-
-	return p, err
-}
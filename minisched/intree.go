@@ -0,0 +1,77 @@
+package minisched
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	plfeature "k8s.io/kubernetes/pkg/scheduler/framework/plugins/feature"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumebinding"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumerestrictions"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// inTreePlugin adapts the factory of an in-tree plugin to PluginFactory.
+// The in-tree plugin gets framework.Handle with the client and the informer factory of minisched.
+// The other features of framework.Handle, like the snapshot of NodeInfos and the waiting pods, aren't available from it.
+func inTreePlugin(factory frameworkruntime.PluginFactory) PluginFactory {
+	return func(configuration runtime.Object, h handle.Handle) (framework.Plugin, error) {
+		fh, err := frameworkruntime.NewFramework(nil, nil,
+			frameworkruntime.WithClientSet(h.ClientSet()),
+			frameworkruntime.WithInformerFactory(h.SharedInformerFactory()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create framework handle: %w", err)
+		}
+		return factory(configuration, fh)
+	}
+}
+
+// newVolumeBinding creates VolumeBinding plugin with the args converted to the internal type.
+func newVolumeBinding(configuration runtime.Object, fh framework.Handle) (framework.Plugin, error) {
+	args, err := decodeVolumeBindingArgs(configuration)
+	if err != nil {
+		return nil, fmt.Errorf("decode args: %w", err)
+	}
+	return volumebinding.New(args, fh)
+}
+
+// newVolumeRestrictions creates VolumeRestrictions plugin.
+// ReadWriteOncePod access mode isn't checked since it needs the snapshot of NodeInfos.
+func newVolumeRestrictions(configuration runtime.Object, fh framework.Handle) (framework.Plugin, error) {
+	return volumerestrictions.New(configuration, fh, plfeature.Features{})
+}
+
+// decodeVolumeBindingArgs converts the args in PluginConfig to the internal VolumeBindingArgs, and sets the default values.
+// The args are v1beta2 VolumeBindingArgs in the default configuration of the simulator, or raw JSON from users.
+// nil args means all default values.
+func decodeVolumeBindingArgs(obj runtime.Object) (*config.VolumeBindingArgs, error) {
+	versioned := &v1beta2config.VolumeBindingArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *v1beta2config.VolumeBindingArgs:
+		versioned = t.DeepCopy()
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(versioned); err != nil {
+			return nil, fmt.Errorf("decode raw args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type VolumeBindingArgs, got %T", obj)
+	}
+
+	scheme.Scheme.Default(versioned)
+	args := &config.VolumeBindingArgs{}
+	if err := scheme.Scheme.Convert(versioned, args, nil); err != nil {
+		return nil, fmt.Errorf("convert args: %w", err)
+	}
+	return args, nil
+}
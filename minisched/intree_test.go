@@ -0,0 +1,194 @@
+package minisched
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/nodevolumelimits"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumebinding"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumerestrictions"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumezone"
+	"k8s.io/utils/pointer"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+	"github.com/sanposhiho/mini-kube-scheduler/pvcontroller"
+)
+
+func TestDecodeVolumeBindingArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		obj     runtime.Object
+		want    *config.VolumeBindingArgs
+		wantErr bool
+	}{
+		{
+			name: "nil args means the default values",
+			obj:  nil,
+			want: &config.VolumeBindingArgs{BindTimeoutSeconds: 600},
+		},
+		{
+			name: "v1beta2 args in the default configuration",
+			obj:  &v1beta2config.VolumeBindingArgs{BindTimeoutSeconds: pointer.Int64Ptr(30)},
+			want: &config.VolumeBindingArgs{BindTimeoutSeconds: 30},
+		},
+		{
+			name: "raw args from users",
+			obj:  &runtime.Unknown{Raw: []byte(`{"bindTimeoutSeconds":10}`)},
+			want: &config.VolumeBindingArgs{BindTimeoutSeconds: 10},
+		},
+		{
+			name:    "unknown field in raw args",
+			obj:     &runtime.Unknown{Raw: []byte(`{"bindTimeout":10}`)},
+			wantErr: true,
+		},
+		{
+			name:    "args of another plugin",
+			obj:     &v1beta2config.NodeAffinityArgs{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := decodeVolumeBindingArgs(tt.obj)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScheduler_volumePlugins(t *testing.T) {
+	t.Parallel()
+	cfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{})
+	require.NoError(t, err)
+	defer apiShutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	pvShutdown, err := pvcontroller.StartPersistentVolumeController(client, pvcontroller.Options{})
+	require.NoError(t, err)
+	defer pvShutdown()
+
+	for _, zone := range []string{"zone-a", "zone-b"} {
+		_, err := client.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-" + zone,
+			Labels: map[string]string{v1.LabelTopologyZone: zone},
+		}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	_, err = client.StorageV1().CSIDrivers().Create(ctx, &storagev1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: "csi.example.com"},
+		Spec:       storagev1.CSIDriverSpec{StorageCapacity: pointer.BoolPtr(true)},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	waitForFirstConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+	_, err = client.StorageV1().StorageClasses().Create(ctx, &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: "wffc"},
+		Provisioner:       "csi.example.com",
+		VolumeBindingMode: &waitForFirstConsumer,
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	// only zone-b has the capacity of the class.
+	capacity := resource.MustParse("10Gi")
+	_, err = client.StorageV1beta1().CSIStorageCapacities("default").Create(ctx, &storagev1beta1.CSIStorageCapacity{
+		ObjectMeta:       metav1.ObjectMeta{Name: "wffc-b"},
+		StorageClassName: "wffc",
+		NodeTopology:     &metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelTopologyZone: "zone-b"}},
+		Capacity:         &capacity,
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	enabled := func(names ...string) v1beta2config.PluginSet {
+		set := v1beta2config.PluginSet{}
+		for _, n := range names {
+			set.Enabled = append(set.Enabled, v1beta2config.Plugin{Name: n})
+		}
+		return set
+	}
+	disabledAll := v1beta2config.PluginSet{Disabled: []v1beta2config.Plugin{{Name: "*"}}}
+	profile := &v1beta2config.KubeSchedulerProfile{Plugins: &v1beta2config.Plugins{
+		PreFilter: enabled(volumerestrictions.Name, volumebinding.Name),
+		Filter:    enabled(volumerestrictions.Name, nodevolumelimits.CSIName, volumebinding.Name, volumezone.Name),
+		PreScore:  disabledAll,
+		Score:     disabledAll,
+		Reserve:   enabled(volumebinding.Name),
+		Permit:    disabledAll,
+		PreBind:   enabled(volumebinding.Name),
+	}}
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	sched, err := New(client, informerFactory, profile, nil)
+	require.NoError(t, err)
+	schedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	informerFactory.Start(schedCtx.Done())
+	informerFactory.WaitForCacheSync(schedCtx.Done())
+	go sched.Run(schedCtx)
+
+	createClaim := func(name string) {
+		class := "wffc"
+		_, err := client.CoreV1().PersistentVolumeClaims("default").Create(ctx, &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				Resources:        v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+			},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	createPod := func(name, claimName string) {
+		_, err := client.CoreV1().Pods("default").Create(ctx, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: "container1", Image: "k8s.gcr.io/pause:3.5"}},
+				Volumes: []v1.Volume{{
+					Name:         "volume1",
+					VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName}},
+				}},
+			},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	nodeName := func(name string) string {
+		pod, err := client.CoreV1().Pods("default").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return ""
+		}
+		return pod.Spec.NodeName
+	}
+
+	// the volume is provisioned in zone-b, and the pod is bound after the claim is bound.
+	createClaim("claim1")
+	createPod("pod1", "claim1")
+	assert.Eventually(t, func() bool { return nodeName("pod1") == "node-zone-b" }, 30*time.Second, 100*time.Millisecond)
+	claim, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "claim1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.ClaimBound, claim.Status.Phase)
+
+	// the pod whose claim doesn't exist is retried when the claim is created.
+	// It may be retried before VolumeBinding plugin sees the claim, and then it's retried after it stays in unschedulableQ for a while.
+	createPod("pod2", "claim2")
+	time.Sleep(time.Second)
+	assert.Empty(t, nodeName("pod2"))
+	createClaim("claim2")
+	assert.Eventually(t, func() bool { return nodeName("pod2") == "node-zone-b" }, 120*time.Second, 100*time.Millisecond)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
 
 	"k8s.io/apimachinery/pkg/types"
 
@@ -17,8 +18,12 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	extenderv1 "k8s.io/kube-scheduler/extender/v1"
 )
 
 // ======
@@ -26,31 +31,58 @@ import (
 // ======
 
 func (sched *Scheduler) Run(ctx context.Context) {
+	defer close(sched.stopped)
+	sched.SchedulingQueue.Run(ctx)
 	wait.UntilWithContext(ctx, sched.scheduleOne, 0)
 }
 
 func (sched *Scheduler) scheduleOne(ctx context.Context) {
 	klog.Info("minischeduler: Try to get pod from queue....")
 	pod := sched.SchedulingQueue.NextPod()
+	if pod == nil {
+		// the queue is closed.
+		return
+	}
 	klog.Info("minischeduler: Start schedule: pod name:" + pod.Name)
+	podSchedulingCycle := sched.SchedulingQueue.SchedulingCycle()
 
 	state := framework.NewCycleState()
 
+	// pre filter
+	status := sched.RunPreFilterPlugins(ctx, state, pod)
+	if !status.IsSuccess() {
+		klog.Error(status.AsError())
+		sched.ErrorFunc(pod, preFilterError(pod, status), podSchedulingCycle)
+		return
+	}
+	klog.Info("minischeduler: ran pre filter plugins successfully")
+
 	// get nodes
 	nodes, err := sched.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		klog.Error(err)
-		sched.ErrorFunc(pod, err)
+		sched.ErrorFunc(pod, err, podSchedulingCycle)
 		return
 	}
 	klog.Info("minischeduler: Get Nodes successfully")
 	klog.Info("minischeduler: got nodes: ", nodes)
 
+	nodeInfos, err := sched.nodeInfos(nodes.Items)
+	if err != nil {
+		klog.Error(err)
+		sched.ErrorFunc(pod, err, podSchedulingCycle)
+		return
+	}
+
 	// filter
-	fasibleNodes, err := sched.RunFilterPlugins(ctx, state, pod, nodes.Items)
+	fasibleNodes, err := sched.RunFilterPlugins(ctx, state, pod, nodeInfos)
 	if err != nil {
 		klog.Error(err)
-		sched.ErrorFunc(pod, err)
+		if fitErr, ok := err.(*framework.FitError); ok {
+			// post filter
+			sched.RunPostFilterPlugins(ctx, state, pod, fitErr.Diagnosis.NodeToStatusMap)
+		}
+		sched.ErrorFunc(pod, err, podSchedulingCycle)
 		return
 	}
 
@@ -58,10 +90,10 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 	klog.Info("minischeduler: fasible nodes: ", fasibleNodes)
 
 	// pre score
-	status := sched.RunPreScorePlugins(ctx, state, pod, fasibleNodes)
+	status = sched.RunPreScorePlugins(ctx, state, pod, fasibleNodes)
 	if !status.IsSuccess() {
 		klog.Error(status.AsError())
-		sched.ErrorFunc(pod, err)
+		sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
 		return
 	}
 	klog.Info("minischeduler: ran pre score plugins successfully")
@@ -70,7 +102,7 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 	score, status := sched.RunScorePlugins(ctx, state, pod, fasibleNodes)
 	if !status.IsSuccess() {
 		klog.Error(status.AsError())
-		sched.ErrorFunc(pod, err)
+		sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
 		return
 	}
 
@@ -80,40 +112,90 @@ func (sched *Scheduler) scheduleOne(ctx context.Context) {
 	nodename, err := sched.selectHost(score)
 	if err != nil {
 		klog.Error(err)
-		sched.ErrorFunc(pod, err)
+		sched.ErrorFunc(pod, err, podSchedulingCycle)
 		return
 	}
 
 	klog.Info("minischeduler: pod " + pod.Name + " will be bound to node " + nodename)
 
+	// the plugins from reserve get the pod assumed on the node as the original kube-scheduler does,
+	// since some of them, like VolumeBinding, read the node from the pod.
+	// The permit plugins get the original pod so that the waiting pods can be handed over as they are.
+	assumedPod := pod.DeepCopy()
+	assumedPod.Spec.NodeName = nodename
+
+	status = sched.RunReservePluginsReserve(ctx, state, assumedPod, nodename)
+	if !status.IsSuccess() {
+		klog.Error(status.AsError())
+		sched.RunReservePluginsUnreserve(ctx, state, assumedPod, nodename)
+		sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
+		return
+	}
+
 	status = sched.RunPermitPlugins(ctx, state, pod, nodename)
 	if status.Code() != framework.Wait && !status.IsSuccess() {
 		klog.Error(status.AsError())
-		sched.ErrorFunc(pod, err)
+		sched.RunReservePluginsUnreserve(ctx, state, assumedPod, nodename)
+		sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
 		return
 	}
 
+	sched.bindingCycles.start(pod)
 	go func() {
-		ctx := ctx
+		defer sched.bindingCycles.finish(pod)
+		// binding cycles continue after the scheduling loop stops, until Shutdown cancels them.
+		ctx := sched.bindingCtx
 
 		status := sched.WaitOnPermit(ctx, pod)
 		if !status.IsSuccess() {
 			klog.Error(status.AsError())
-			sched.ErrorFunc(pod, err)
+			sched.RunReservePluginsUnreserve(ctx, state, assumedPod, nodename)
+			sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
 			return
 		}
 
-		if err := sched.Bind(ctx, nil, pod, nodename); err != nil {
-			klog.Error(err)
-			sched.ErrorFunc(pod, err)
+		status = sched.RunPreBindPlugins(ctx, state, assumedPod, nodename)
+		if !status.IsSuccess() {
+			klog.Error(status.AsError())
+			sched.RunReservePluginsUnreserve(ctx, state, assumedPod, nodename)
+			sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
+			return
+		}
+
+		status = sched.RunBindPlugins(ctx, state, assumedPod, nodename)
+		if !status.IsSuccess() {
+			klog.Error(status.AsError())
+			sched.RunReservePluginsUnreserve(ctx, state, assumedPod, nodename)
+			sched.ErrorFunc(pod, status.AsError(), podSchedulingCycle)
 			return
 		}
 		klog.Info("minischeduler: Bind Pod successfully")
+
+		sched.RunPostBindPlugins(ctx, state, assumedPod, nodename)
 	}()
 }
 
-func (sched *Scheduler) RunFilterPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []v1.Node) ([]*v1.Node, error) {
-	feasibleNodes := make([]*v1.Node, 0, len(nodes))
+// RunPreFilterPlugins runs the pre filter plugins.
+// If any of them fails, it stops running the rest and returns the failure.
+func (sched *Scheduler) RunPreFilterPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod) *framework.Status {
+	for _, pl := range sched.preFilterPlugins {
+		status := pl.PreFilter(ctx, state, pod)
+		if !status.IsSuccess() {
+			status.SetFailedPlugin(pl.Name())
+			if status.IsUnschedulable() {
+				return status
+			}
+			err := status.AsError()
+			klog.ErrorS(err, "Failed running PreFilter plugin", "plugin", pl.Name(), "pod", klog.KObj(pod))
+			return framework.AsStatus(fmt.Errorf("running PreFilter plugin %q: %w", pl.Name(), err)).WithFailedPlugin(pl.Name())
+		}
+	}
+
+	return nil
+}
+
+func (sched *Scheduler) RunFilterPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfos []*framework.NodeInfo) ([]*v1.Node, error) {
+	feasibleNodes := make([]*v1.Node, 0, len(nodeInfos))
 
 	diagnosis := framework.Diagnosis{
 		NodeToStatusMap:      make(framework.NodeToStatusMap),
@@ -121,25 +203,32 @@ func (sched *Scheduler) RunFilterPlugins(ctx context.Context, state *framework.C
 	}
 
 	// TODO: consider about nominated pod
-	for _, n := range nodes {
-		n := n
-		nodeInfo := framework.NewNodeInfo()
-		nodeInfo.SetNode(&n)
-
+	for _, nodeInfo := range nodeInfos {
 		status := framework.NewStatus(framework.Success)
 		for _, pl := range sched.filterPlugins {
 			status = pl.Filter(ctx, state, pod, nodeInfo)
 			if !status.IsSuccess() {
+				sched.recorder.AddFilterResult(pod.Namespace, pod.Name, nodeInfo.Node().Name, pl.Name(), status.Message())
 				status.SetFailedPlugin(pl.Name())
+				diagnosis.NodeToStatusMap[nodeInfo.Node().Name] = status
 				diagnosis.UnschedulablePlugins.Insert(status.FailedPlugin())
 				break
 			}
+			sched.recorder.AddFilterResult(pod.Namespace, pod.Name, nodeInfo.Node().Name, pl.Name(), resultstore.PassedFilterMessage)
 		}
 		if status.IsSuccess() {
 			feasibleNodes = append(feasibleNodes, nodeInfo.Node())
 		}
 	}
 
+	if len(feasibleNodes) != 0 {
+		var err error
+		feasibleNodes, err = sched.findNodesThatPassExtenders(pod, feasibleNodes, diagnosis.NodeToStatusMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(feasibleNodes) == 0 {
 		return nil, &framework.FitError{
 			Pod:       pod,
@@ -150,6 +239,63 @@ func (sched *Scheduler) RunFilterPlugins(ctx context.Context, state *framework.C
 	return feasibleNodes, nil
 }
 
+// findNodesThatPassExtenders filters feasibleNodes with the extenders interested in the pod,
+// and records why nodes don't pass them in statuses.
+// The error of the extender with Ignorable is ignored.
+func (sched *Scheduler) findNodesThatPassExtenders(pod *v1.Pod, feasibleNodes []*v1.Node, statuses framework.NodeToStatusMap) ([]*v1.Node, error) {
+	for _, ext := range sched.extenders {
+		if len(feasibleNodes) == 0 {
+			break
+		}
+		if !ext.IsInterested(pod) {
+			continue
+		}
+
+		filtered, failed, failedAndUnresolvable, err := ext.Filter(pod, feasibleNodes)
+		if err != nil {
+			if ext.IsIgnorable() {
+				klog.InfoS("Skipping extender as it returned error and has ignorable flag set", "extender", ext.Name(), "err", err)
+				continue
+			}
+			return nil, fmt.Errorf("running extender %q filter: %w", ext.Name(), err)
+		}
+
+		for name, reason := range failedAndUnresolvable {
+			statuses[name] = framework.NewStatus(framework.UnschedulableAndUnresolvable, reason)
+		}
+		for name, reason := range failed {
+			if _, ok := failedAndUnresolvable[name]; ok {
+				continue
+			}
+			statuses[name] = framework.NewStatus(framework.Unschedulable, reason)
+		}
+		feasibleNodes = filtered
+	}
+
+	return feasibleNodes, nil
+}
+
+// RunPostFilterPlugins runs the post filter plugins for the pod which no node fits.
+// It stops at the first plugin which makes the pod schedulable.
+// minisched doesn't support the preemption, so the nominated node in the result is only logged.
+func (sched *Scheduler) RunPostFilterPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) {
+	for _, pl := range sched.postFilterPlugins {
+		result, status := pl.PostFilter(ctx, state, pod, filteredNodeStatusMap)
+		if status.IsSuccess() {
+			nominated := ""
+			if result != nil {
+				nominated = result.NominatedNodeName
+			}
+			klog.InfoS("PostFilter plugin made the pod schedulable", "plugin", pl.Name(), "pod", klog.KObj(pod), "nominatedNode", nominated)
+			return
+		}
+		if !status.IsUnschedulable() {
+			klog.ErrorS(status.AsError(), "Failed running PostFilter plugin", "plugin", pl.Name(), "pod", klog.KObj(pod))
+			return
+		}
+	}
+}
+
 func (sched *Scheduler) RunPreScorePlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
 	for _, pl := range sched.preScorePlugins {
 		status := pl.PreScore(ctx, state, pod, nodes)
@@ -164,27 +310,32 @@ func (sched *Scheduler) RunPreScorePlugins(ctx context.Context, state *framework
 func (sched *Scheduler) RunScorePlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) (framework.NodeScoreList, *framework.Status) {
 	scoresMap := sched.createPluginToNodeScores(nodes)
 
-	for index, n := range nodes {
-		for _, pl := range sched.scorePlugins {
+	for _, pl := range sched.scorePlugins {
+		for index, n := range nodes {
 			score, status := pl.Score(ctx, state, pod, n.Name)
 			if !status.IsSuccess() {
 				return nil, status
 			}
+			sched.recorder.AddScoreResult(pod.Namespace, pod.Name, n.Name, pl.Name(), score)
 			scoresMap[pl.Name()][index] = framework.NodeScore{
 				Name:  n.Name,
 				Score: score,
 			}
+		}
 
-			if pl.ScoreExtensions() != nil {
-				status := pl.ScoreExtensions().NormalizeScore(ctx, state, pod, scoresMap[pl.Name()])
-				if !status.IsSuccess() {
-					return nil, status
-				}
+		if pl.ScoreExtensions() != nil {
+			status := pl.ScoreExtensions().NormalizeScore(ctx, state, pod, scoresMap[pl.Name()])
+			if !status.IsSuccess() {
+				return nil, status
 			}
 		}
-	}
 
-	// TODO: plugin weight
+		weight := sched.scorePluginWeight[pl.Name()]
+		for index, ns := range scoresMap[pl.Name()] {
+			scoresMap[pl.Name()][index].Score *= weight
+			sched.recorder.AddFinalScoreResult(pod.Namespace, pod.Name, ns.Name, pl.Name(), scoresMap[pl.Name()][index].Score)
+		}
+	}
 
 	result := make(framework.NodeScoreList, 0, len(nodes))
 
@@ -195,9 +346,57 @@ func (sched *Scheduler) RunScorePlugins(ctx context.Context, state *framework.Cy
 		}
 	}
 
+	sched.addExtenderScores(pod, nodes, result)
+
 	return result, nil
 }
 
+// addExtenderScores adds the scores from the extenders interested in the pod to scores.
+// The score of each extender is multiplied by its weight, and scaled to the range of the score plugins.
+// The extender which fails is ignored, as the original kube-scheduler does.
+func (sched *Scheduler) addExtenderScores(pod *v1.Pod, nodes []*v1.Node, scores framework.NodeScoreList) {
+	combinedScores := make(map[string]int64, len(nodes))
+	for _, ext := range sched.extenders {
+		if !ext.IsInterested(pod) {
+			continue
+		}
+		prioritizedList, weight, err := ext.Prioritize(pod, nodes)
+		if err != nil {
+			klog.V(5).InfoS("Failed to run extender's priority function. No score given by this extender.", "error", err, "pod", klog.KObj(pod), "extender", ext.Name())
+			continue
+		}
+		for _, hp := range *prioritizedList {
+			combinedScores[hp.Host] += hp.Score * weight
+		}
+	}
+
+	for i := range scores {
+		scores[i].Score += combinedScores[scores[i].Name] * (framework.MaxNodeScore / extenderv1.MaxExtenderPriority)
+	}
+}
+
+// RunReservePluginsReserve runs the Reserve method of reserve plugins.
+// If any of them fails, it stops running the rest and returns the failure.
+func (sched *Scheduler) RunReservePluginsReserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	for _, pl := range sched.reservePlugins {
+		status := pl.Reserve(ctx, state, pod, nodeName)
+		if !status.IsSuccess() {
+			err := status.AsError()
+			klog.ErrorS(err, "Failed running Reserve plugin", "plugin", pl.Name(), "pod", klog.KObj(pod))
+			return framework.AsStatus(fmt.Errorf("running Reserve plugin %q: %w", pl.Name(), err))
+		}
+	}
+	return nil
+}
+
+// RunReservePluginsUnreserve runs the Unreserve method of reserve plugins in the reverse order
+// to release the resources reserved for the pod.
+func (sched *Scheduler) RunReservePluginsUnreserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	for i := len(sched.reservePlugins) - 1; i >= 0; i-- {
+		sched.reservePlugins[i].Unreserve(ctx, state, pod, nodeName)
+	}
+}
+
 func (sched *Scheduler) RunPermitPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (status *framework.Status) {
 	pluginsWaitTime := make(map[string]time.Duration)
 	statusCode := framework.Success
@@ -227,7 +426,7 @@ func (sched *Scheduler) RunPermitPlugins(ctx context.Context, state *framework.C
 
 	if statusCode == framework.Wait {
 		waitingPod := waitingpod.NewWaitingPod(pod, pluginsWaitTime)
-		sched.waitingPods[pod.UID] = waitingPod
+		sched.waitingPods.Add(waitingPod)
 		msg := fmt.Sprintf("one or more plugins asked to wait and no plugin rejected pod %q", pod.Name)
 		klog.InfoS("One or more plugins asked to wait and no plugin rejected pod", "pod", klog.KObj(pod))
 		return framework.NewStatus(framework.Wait, msg)
@@ -238,15 +437,17 @@ func (sched *Scheduler) RunPermitPlugins(ctx context.Context, state *framework.C
 
 // WaitOnPermit will block, if the pod is a waiting pod, until the waiting pod is rejected or allowed.
 func (sched *Scheduler) WaitOnPermit(ctx context.Context, pod *v1.Pod) *framework.Status {
-	waitingPod := sched.waitingPods[pod.UID]
+	waitingPod := sched.waitingPods.Get(pod.UID)
 	if waitingPod == nil {
 		return nil
 	}
-	defer delete(sched.waitingPods, pod.UID)
+	defer sched.waitingPods.Remove(pod.UID)
 
 	klog.InfoS("Pod waiting on permit", "pod", klog.KObj(pod))
 
 	s := waitingPod.GetSignal()
+	// record the history before the pod is removed, since nobody can see it after that.
+	sched.recorder.AddPermitResult(pod.Namespace, pod.Name, waitingPod.GetHistory())
 
 	if !s.IsSuccess() {
 		if s.IsUnschedulable() {
@@ -263,6 +464,65 @@ func (sched *Scheduler) WaitOnPermit(ctx context.Context, pod *v1.Pod) *framewor
 	return nil
 }
 
+// RunPreBindPlugins runs the pre bind plugins.
+// If any of them fails, it stops running the rest and returns the failure.
+func (sched *Scheduler) RunPreBindPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	for _, pl := range sched.preBindPlugins {
+		status := pl.PreBind(ctx, state, pod, nodeName)
+		if !status.IsSuccess() {
+			err := status.AsError()
+			klog.ErrorS(err, "Failed running PreBind plugin", "plugin", pl.Name(), "pod", klog.KObj(pod))
+			return framework.AsStatus(fmt.Errorf("running PreBind plugin %q: %w", pl.Name(), err))
+		}
+	}
+	return nil
+}
+
+// RunBindPlugins runs the bind plugins until one of them handles the pod.
+// The extender which binds pods takes precedence over them if it's interested in the pod.
+// The pod is bound by Bind when all of them skip it, or no bind plugin is enabled.
+func (sched *Scheduler) RunBindPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	for _, ext := range sched.extenders {
+		if !ext.IsBinder() || !ext.IsInterested(pod) {
+			continue
+		}
+		err := ext.Bind(&v1.Binding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name, UID: pod.UID},
+			Target:     v1.ObjectReference{Kind: "Node", Name: nodeName},
+		})
+		if err != nil {
+			klog.ErrorS(err, "Failed running extender bind", "extender", ext.Name(), "pod", klog.KObj(pod))
+			return framework.AsStatus(fmt.Errorf("running extender %q bind: %w", ext.Name(), err))
+		}
+		return nil
+	}
+
+	for _, pl := range sched.bindPlugins {
+		status := pl.Bind(ctx, state, pod, nodeName)
+		if status.Code() == framework.Skip {
+			continue
+		}
+		if !status.IsSuccess() {
+			err := status.AsError()
+			klog.ErrorS(err, "Failed running Bind plugin", "plugin", pl.Name(), "pod", klog.KObj(pod))
+			return framework.AsStatus(fmt.Errorf("running Bind plugin %q: %w", pl.Name(), err))
+		}
+		return nil
+	}
+
+	if err := sched.Bind(ctx, state, pod, nodeName); err != nil {
+		return framework.AsStatus(fmt.Errorf("bind pod: %w", err))
+	}
+	return nil
+}
+
+// RunPostBindPlugins runs the post bind plugins. They are informational, so they can't fail.
+func (sched *Scheduler) RunPostBindPlugins(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) {
+	for _, pl := range sched.postBindPlugins {
+		pl.PostBind(ctx, state, pod, nodeName)
+	}
+}
+
 func (sched *Scheduler) Bind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) error {
 	binding := &v1.Binding{
 		ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: p.Name, UID: p.UID},
@@ -276,11 +536,77 @@ func (sched *Scheduler) Bind(ctx context.Context, state *framework.CycleState, p
 	return nil
 }
 
+// HandOverResult is the pods handed over to the next scheduler.
+type HandOverResult struct {
+	// QueuedPods are the pods moved from the scheduling queue.
+	QueuedPods []*v1.Pod
+	// RejectedWaitingPods are the pods rejected while waiting in the permit phase.
+	// They are added to activeQ of the next scheduler.
+	RejectedWaitingPods []*v1.Pod
+	// CancelledBindingPods are "<namespace>/<name>" of the pods whose binding cycle is cancelled
+	// since it didn't finish in time. They are scheduled again by the next scheduler.
+	CancelledBindingPods []string
+}
+
+// handOverPluginName is the plugin name recorded on the waiting pods rejected by HandOver.
+const handOverPluginName = "HandOver"
+
+// HandOver hands over the pods which the scheduler hasn't bound yet to next,
+// so that the pods are scheduled by next without being re-discovered.
+// The context passed to Run must be cancelled before calling it.
+// The pods which have already passed the permit phase are bound by this scheduler until ctx is done,
+// and the binding cycles which don't finish by then are cancelled.
+// HandOver returns after all binding cycles return, so that next, which is started after that,
+// doesn't schedule the pods being bound. The plugins which implement io.Closer are closed before returning.
+func (sched *Scheduler) HandOver(ctx context.Context, next *Scheduler) *HandOverResult {
+	// wait for the scheduling cycle in progress.
+	// The context passed to Run has been cancelled, so the scheduling loop stops without the deadline.
+	_ = sched.stopScheduling(context.Background())
+
+	ret := &HandOverResult{}
+	for _, pod := range sched.rejectWaitingPods(handOverPluginName, "rejected because the scheduler is restarted") {
+		if err := next.SchedulingQueue.Add(pod); err != nil {
+			klog.ErrorS(err, "Error handing over the waiting pod", "pod", klog.KObj(pod))
+			continue
+		}
+		ret.RejectedWaitingPods = append(ret.RejectedWaitingPods, pod)
+	}
+
+	ret.QueuedPods = sched.SchedulingQueue.HandOverTo(next.SchedulingQueue)
+
+	ret.CancelledBindingPods = sched.bindingCycles.wait(ctx)
+	sched.cancelBinding()
+	// the cancelled binding cycles return without binding the pods, and next finds them unassigned.
+	sched.bindingCycles.wait(context.Background())
+
+	if err := sched.closePlugins(); err != nil {
+		klog.ErrorS(err, "Error closing plugins of the handed over scheduler")
+	}
+
+	return ret
+}
+
 // ============
 // util funcs
 // ============
 
-func (sched *Scheduler) ErrorFunc(pod *v1.Pod, err error) {
+// preFilterError converts the failure of pre filter plugins to the error passed to ErrorFunc.
+// The unschedulable pod is treated in the same way as the pod which no node fits.
+func preFilterError(pod *v1.Pod, status *framework.Status) error {
+	if !status.IsUnschedulable() {
+		return status.AsError()
+	}
+	return &framework.FitError{
+		Pod: pod,
+		Diagnosis: framework.Diagnosis{
+			NodeToStatusMap:      make(framework.NodeToStatusMap),
+			UnschedulablePlugins: sets.NewString(status.FailedPlugin()),
+		},
+	}
+}
+
+// ErrorFunc adds the pod which fails in the scheduling cycle podSchedulingCycle back to the queue.
+func (sched *Scheduler) ErrorFunc(pod *v1.Pod, err error, podSchedulingCycle int64) {
 	podInfo := &framework.QueuedPodInfo{
 		PodInfo: framework.NewPodInfo(pod),
 	}
@@ -292,13 +618,39 @@ func (sched *Scheduler) ErrorFunc(pod *v1.Pod, err error) {
 		klog.ErrorS(err, "Error scheduling pod; retrying", "pod", klog.KObj(pod))
 	}
 
-	if err := sched.SchedulingQueue.AddUnschedulable(podInfo); err != nil {
+	// the pod may not be updated until the next try, so the results are recorded now.
+	sched.recorder.Flush(pod)
+
+	if err := sched.SchedulingQueue.AddUnschedulable(podInfo, podSchedulingCycle); err != nil {
 		klog.ErrorS(err, "Error occurred")
 	}
 }
 
+// ClientSet returns a kubernetes clientSet.
+func (sched *Scheduler) ClientSet() clientset.Interface {
+	return sched.client
+}
+
+// SharedInformerFactory returns the informer factory of the scheduler.
+func (sched *Scheduler) SharedInformerFactory() informers.SharedInformerFactory {
+	return sched.informerFactory
+}
+
+// GetWaitingPod returns a waiting pod given its UID.
 func (sched *Scheduler) GetWaitingPod(uid types.UID) *waitingpod.WaitingPod {
-	return sched.waitingPods[uid]
+	return sched.waitingPods.Get(uid)
+}
+
+// IterateOverWaitingPods acquires a read lock and iterates over the waiting pods.
+func (sched *Scheduler) IterateOverWaitingPods(callback func(*waitingpod.WaitingPod)) {
+	sched.waitingPods.Iterate(callback)
+}
+
+// RejectWaitingPod rejects a waiting pod given its UID.
+func (sched *Scheduler) RejectWaitingPod(uid types.UID) {
+	if wp := sched.waitingPods.Get(uid); wp != nil {
+		wp.Reject("", "removed")
+	}
 }
 
 func (sched *Scheduler) selectHost(nodeScoreList framework.NodeScoreList) (string, error) {
@@ -324,6 +676,29 @@ func (sched *Scheduler) selectHost(nodeScoreList framework.NodeScoreList) (strin
 	return selected, nil
 }
 
+// nodeInfos returns NodeInfo of each node with the pods assigned to it,
+// so that the filter plugins can check the pods on nodes, like the volumes they use.
+func (sched *Scheduler) nodeInfos(nodes []v1.Node) ([]*framework.NodeInfo, error) {
+	pods, err := sched.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	podsOnNode := make(map[string][]*v1.Pod, len(nodes))
+	for _, p := range pods {
+		if assignedPod(p) {
+			podsOnNode[p.Spec.NodeName] = append(podsOnNode[p.Spec.NodeName], p)
+		}
+	}
+
+	nodeInfos := make([]*framework.NodeInfo, 0, len(nodes))
+	for i := range nodes {
+		nodeInfo := framework.NewNodeInfo(podsOnNode[nodes[i].Name]...)
+		nodeInfo.SetNode(&nodes[i])
+		nodeInfos = append(nodeInfos, nodeInfo)
+	}
+	return nodeInfos, nil
+}
+
 func (sched *Scheduler) createPluginToNodeScores(nodes []*v1.Node) framework.PluginToNodeScores {
 	pluginToNodeScores := make(framework.PluginToNodeScores, len(sched.scorePlugins))
 	for _, pl := range sched.scorePlugins {
@@ -0,0 +1,133 @@
+package minisched
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/extender"
+)
+
+// newTestExtender starts an in-process extender which responds with resp, or 500 if resp is nil.
+func newTestExtender(t *testing.T, config v1beta2config.Extender, resp interface{}) *extender.HTTPExtender {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if resp == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	config.URLPrefix = server.URL
+	ext, err := extender.NewHTTPExtender(&config)
+	require.NoError(t, err)
+	return ext
+}
+
+func TestScheduler_RunFilterPlugins_withExtenders(t *testing.T) {
+	t.Parallel()
+	var nodeInfos []*framework.NodeInfo
+	for _, name := range []string{"node1", "node2"} {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		nodeInfos = append(nodeInfos, nodeInfo)
+	}
+	filterConfig := v1beta2config.Extender{FilterVerb: "filter", NodeCacheCapable: true}
+	tests := []struct {
+		name      string
+		config    v1beta2config.Extender
+		resp      interface{}
+		wantNodes []string
+		// wantStatuses are the statuses of nodes in FitError.
+		wantStatuses framework.NodeToStatusMap
+		wantErr      bool
+	}{
+		{
+			name:      "filter out the nodes which the extender rejects",
+			config:    filterConfig,
+			resp:      &extenderv1.ExtenderFilterResult{NodeNames: &[]string{"node1"}, FailedNodes: extenderv1.FailedNodesMap{"node2": "reason"}},
+			wantNodes: []string{"node1"},
+		},
+		{
+			name:   "return FitError with the reasons of the extender if no node passes it",
+			config: filterConfig,
+			resp: &extenderv1.ExtenderFilterResult{
+				NodeNames:                  &[]string{},
+				FailedNodes:                extenderv1.FailedNodesMap{"node1": "reason1"},
+				FailedAndUnresolvableNodes: extenderv1.FailedNodesMap{"node2": "reason2"},
+			},
+			wantStatuses: framework.NodeToStatusMap{
+				"node1": framework.NewStatus(framework.Unschedulable, "reason1"),
+				"node2": framework.NewStatus(framework.UnschedulableAndUnresolvable, "reason2"),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "fail if the extender fails",
+			config:  filterConfig,
+			wantErr: true,
+		},
+		{
+			name:      "ignore the ignorable extender which fails",
+			config:    v1beta2config.Extender{FilterVerb: "filter", NodeCacheCapable: true, Ignorable: true},
+			wantNodes: []string{"node1", "node2"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sched := &Scheduler{extenders: []*extender.HTTPExtender{newTestExtender(t, tt.config, tt.resp)}}
+
+			got, err := sched.RunFilterPlugins(context.Background(), framework.NewCycleState(), &v1.Pod{}, nodeInfos)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.wantStatuses != nil {
+					fitErr, ok := err.(*framework.FitError)
+					require.True(t, ok)
+					assert.Equal(t, tt.wantStatuses, fitErr.Diagnosis.NodeToStatusMap)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			var names []string
+			for _, n := range got {
+				names = append(names, n.Name)
+			}
+			assert.Equal(t, tt.wantNodes, names)
+		})
+	}
+}
+
+func TestScheduler_addExtenderScores(t *testing.T) {
+	t.Parallel()
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	}
+	sched := &Scheduler{extenders: []*extender.HTTPExtender{
+		newTestExtender(t, v1beta2config.Extender{PrioritizeVerb: "prioritize", Weight: 2},
+			extenderv1.HostPriorityList{{Host: "node1", Score: 1}, {Host: "node2", Score: 5}}),
+		// the extender which fails gives no score.
+		newTestExtender(t, v1beta2config.Extender{PrioritizeVerb: "prioritize", Weight: 1}, nil),
+	}}
+	scores := framework.NodeScoreList{{Name: "node1", Score: 10}, {Name: "node2", Score: 0}}
+
+	sched.addExtenderScores(&v1.Pod{}, nodes, scores)
+
+	// the score of the extender is multiplied by the weight, and scaled by MaxNodeScore/MaxExtenderPriority.
+	assert.Equal(t, framework.NodeScoreList{{Name: "node1", Score: 30}, {Name: "node2", Score: 100}}, scores)
+}
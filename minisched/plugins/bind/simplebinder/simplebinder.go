@@ -0,0 +1,57 @@
+package simplebinder
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// SimpleBinder is a sample bind plugin.
+// It binds the pod to the node with the binding subresource of the pod.
+// The pod annotated with BinderAnnotation of another binder is skipped, so that the next bind plugin handles it.
+// minisched binds the pod by itself if all bind plugins skip it.
+type SimpleBinder struct {
+	h handle.Handle
+}
+
+var _ framework.BindPlugin = &SimpleBinder{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "SimpleBinder"
+
+	// BinderAnnotation is the annotation of the name of the bind plugin which should bind the pod.
+	BinderAnnotation = "minisched/binder"
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *SimpleBinder) Name() string {
+	return Name
+}
+
+// Bind binds the pod to nodeName, or skips the pod which another binder should bind.
+func (pl *SimpleBinder) Bind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
+	if binder, ok := p.Annotations[BinderAnnotation]; ok && binder != Name {
+		return framework.NewStatus(framework.Skip)
+	}
+
+	binding := &v1.Binding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: p.Namespace, Name: p.Name, UID: p.UID},
+		Target:     v1.ObjectReference{Kind: "Node", Name: nodeName},
+	}
+	if err := pl.h.ClientSet().CoreV1().Pods(p.Namespace).Bind(ctx, binding, metav1.CreateOptions{}); err != nil {
+		return framework.AsStatus(fmt.Errorf("bind pod %s/%s: %w", p.Namespace, p.Name, err))
+	}
+	return nil
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	return &SimpleBinder{h: h}, nil
+}
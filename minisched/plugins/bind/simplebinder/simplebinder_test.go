@@ -0,0 +1,71 @@
+package simplebinder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func TestSimpleBinder_Bind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantCode    framework.Code
+		wantBinding bool
+	}{
+		{
+			name:        "bind the pod without the annotation",
+			wantCode:    framework.Success,
+			wantBinding: true,
+		},
+		{
+			name:        "bind the pod annotated with the plugin",
+			annotations: map[string]string{BinderAnnotation: Name},
+			wantCode:    framework.Success,
+			wantBinding: true,
+		},
+		{
+			name:        "skip the pod annotated with another binder",
+			annotations: map[string]string{BinderAnnotation: "AnotherBinder"},
+			wantCode:    framework.Skip,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", Annotations: tt.annotations}}
+			client := clientsetfake.NewSimpleClientset(pod)
+			var got *v1.Binding
+			client.PrependReactor("create", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "binding" {
+					return false, nil, nil
+				}
+				got = action.(clienttesting.CreateAction).GetObject().(*v1.Binding)
+				return true, got, nil
+			})
+			pl, err := New(nil, fake.NewHandle(client))
+			assert.NoError(t, err)
+
+			status := pl.(framework.BindPlugin).Bind(context.Background(), nil, pod, "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			if !tt.wantBinding {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, "pod1", got.Name)
+			assert.Equal(t, "node1", got.Target.Name)
+		})
+	}
+}
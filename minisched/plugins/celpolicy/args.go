@@ -0,0 +1,84 @@
+package celpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// CELPolicyArgs holds arguments used to configure CELPolicy.
+type CELPolicyArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// FilterRules are the rules which every node must satisfy. The node is filtered out by the first rule evaluated to false.
+	FilterRules []FilterRule `json:"filterRules,omitempty"`
+	// ScoreExpression is the expression which computes the score of the node. It's evaluated to a non-negative number.
+	ScoreExpression string `json:"scoreExpression,omitempty"`
+}
+
+// FilterRule is a CEL expression evaluated to bool.
+type FilterRule struct {
+	// Name is the name of the rule. It must be unique in the args.
+	Name string `json:"name"`
+	// Expression is the CEL expression. e.g. "node.labels['zone'] == pod.labels['zone']"
+	Expression string `json:"expression"`
+	// Message is the reason of the status when the node doesn't satisfy the rule.
+	// Defaults to "node(s) didn't satisfy the rule <Name>".
+	Message string `json:"message,omitempty"`
+}
+
+// decodeArgs decodes the args of the plugin in PluginConfig.
+func decodeArgs(obj runtime.Object) (*CELPolicyArgs, error) {
+	args := &CELPolicyArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(args); err != nil {
+			return nil, fmt.Errorf("decode args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type CELPolicyArgs, got %T", obj)
+	}
+
+	return args, nil
+}
+
+// setDefaults fills the omitted fields with the default values.
+func setDefaults(args *CELPolicyArgs) {
+	for i := range args.FilterRules {
+		if args.FilterRules[i].Message == "" {
+			args.FilterRules[i].Message = fmt.Sprintf("node(s) didn't satisfy the rule %s", args.FilterRules[i].Name)
+		}
+	}
+}
+
+// validateArgs validates the args. It reports all invalid fields at once.
+// The expressions are compiled by compile, which also reports all errors at once.
+func validateArgs(args *CELPolicyArgs) error {
+	var allErrs field.ErrorList
+	if len(args.FilterRules) == 0 && args.ScoreExpression == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("filterRules"), "either filterRules or scoreExpression must be set"))
+	}
+	names := sets.NewString()
+	for i, r := range args.FilterRules {
+		path := field.NewPath("filterRules").Index(i)
+		switch {
+		case r.Name == "":
+			allErrs = append(allErrs, field.Required(path.Child("name"), ""))
+		case names.Has(r.Name):
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), r.Name))
+		}
+		names.Insert(r.Name)
+		if r.Expression == "" {
+			allErrs = append(allErrs, field.Required(path.Child("expression"), ""))
+		}
+	}
+	return allErrs.ToAggregate()
+}
@@ -0,0 +1,356 @@
+package celpolicy
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// CELPolicy is a plugin whose filter rules and score expression are written in CEL (https://github.com/google/cel-spec).
+// The expressions can refer to the pod as `pod` and the node as `node`. See podVars and nodeVars for their fields.
+// The expressions are compiled when the plugin is created, and the errors while evaluating them result in the Error status.
+type CELPolicy struct {
+	filterRules []filterRule
+	score       cel.Program
+
+	// mu protects filterState in CycleState, which Filter may add NodeInfo to for the nodes in parallel.
+	mu sync.Mutex
+}
+
+var _ framework.FilterPlugin = &CELPolicy{}
+var _ framework.PreScorePlugin = &CELPolicy{}
+var _ framework.ScorePlugin = &CELPolicy{}
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = "CELPolicy"
+const (
+	filterStateKey   = "Filter" + Name
+	preScoreStateKey = "PreScore" + Name
+)
+
+type filterRule struct {
+	FilterRule
+	program cel.Program
+}
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *CELPolicy) Name() string {
+	return Name
+}
+
+// filterState keeps NodeInfo given at Filter so that Score can refer to the pods on the nodes.
+// NodeInfo isn't given to Score.
+type filterState struct {
+	// node name → NodeInfo
+	nodeInfos map[string]*framework.NodeInfo
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *filterState) Clone() framework.StateData {
+	return s
+}
+
+// Filter evaluates the filter rules in order, and filters out the node when one of them is evaluated to false.
+func (pl *CELPolicy) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if pl.score != nil {
+		pl.keepNodeInfo(state, nodeInfo)
+	}
+
+	vars := map[string]interface{}{
+		"pod":  podVars(pod),
+		"node": nodeVars(nodeInfo),
+	}
+	for _, r := range pl.filterRules {
+		val, _, err := r.program.Eval(vars)
+		if err != nil {
+			return framework.AsStatus(fmt.Errorf("evaluate the filter rule %s: %w", r.Name, err))
+		}
+		ok, isBool := val.(types.Bool)
+		if !isBool {
+			return framework.AsStatus(fmt.Errorf("evaluate the filter rule %s: want bool, got %s", r.Name, val.Type().TypeName()))
+		}
+		if !ok {
+			return framework.NewStatus(framework.Unschedulable, r.Message)
+		}
+	}
+	return nil
+}
+
+// keepNodeInfo adds nodeInfo to filterState, creating filterState at the first node.
+func (pl *CELPolicy) keepNodeInfo(state *framework.CycleState, nodeInfo *framework.NodeInfo) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	var s *filterState
+	if data, err := state.Read(filterStateKey); err == nil {
+		s = data.(*filterState)
+	} else {
+		s = &filterState{nodeInfos: map[string]*framework.NodeInfo{}}
+		state.Write(filterStateKey, s)
+	}
+	s.nodeInfos[nodeInfo.Node().Name] = nodeInfo
+}
+
+// nodeInfo returns NodeInfo of the node kept at Filter.
+// If Filter of this plugin isn't enabled, it returns NodeInfo only with the node, which has no pods.
+func (pl *CELPolicy) nodeInfo(state *framework.CycleState, node *v1.Node) *framework.NodeInfo {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if data, err := state.Read(filterStateKey); err == nil {
+		if nodeInfo, ok := data.(*filterState).nodeInfos[node.Name]; ok {
+			return nodeInfo
+		}
+	}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
+
+// preScoreState computed at PreScore and used at Score.
+type preScoreState struct {
+	pod   map[string]interface{}
+	nodes map[string]*v1.Node
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore keeps the pod and the nodes so that Score can evaluate the expression with them.
+func (pl *CELPolicy) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	s := &preScoreState{pod: podVars(pod), nodes: make(map[string]*v1.Node, len(nodes))}
+	for _, n := range nodes {
+		s.nodes[n.Name] = n
+	}
+	state.Write(preScoreStateKey, s)
+
+	return nil
+}
+
+// Score evaluates the score expression. The score is scaled at NormalizeScore.
+func (pl *CELPolicy) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	if pl.score == nil {
+		return 0, nil
+	}
+
+	data, err := state.Read(preScoreStateKey)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	s := data.(*preScoreState)
+
+	val, _, err := pl.score.Eval(map[string]interface{}{
+		"pod":  s.pod,
+		"node": nodeVars(pl.nodeInfo(state, s.nodes[nodeName])),
+	})
+	if err != nil {
+		return 0, framework.AsStatus(fmt.Errorf("evaluate the score expression: %w", err))
+	}
+
+	var score int64
+	switch v := val.(type) {
+	case types.Int:
+		score = int64(v)
+	case types.Uint:
+		score = int64(v)
+	case types.Double:
+		score = int64(v)
+	default:
+		return 0, framework.AsStatus(fmt.Errorf("evaluate the score expression: want number, got %s", val.Type().TypeName()))
+	}
+	if score < 0 {
+		return 0, framework.AsStatus(fmt.Errorf("evaluate the score expression: %d is negative", score))
+	}
+	return score, nil
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *CELPolicy) ScoreExtensions() framework.ScoreExtensions {
+	return pl
+}
+
+// NormalizeScore scales the scores into [0, 100] keeping their ratio, if the highest score exceeds 100.
+func (pl *CELPolicy) NormalizeScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, scores framework.NodeScoreList) *framework.Status {
+	var highest int64
+	for _, s := range scores {
+		if s.Score > highest {
+			highest = s.Score
+		}
+	}
+	if highest <= framework.MaxNodeScore {
+		return nil
+	}
+
+	for i := range scores {
+		// the product is computed in 128 bits since it can overflow int64.
+		// The quotient fits in 64 bits because the score isn't higher than highest.
+		hi, lo := bits.Mul64(uint64(scores[i].Score), uint64(framework.MaxNodeScore))
+		q, _ := bits.Div64(hi, lo, uint64(highest))
+		scores[i].Score = int64(q)
+	}
+	return nil
+}
+
+func (pl *CELPolicy) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeLabel | framework.UpdateNodeTaint},
+	}
+}
+
+// podVars returns the fields of the pod which the expressions can refer to.
+// Resources are int, and CPU is in millicores.
+func podVars(pod *v1.Pod) map[string]interface{} {
+	reqs, _ := resourcehelper.PodRequestsAndLimits(pod)
+	return map[string]interface{}{
+		"name":        pod.Name,
+		"namespace":   pod.Namespace,
+		"labels":      stringMap(pod.Labels),
+		"annotations": stringMap(pod.Annotations),
+		"priority":    int64(corev1helpers.PodPriority(pod)),
+		"requests":    resourceVars(reqs),
+	}
+}
+
+// nodeVars returns the fields of the node and the NodeInfo which the expressions can refer to.
+// Resources are int, and CPU is in millicores.
+func nodeVars(nodeInfo *framework.NodeInfo) map[string]interface{} {
+	node := nodeInfo.Node()
+	taints := make([]interface{}, 0, len(node.Spec.Taints))
+	for _, t := range node.Spec.Taints {
+		taints = append(taints, map[string]interface{}{
+			"key":    t.Key,
+			"value":  t.Value,
+			"effect": string(t.Effect),
+		})
+	}
+	return map[string]interface{}{
+		"name":          node.Name,
+		"labels":        stringMap(node.Labels),
+		"annotations":   stringMap(node.Annotations),
+		"unschedulable": node.Spec.Unschedulable,
+		"taints":        taints,
+		"allocatable":   resourceVars(node.Status.Allocatable),
+		"requested":     requestedVars(nodeInfo.Requested),
+		"podCount":      int64(len(nodeInfo.Pods)),
+	}
+}
+
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func resourceVars(list v1.ResourceList) map[string]int64 {
+	ret := make(map[string]int64, len(list))
+	for name, q := range list {
+		if name == v1.ResourceCPU {
+			ret[string(name)] = q.MilliValue()
+			continue
+		}
+		ret[string(name)] = q.Value()
+	}
+	return ret
+}
+
+func requestedVars(r *framework.Resource) map[string]int64 {
+	ret := map[string]int64{
+		string(v1.ResourceCPU):              r.MilliCPU,
+		string(v1.ResourceMemory):           r.Memory,
+		string(v1.ResourceEphemeralStorage): r.EphemeralStorage,
+	}
+	for name, v := range r.ScalarResources {
+		ret[string(name)] = v
+	}
+	return ret
+}
+
+// compile compiles the expressions in the args. It reports all errors at once.
+func compile(args *CELPolicyArgs) (*CELPolicy, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("pod", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("node", decls.NewMapType(decls.String, decls.Dyn)),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("create CEL environment: %w", err)
+	}
+
+	pl := &CELPolicy{}
+	var allErrs field.ErrorList
+	for i, r := range args.FilterRules {
+		path := field.NewPath("filterRules").Index(i).Child("expression")
+		prg, err := program(env, r.Expression, decls.Bool)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path, r.Expression, err.Error()))
+			continue
+		}
+		pl.filterRules = append(pl.filterRules, filterRule{FilterRule: r, program: prg})
+	}
+	if args.ScoreExpression != "" {
+		prg, err := program(env, args.ScoreExpression, decls.Int, decls.Uint, decls.Double)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("scoreExpression"), args.ScoreExpression, err.Error()))
+		}
+		pl.score = prg
+	}
+	if err := allErrs.ToAggregate(); err != nil {
+		return nil, err
+	}
+	return pl, nil
+}
+
+// program compiles the expression into the program. The expression must result in one of the types, or dyn.
+func program(env *cel.Env, expr string, resultTypes ...*exprpb.Type) (cel.Program, error) {
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	ok := proto.Equal(ast.ResultType(), decls.Dyn)
+	for _, t := range resultTypes {
+		ok = ok || proto.Equal(ast.ResultType(), t)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s", checker.FormatCheckedType(ast.ResultType()))
+	}
+	return env.Program(ast)
+}
+
+// New initializes a new plugin and returns it.
+func New(obj runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	setDefaults(args)
+	if err := validateArgs(args); err != nil {
+		return nil, fmt.Errorf("validate CELPolicyArgs: %w", err)
+	}
+
+	pl, err := compile(args)
+	if err != nil {
+		return nil, fmt.Errorf("compile CELPolicyArgs: %w", err)
+	}
+	return pl, nil
+}
@@ -0,0 +1,256 @@
+package celpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newPlugin(t *testing.T, args string) *CELPolicy {
+	t.Helper()
+	pl, err := New(&runtime.Unknown{Raw: []byte(args)}, fake.NewHandle(nil))
+	require.NoError(t, err)
+	return pl.(*CELPolicy)
+}
+
+func newPod(labels map[string]string, cpu string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", Labels: labels},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}}},
+			},
+		},
+	}
+}
+
+func newNode(name string, labels map[string]string, cpu string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status:     v1.NodeStatus{Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}},
+	}
+}
+
+func TestCELPolicy_Filter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		args        string
+		pod         *v1.Pod
+		node        *v1.Node
+		wantCode    framework.Code
+		wantMessage string
+	}{
+		{
+			name:     "the node is in the same zone as the pod",
+			args:     `{"filterRules":[{"name":"same-zone","expression":"node.labels['zone'] == pod.labels['zone']"}]}`,
+			pod:      newPod(map[string]string{"zone": "a"}, "100m"),
+			node:     newNode("node1", map[string]string{"zone": "a"}, "1"),
+			wantCode: framework.Success,
+		},
+		{
+			name:        "the node is in the different zone from the pod",
+			args:        `{"filterRules":[{"name":"same-zone","expression":"node.labels['zone'] == pod.labels['zone']"}]}`,
+			pod:         newPod(map[string]string{"zone": "a"}, "100m"),
+			node:        newNode("node1", map[string]string{"zone": "b"}, "1"),
+			wantCode:    framework.Unschedulable,
+			wantMessage: "node(s) didn't satisfy the rule same-zone",
+		},
+		{
+			name: "the node doesn't satisfy the second rule",
+			args: `{"filterRules":[
+				{"name":"same-zone","expression":"node.labels['zone'] == pod.labels['zone']"},
+				{"name":"enough-cpu","expression":"node.allocatable.cpu >= pod.requests.cpu","message":"too small"}
+			]}`,
+			pod:         newPod(map[string]string{"zone": "a"}, "2"),
+			node:        newNode("node1", map[string]string{"zone": "a"}, "1500m"),
+			wantCode:    framework.Unschedulable,
+			wantMessage: "too small",
+		},
+		{
+			name:        "the expression fails when the label doesn't exist",
+			args:        `{"filterRules":[{"name":"same-zone","expression":"node.labels['zone'] == pod.labels['zone']"}]}`,
+			pod:         newPod(nil, "100m"),
+			node:        newNode("node1", map[string]string{"zone": "a"}, "1"),
+			wantCode:    framework.Error,
+			wantMessage: "evaluate the filter rule same-zone: no such key: zone",
+		},
+		{
+			name:        "the expression isn't evaluated to bool",
+			args:        `{"filterRules":[{"name":"zone","expression":"node.labels['zone']"}]}`,
+			pod:         newPod(nil, "100m"),
+			node:        newNode("node1", map[string]string{"zone": "a"}, "1"),
+			wantCode:    framework.Error,
+			wantMessage: "evaluate the filter rule zone: want bool, got string",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, tt.args)
+			nodeInfo := framework.NewNodeInfo()
+			nodeInfo.SetNode(tt.node)
+
+			status := pl.Filter(context.Background(), framework.NewCycleState(), tt.pod, nodeInfo)
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Equal(t, tt.wantMessage, status.Message())
+		})
+	}
+}
+
+func TestCELPolicy_Score(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		args string
+		// podsOnNodes is the pods on each node, which Filter is given before scoring.
+		podsOnNodes map[string][]*v1.Pod
+		wantScores  framework.NodeScoreList
+		wantErr     string
+	}{
+		{
+			name: "score the nodes within [0, 100]",
+			args: `{"scoreExpression":"node.labels['zone'] == 'a' ? 100 : 10"}`,
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 100},
+				{Name: "node2", Score: 10},
+			},
+		},
+		{
+			name: "scale the scores exceeding 100",
+			args: `{"scoreExpression":"node.allocatable.cpu - pod.requests.cpu"}`,
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 50},
+				{Name: "node2", Score: 100},
+			},
+		},
+		{
+			name: "score with the pods on the nodes given at Filter",
+			args: `{"scoreExpression":"node.podCount * 10 + node.requested.cpu / 100"}`,
+			podsOnNodes: map[string][]*v1.Pod{
+				"node1": {newPod(nil, "300m"), newPod(nil, "300m")},
+				"node2": {newPod(nil, "100m")},
+			},
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 26},
+				{Name: "node2", Score: 11},
+			},
+		},
+		{
+			name: "scale the scores close to the max int64 without overflow",
+			args: `{"scoreExpression":"node.allocatable.cpu * 4611686018427387"}`,
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 52},
+				{Name: "node2", Score: 100},
+			},
+		},
+		{
+			name: "score without the pods if Filter isn't run",
+			args: `{"scoreExpression":"node.podCount * 10 + node.requested.cpu / 100"}`,
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 0},
+				{Name: "node2", Score: 0},
+			},
+		},
+		{
+			name:    "the score is negative",
+			args:    `{"scoreExpression":"pod.requests.cpu - node.allocatable.cpu"}`,
+			wantErr: "evaluate the score expression: -900 is negative",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, tt.args)
+			pod := newPod(nil, "100m")
+			nodes := []*v1.Node{
+				newNode("node1", map[string]string{"zone": "a"}, "1"),
+				newNode("node2", map[string]string{"zone": "b"}, "1900m"),
+			}
+			state := framework.NewCycleState()
+			if tt.podsOnNodes != nil {
+				for _, n := range nodes {
+					nodeInfo := framework.NewNodeInfo(tt.podsOnNodes[n.Name]...)
+					nodeInfo.SetNode(n)
+					require.True(t, pl.Filter(context.Background(), state, pod, nodeInfo).IsSuccess())
+				}
+			}
+			require.True(t, pl.PreScore(context.Background(), state, pod, nodes).IsSuccess())
+
+			var scores framework.NodeScoreList
+			for _, n := range nodes {
+				score, status := pl.Score(context.Background(), state, pod, n.Name)
+				if tt.wantErr != "" {
+					assert.Equal(t, framework.Error, status.Code())
+					assert.Equal(t, tt.wantErr, status.Message())
+					return
+				}
+				require.True(t, status.IsSuccess())
+				scores = append(scores, framework.NodeScore{Name: n.Name, Score: score})
+			}
+			require.True(t, pl.NormalizeScore(context.Background(), state, pod, scores).IsSuccess())
+
+			assert.Equal(t, tt.wantScores, scores)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		obj     runtime.Object
+		wantErr string
+	}{
+		{
+			name: "compile the expressions",
+			obj:  &runtime.Unknown{Raw: []byte(`{"filterRules":[{"name":"rule1","expression":"!node.unschedulable"}],"scoreExpression":"node.podCount"}`)},
+		},
+		{
+			name:    "fail without args",
+			obj:     nil,
+			wantErr: "validate CELPolicyArgs: filterRules: Required value: either filterRules or scoreExpression must be set",
+		},
+		{
+			name:    "report all invalid fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"filterRules":[{"name":"rule1","expression":"true"},{"name":"rule1"},{"expression":"true"}]}`)},
+			wantErr: `validate CELPolicyArgs: [filterRules[1].name: Duplicate value: "rule1", filterRules[1].expression: Required value, filterRules[2].name: Required value]`,
+		},
+		{
+			name: "report all invalid expressions",
+			obj:  &runtime.Unknown{Raw: []byte(`{"filterRules":[{"name":"rule1","expression":"1 + 1"},{"name":"rule2","expression":"nodes.size() > 0"}],"scoreExpression":"'a'"}`)},
+			wantErr: `compile CELPolicyArgs: [filterRules[0].expression: Invalid value: "1 + 1": unexpected result type int, filterRules[1].expression: Invalid value: "nodes.size() > 0": ERROR: <input>:1:1: undeclared reference to 'nodes' (in container '')
+ | nodes.size() > 0
+ | ^, scoreExpression: Invalid value: "'a'": unexpected result type string]`,
+		},
+		{
+			name:    "fail with unknown fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"rules":[]}`)},
+			wantErr: `decode args: json: unknown field "rules"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := New(tt.obj, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package requirednodelabel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// RequiredNodeLabel is a sample pre filter and filter plugin.
+// It filters out nodes which don't have the label keys listed in RequiredNodeLabelsAnnotation of the pod.
+// For example, the pod annotated with `minisched/required-node-labels: "disktype,zone"`
+// can be scheduled only to nodes which have both "disktype" and "zone" labels.
+//
+// PreFilter parses and validates the annotation once per scheduling cycle, and Filter uses the result for each node.
+type RequiredNodeLabel struct{}
+
+var _ framework.PreFilterPlugin = &RequiredNodeLabel{}
+var _ framework.FilterPlugin = &RequiredNodeLabel{}
+var _ framework.EnqueueExtensions = &RequiredNodeLabel{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "RequiredNodeLabel"
+
+	// RequiredNodeLabelsAnnotation is the annotation of the comma separated label keys which nodes must have.
+	RequiredNodeLabelsAnnotation = "minisched/required-node-labels"
+
+	preFilterStateKey = "PreFilter" + Name
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *RequiredNodeLabel) Name() string {
+	return Name
+}
+
+// preFilterState computed at PreFilter and used at Filter.
+type preFilterState struct {
+	labelKeys []string
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *preFilterState) Clone() framework.StateData {
+	return s
+}
+
+// PreFilter parses RequiredNodeLabelsAnnotation of the pod.
+// The pod with the invalid annotation is unschedulable to any node.
+func (pl *RequiredNodeLabel) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) *framework.Status {
+	s := &preFilterState{}
+	for _, key := range strings.Split(pod.Annotations[RequiredNodeLabelsAnnotation], ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return framework.NewStatus(framework.UnschedulableAndUnresolvable,
+				fmt.Sprintf("annotation %s has invalid label key %q: %s", RequiredNodeLabelsAnnotation, key, strings.Join(errs, "; ")))
+		}
+		s.labelKeys = append(s.labelKeys, key)
+	}
+	state.Write(preFilterStateKey, s)
+
+	return nil
+}
+
+// PreFilterExtensions returns nil since the plugin doesn't depend on the other pods.
+func (pl *RequiredNodeLabel) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+// Filter invoked at the filter extension point.
+func (pl *RequiredNodeLabel) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	data, err := state.Read(preFilterStateKey)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	s := data.(*preFilterState)
+
+	for _, key := range s.labelKeys {
+		if _, ok := nodeInfo.Node().Labels[key]; !ok {
+			return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("node doesn't have label %s", key))
+		}
+	}
+	return nil
+}
+
+// EventsToRegister returns the events which may make the pod filtered out by the plugin schedulable.
+func (pl *RequiredNodeLabel) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Node, ActionType: framework.Add | framework.UpdateNodeLabel},
+	}
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	return &RequiredNodeLabel{}, nil
+}
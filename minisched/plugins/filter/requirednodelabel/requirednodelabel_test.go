@@ -0,0 +1,77 @@
+package requirednodelabel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newNodeInfo(labels map[string]string) *framework.NodeInfo {
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: labels}})
+	return nodeInfo
+}
+
+func TestRequiredNodeLabel(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		annotation    string
+		nodeLabels    map[string]string
+		wantPreFilter framework.Code
+		wantFilter    framework.Code
+	}{
+		{
+			name:          "any node fits the pod without the annotation",
+			wantPreFilter: framework.Success,
+			wantFilter:    framework.Success,
+		},
+		{
+			name:          "the node which has all labels fits",
+			annotation:    "disktype, zone",
+			nodeLabels:    map[string]string{"disktype": "ssd", "zone": "a", "other": ""},
+			wantPreFilter: framework.Success,
+			wantFilter:    framework.Success,
+		},
+		{
+			name:          "the node which lacks any label doesn't fit",
+			annotation:    "disktype,zone",
+			nodeLabels:    map[string]string{"disktype": "ssd"},
+			wantPreFilter: framework.Success,
+			wantFilter:    framework.Unschedulable,
+		},
+		{
+			name:          "the pod with the invalid label key is unschedulable",
+			annotation:    "disk type",
+			wantPreFilter: framework.UnschedulableAndUnresolvable,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl, err := New(nil, fake.NewHandle(nil))
+			assert.NoError(t, err)
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod1",
+				Annotations: map[string]string{RequiredNodeLabelsAnnotation: tt.annotation},
+			}}
+			state := framework.NewCycleState()
+
+			status := pl.(framework.PreFilterPlugin).PreFilter(context.Background(), state, pod)
+			assert.Equal(t, tt.wantPreFilter, status.Code())
+			if !status.IsSuccess() {
+				return
+			}
+
+			status = pl.(framework.FilterPlugin).Filter(context.Background(), state, pod, newNodeInfo(tt.nodeLabels))
+			assert.Equal(t, tt.wantFilter, status.Code())
+		})
+	}
+}
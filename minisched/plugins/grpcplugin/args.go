@@ -0,0 +1,81 @@
+package grpcplugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// GRPCPluginArgs holds arguments used to configure GRPCPlugin.
+type GRPCPluginArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// SocketPath is the path of the unix socket which the plugin process listens on. Required.
+	SocketPath string `json:"socketPath"`
+	// Timeout is the timeout of each call to the plugin process.
+	// Defaults to 1s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// FallbackStatus is the status code used when the call fails or times out.
+	// One of "Success", "Unschedulable" and "Error". "Success" means that Filter and Permit pass the pod and Score gives 0.
+	// Defaults to "Error".
+	FallbackStatus string `json:"fallbackStatus,omitempty"`
+}
+
+const defaultTimeout = time.Second
+
+// fallbackCodes are the codes which FallbackStatus can be.
+var fallbackCodes = map[string]framework.Code{
+	framework.Success.String():       framework.Success,
+	framework.Unschedulable.String(): framework.Unschedulable,
+	framework.Error.String():         framework.Error,
+}
+
+// decodeArgs decodes the args of the plugin in PluginConfig, and sets the default values.
+func decodeArgs(obj runtime.Object) (*GRPCPluginArgs, error) {
+	args := &GRPCPluginArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(args); err != nil {
+			return nil, fmt.Errorf("decode args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type GRPCPluginArgs, got %T", obj)
+	}
+
+	setDefaults(args)
+	return args, nil
+}
+
+func setDefaults(args *GRPCPluginArgs) {
+	if args.Timeout == nil {
+		args.Timeout = &metav1.Duration{Duration: defaultTimeout}
+	}
+	if args.FallbackStatus == "" {
+		args.FallbackStatus = framework.Error.String()
+	}
+}
+
+// validateArgs validates the defaulted args. It reports all invalid fields at once.
+func validateArgs(args *GRPCPluginArgs) error {
+	var allErrs field.ErrorList
+	if args.SocketPath == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("socketPath"), ""))
+	}
+	if args.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("timeout"), args.Timeout.Duration.String(), "must be positive"))
+	}
+	if _, ok := fallbackCodes[args.FallbackStatus]; !ok {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("fallbackStatus"), args.FallbackStatus,
+			[]string{framework.Success.String(), framework.Unschedulable.String(), framework.Error.String()}))
+	}
+	return allErrs.ToAggregate()
+}
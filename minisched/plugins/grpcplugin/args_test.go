@@ -0,0 +1,67 @@
+package grpcplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNew_args(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		wantArgs *GRPCPluginArgs
+		wantErr  string
+	}{
+		{
+			name: "default values",
+			obj:  &runtime.Unknown{Raw: []byte(`{"socketPath": "/tmp/plugin.sock"}`)},
+			wantArgs: &GRPCPluginArgs{
+				SocketPath:     "/tmp/plugin.sock",
+				Timeout:        &metav1.Duration{Duration: time.Second},
+				FallbackStatus: "Error",
+			},
+		},
+		{
+			name: "decode args in PluginConfig",
+			obj:  &runtime.Unknown{Raw: []byte(`{"socketPath": "/tmp/plugin.sock", "timeout": "3s", "fallbackStatus": "Success"}`)},
+			wantArgs: &GRPCPluginArgs{
+				SocketPath:     "/tmp/plugin.sock",
+				Timeout:        &metav1.Duration{Duration: 3 * time.Second},
+				FallbackStatus: "Success",
+			},
+		},
+		{
+			name:    "report all invalid fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"timeout": "0s", "fallbackStatus": "Wait"}`)},
+			wantErr: `validate GRPCPluginArgs: [socketPath: Required value, timeout: Invalid value: "0s": must be positive, fallbackStatus: Unsupported value: "Wait": supported values: "Success", "Unschedulable", "Error"]`,
+		},
+		{
+			name:    "fail without args",
+			obj:     nil,
+			wantErr: `validate GRPCPluginArgs: socketPath: Required value`,
+		},
+		{
+			name:    "fail with unknown fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"socket": "/tmp/plugin.sock"}`)},
+			wantErr: `decode args: json: unknown field "socket"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl, err := New(tt.obj, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantArgs, pl.(*GRPCPlugin).args)
+		})
+	}
+}
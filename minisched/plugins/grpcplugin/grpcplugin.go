@@ -0,0 +1,186 @@
+package grpcplugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// GRPCPlugin is a plugin that forwards Filter, Score and Permit to a plugin process over gRPC on a unix socket,
+// so that plugins can be developed as separate binaries without rebuilding the scheduler.
+// The plugin process serves the server made by NewServer, or implements the same protocol in another language.
+//
+// Each call times out after GRPCPluginArgs.Timeout, and the failed call results in GRPCPluginArgs.FallbackStatus.
+// The connection is established lazily, so the plugin process can start after the scheduler,
+// and it's closed when the scheduler is shut down.
+type GRPCPlugin struct {
+	args *GRPCPluginArgs
+	conn *grpc.ClientConn
+}
+
+var _ framework.FilterPlugin = &GRPCPlugin{}
+var _ framework.PreScorePlugin = &GRPCPlugin{}
+var _ framework.ScorePlugin = &GRPCPlugin{}
+var _ framework.PermitPlugin = &GRPCPlugin{}
+var _ io.Closer = &GRPCPlugin{}
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = "GRPCPlugin"
+const preScoreStateKey = "PreScore" + Name
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *GRPCPlugin) Name() string {
+	return Name
+}
+
+// Filter asks the plugin process whether the pod fits the node.
+func (pl *GRPCPlugin) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	ctx, cancel := context.WithTimeout(ctx, pl.args.Timeout.Duration)
+	defer cancel()
+
+	req := &FilterRequest{Pod: pod, Node: nodeInfo.Node()}
+	for _, p := range nodeInfo.Pods {
+		req.Pods = append(req.Pods, p.Pod)
+	}
+	resp := &FilterResponse{}
+	if err := invoke(ctx, pl.conn, "Filter", req, resp); err != nil {
+		return pl.fallback("Filter", err)
+	}
+
+	s, err := toStatus(resp.Status, framework.Success, framework.Unschedulable, framework.UnschedulableAndUnresolvable)
+	if err != nil {
+		return pl.fallback("Filter", err)
+	}
+	return s
+}
+
+// preScoreState computed at PreScore and used at Score.
+type preScoreState struct {
+	nodes map[string]*v1.Node
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore keeps the nodes so that Score can send them to the plugin process.
+func (pl *GRPCPlugin) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	s := &preScoreState{nodes: make(map[string]*v1.Node, len(nodes))}
+	for _, n := range nodes {
+		s.nodes[n.Name] = n
+	}
+	state.Write(preScoreStateKey, s)
+
+	return nil
+}
+
+// Score asks the plugin process for the score of the node.
+func (pl *GRPCPlugin) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	data, err := state.Read(preScoreStateKey)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	s := data.(*preScoreState)
+
+	ctx, cancel := context.WithTimeout(ctx, pl.args.Timeout.Duration)
+	defer cancel()
+
+	resp := &ScoreResponse{}
+	if err := invoke(ctx, pl.conn, "Score", &ScoreRequest{Pod: pod, Node: s.nodes[nodeName]}, resp); err != nil {
+		return 0, pl.fallback("Score", err)
+	}
+
+	status, err := toStatus(resp.Status, framework.Success)
+	if err != nil {
+		return 0, pl.fallback("Score", err)
+	}
+	if resp.Score < framework.MinNodeScore || resp.Score > framework.MaxNodeScore {
+		return 0, pl.fallback("Score", fmt.Errorf("score %d is out of the range [%d, %d]", resp.Score, framework.MinNodeScore, framework.MaxNodeScore))
+	}
+	return resp.Score, status
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *GRPCPlugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// Permit asks the plugin process whether the pod can be bound to the node.
+// The plugin process can't make the pod wait, because it has no way to allow the pod later.
+func (pl *GRPCPlugin) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, pl.args.Timeout.Duration)
+	defer cancel()
+
+	resp := &PermitResponse{}
+	if err := invoke(ctx, pl.conn, "Permit", &PermitRequest{Pod: p, NodeName: nodeName}, resp); err != nil {
+		return pl.fallback("Permit", err), 0
+	}
+
+	s, err := toStatus(resp.Status, framework.Success, framework.Unschedulable, framework.UnschedulableAndUnresolvable)
+	if err != nil {
+		return pl.fallback("Permit", err), 0
+	}
+	return s, 0
+}
+
+func (pl *GRPCPlugin) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Node, ActionType: framework.Add},
+	}
+}
+
+// Close closes the connection to the plugin process.
+func (pl *GRPCPlugin) Close() error {
+	return pl.conn.Close()
+}
+
+// fallback returns the status of FallbackStatus for the failed call to method.
+func (pl *GRPCPlugin) fallback(method string, err error) *framework.Status {
+	klog.ErrorS(err, "Failed calling the plugin process; fall back", "method", method, "socket", pl.args.SocketPath, "fallbackStatus", pl.args.FallbackStatus)
+
+	code := fallbackCodes[pl.args.FallbackStatus]
+	if code == framework.Success {
+		return nil
+	}
+	return framework.NewStatus(code, fmt.Sprintf("calling %s of the plugin process: %v", method, err))
+}
+
+// toStatus converts the status in the response to *framework.Status. The code must be one of allowed.
+func toStatus(s Status, allowed ...framework.Code) (*framework.Status, error) {
+	for _, c := range allowed {
+		if c.String() == s.Code {
+			return framework.NewStatus(c, s.Reasons...), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected status code %q", s.Code)
+}
+
+// New initializes a new plugin and returns it.
+func New(obj runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArgs(args); err != nil {
+		return nil, fmt.Errorf("validate GRPCPluginArgs: %w", err)
+	}
+
+	conn, err := grpc.Dial("unix://"+args.SocketPath, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("dial plugin process on %s: %w", args.SocketPath, err)
+	}
+
+	return &GRPCPlugin{args: args, conn: conn}, nil
+}
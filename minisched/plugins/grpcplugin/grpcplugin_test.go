@@ -0,0 +1,213 @@
+package grpcplugin
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+// fakePluginServer is the plugin process which
+// - filters out nodes whose name has "unschedulable", and nodes which have 2 pods or more,
+// - scores nodes with the length of their name,
+// - rejects pods whose name has "rejected",
+// - and doesn't respond to pods whose name has "slow" until the call times out.
+type fakePluginServer struct{}
+
+func (fakePluginServer) Filter(ctx context.Context, req *FilterRequest) (*FilterResponse, error) {
+	if strings.Contains(req.Pod.Name, "slow") {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if strings.Contains(req.Node.Name, "unschedulable") {
+		return &FilterResponse{Status: Status{Code: "Unschedulable", Reasons: []string{"unschedulable node"}}}, nil
+	}
+	if len(req.Pods) >= 2 {
+		return &FilterResponse{Status: Status{Code: "Unschedulable", Reasons: []string{"too many pods"}}}, nil
+	}
+	return &FilterResponse{Status: Status{Code: "Success"}}, nil
+}
+
+func (fakePluginServer) Score(ctx context.Context, req *ScoreRequest) (*ScoreResponse, error) {
+	return &ScoreResponse{Score: int64(len(req.Node.Name)), Status: Status{Code: "Success"}}, nil
+}
+
+func (fakePluginServer) Permit(ctx context.Context, req *PermitRequest) (*PermitResponse, error) {
+	if strings.Contains(req.Pod.Name, "rejected") {
+		return &PermitResponse{Status: Status{Code: "Unschedulable", Reasons: []string{"rejected pod"}}}, nil
+	}
+	return &PermitResponse{Status: Status{Code: "Wait"}}, nil
+}
+
+// startPluginServer serves srv on a unix socket during the test, and returns the path of the socket.
+func startPluginServer(t *testing.T, srv PluginServer) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	s := NewServer(srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+	return socket
+}
+
+func newPlugin(t *testing.T, socket, fallbackStatus string) *GRPCPlugin {
+	t.Helper()
+	pl, err := New(&runtime.Unknown{Raw: []byte(`{"socketPath":"` + socket + `","timeout":"100ms","fallbackStatus":"` + fallbackStatus + `"}`)}, fake.NewHandle(nil))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pl.(*GRPCPlugin).Close())
+	})
+	return pl.(*GRPCPlugin)
+}
+
+func newPod(name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func newNodeInfo(name string, pods ...*v1.Pod) *framework.NodeInfo {
+	nodeInfo := framework.NewNodeInfo(pods...)
+	nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	return nodeInfo
+}
+
+func TestGRPCPlugin_Filter(t *testing.T) {
+	t.Parallel()
+	socket := startPluginServer(t, fakePluginServer{})
+	tests := []struct {
+		name           string
+		socket         string
+		fallbackStatus string
+		pod            *v1.Pod
+		node           string
+		podsOnNode     []*v1.Pod
+		wantCode       framework.Code
+	}{
+		{
+			name:     "the node fits the pod",
+			socket:   socket,
+			pod:      newPod("pod1"),
+			node:     "node1",
+			wantCode: framework.Success,
+		},
+		{
+			name:     "the node doesn't fit the pod",
+			socket:   socket,
+			pod:      newPod("pod1"),
+			node:     "unschedulable-node",
+			wantCode: framework.Unschedulable,
+		},
+		{
+			name:       "the node doesn't fit the pod because of the pods on it",
+			socket:     socket,
+			pod:        newPod("pod1"),
+			node:       "node1",
+			podsOnNode: []*v1.Pod{newPod("pod2"), newPod("pod3")},
+			wantCode:   framework.Unschedulable,
+		},
+		{
+			name:           "fall back to the status when the call times out",
+			socket:         socket,
+			fallbackStatus: "Unschedulable",
+			pod:            newPod("slow-pod"),
+			node:           "node1",
+			wantCode:       framework.Unschedulable,
+		},
+		{
+			name:           "fall back to the status when the plugin process isn't running",
+			socket:         filepath.Join(t.TempDir(), "not-found.sock"),
+			fallbackStatus: "Success",
+			pod:            newPod("pod1"),
+			node:           "unschedulable-node",
+			wantCode:       framework.Success,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, tt.socket, tt.fallbackStatus)
+
+			status := pl.Filter(context.Background(), framework.NewCycleState(), tt.pod, newNodeInfo(tt.node, tt.podsOnNode...))
+
+			assert.Equal(t, tt.wantCode, status.Code())
+		})
+	}
+}
+
+func TestGRPCPlugin_Score(t *testing.T) {
+	t.Parallel()
+	pl := newPlugin(t, startPluginServer(t, fakePluginServer{}), "")
+	pod := newPod("pod1")
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-long-name"}},
+	}
+	state := framework.NewCycleState()
+
+	status := pl.PreScore(context.Background(), state, pod, nodes)
+	require.True(t, status.IsSuccess())
+
+	for _, n := range nodes {
+		score, status := pl.Score(context.Background(), state, pod, n.Name)
+		assert.True(t, status.IsSuccess())
+		assert.Equal(t, int64(len(n.Name)), score)
+	}
+}
+
+func TestGRPCPlugin_Permit(t *testing.T) {
+	t.Parallel()
+	socket := startPluginServer(t, fakePluginServer{})
+	tests := []struct {
+		name           string
+		fallbackStatus string
+		pod            *v1.Pod
+		wantCode       framework.Code
+	}{
+		{
+			name:     "reject the pod",
+			pod:      newPod("rejected-pod"),
+			wantCode: framework.Unschedulable,
+		},
+		{
+			name:     "fall back to the default status when the plugin process makes the pod wait",
+			pod:      newPod("pod1"),
+			wantCode: framework.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, socket, tt.fallbackStatus)
+
+			status, timeout := pl.Permit(context.Background(), framework.NewCycleState(), tt.pod, "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Equal(t, time.Duration(0), timeout)
+		})
+	}
+}
+
+func TestGRPCPlugin_unimplemented(t *testing.T) {
+	t.Parallel()
+	pl := newPlugin(t, startPluginServer(t, UnimplementedPluginServer{}), "Unschedulable")
+
+	status := pl.Filter(context.Background(), framework.NewCycleState(), newPod("pod1"), newNodeInfo("node1"))
+
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	assert.Contains(t, status.Message(), "not implemented")
+}
@@ -0,0 +1,154 @@
+package grpcplugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+)
+
+// The protocol between GRPCPlugin and the plugin process.
+//
+// It's a gRPC service whose messages are encoded in JSON instead of protocol buffers,
+// so that the messages can carry the Kubernetes objects as they are in the API.
+// The requests are sent with the content-type "application/grpc+json".
+
+// ServiceName is the full name of the gRPC service which the plugin process serves.
+const ServiceName = "minisched.grpcplugin.v1.Plugin"
+
+// Status is the result of the call. Code is the name of framework.Code, e.g. "Success" or "Unschedulable".
+type Status struct {
+	Code    string   `json:"code"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// FilterRequest is the request of Filter.
+type FilterRequest struct {
+	Pod  *v1.Pod  `json:"pod"`
+	Node *v1.Node `json:"node"`
+	// Pods are the pods on the node.
+	Pods []*v1.Pod `json:"pods,omitempty"`
+}
+
+// FilterResponse is the response of Filter.
+type FilterResponse struct {
+	Status Status `json:"status"`
+}
+
+// ScoreRequest is the request of Score.
+type ScoreRequest struct {
+	Pod  *v1.Pod  `json:"pod"`
+	Node *v1.Node `json:"node"`
+}
+
+// ScoreResponse is the response of Score. Score must be in [framework.MinNodeScore, framework.MaxNodeScore].
+type ScoreResponse struct {
+	Score  int64  `json:"score"`
+	Status Status `json:"status"`
+}
+
+// PermitRequest is the request of Permit.
+type PermitRequest struct {
+	Pod      *v1.Pod `json:"pod"`
+	NodeName string  `json:"nodeName"`
+}
+
+// PermitResponse is the response of Permit. The code must not be "Wait".
+type PermitResponse struct {
+	Status Status `json:"status"`
+}
+
+// PluginServer is the server API of the plugin process.
+type PluginServer interface {
+	Filter(context.Context, *FilterRequest) (*FilterResponse, error)
+	Score(context.Context, *ScoreRequest) (*ScoreResponse, error)
+	Permit(context.Context, *PermitRequest) (*PermitResponse, error)
+}
+
+// UnimplementedPluginServer can be embedded in the PluginServer which implements only some of the methods.
+type UnimplementedPluginServer struct{}
+
+// Filter returns the Unimplemented error.
+func (UnimplementedPluginServer) Filter(context.Context, *FilterRequest) (*FilterResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Filter not implemented")
+}
+
+// Score returns the Unimplemented error.
+func (UnimplementedPluginServer) Score(context.Context, *ScoreRequest) (*ScoreResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Score not implemented")
+}
+
+// Permit returns the Unimplemented error.
+func (UnimplementedPluginServer) Permit(context.Context, *PermitRequest) (*PermitResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Permit not implemented")
+}
+
+// NewServer returns the gRPC server serving srv. The plugin process serves it on a unix socket:
+//
+//	lis, _ := net.Listen("unix", "/tmp/plugin.sock")
+//	grpcplugin.NewServer(myPlugin).Serve(lis)
+func NewServer(srv PluginServer) *grpc.Server {
+	s := grpc.NewServer(grpc.ForceServerCodec(jsonCodec{}))
+	s.RegisterService(&serviceDesc, srv)
+	return s
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*PluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Filter",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &FilterRequest{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return srv.(PluginServer).Filter(ctx, in)
+			},
+		},
+		{
+			MethodName: "Score",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &ScoreRequest{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return srv.(PluginServer).Score(ctx, in)
+			},
+		},
+		{
+			MethodName: "Permit",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &PermitRequest{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return srv.(PluginServer).Permit(ctx, in)
+			},
+		},
+	},
+}
+
+// invoke calls the method of the plugin process.
+func invoke(ctx context.Context, conn *grpc.ClientConn, method string, in, out interface{}) error {
+	return conn.Invoke(ctx, "/"+ServiceName+"/"+method, in, out, grpc.ForceCodec(jsonCodec{}))
+}
+
+// jsonCodec encodes the messages in JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
@@ -0,0 +1,58 @@
+package coscheduling
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// CoschedulingArgs holds arguments used to configure Coscheduling plugin.
+type CoschedulingArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// PermitWaitingTime is how long pods wait for the quorum of the pod group in the permit phase.
+	// Defaults to 60s.
+	PermitWaitingTime *metav1.Duration `json:"permitWaitingTime,omitempty"`
+}
+
+const defaultPermitWaitingTime = 60 * time.Second
+
+// decodeArgs decodes the args of the plugin in PluginConfig, and sets the default values.
+// nil args means all default values.
+func decodeArgs(obj runtime.Object) (*CoschedulingArgs, error) {
+	args := &CoschedulingArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(args); err != nil {
+			return nil, fmt.Errorf("decode args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type CoschedulingArgs, got %T", obj)
+	}
+
+	setDefaults(args)
+	return args, nil
+}
+
+func setDefaults(args *CoschedulingArgs) {
+	if args.PermitWaitingTime == nil {
+		args.PermitWaitingTime = &metav1.Duration{Duration: defaultPermitWaitingTime}
+	}
+}
+
+// validateArgs validates the defaulted args. It reports all invalid fields at once.
+func validateArgs(args *CoschedulingArgs) error {
+	var allErrs field.ErrorList
+	if args.PermitWaitingTime.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("permitWaitingTime"), args.PermitWaitingTime.Duration.String(), "must be positive"))
+	}
+	return allErrs.ToAggregate()
+}
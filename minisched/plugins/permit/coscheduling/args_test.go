@@ -0,0 +1,68 @@
+package coscheduling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestNew_args(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		wantArgs *CoschedulingArgs
+		wantErr  string
+	}{
+		{
+			name: "default values without args",
+			obj:  nil,
+			wantArgs: &CoschedulingArgs{
+				PermitWaitingTime: &metav1.Duration{Duration: 60 * time.Second},
+			},
+		},
+		{
+			name: "decode args in PluginConfig",
+			obj: &runtime.Unknown{Raw: []byte(`{
+				"apiVersion": "kubescheduler.config.k8s.io/v1beta2",
+				"kind": "CoschedulingArgs",
+				"permitWaitingTime": "10s"
+			}`)},
+			wantArgs: &CoschedulingArgs{
+				TypeMeta:          metav1.TypeMeta{APIVersion: "kubescheduler.config.k8s.io/v1beta2", Kind: "CoschedulingArgs"},
+				PermitWaitingTime: &metav1.Duration{Duration: 10 * time.Second},
+			},
+		},
+		{
+			name:    "fail with unknown fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"permitWaitingTimeSeconds": 10}`)},
+			wantErr: `decode args: json: unknown field "permitWaitingTimeSeconds"`,
+		},
+		{
+			name:    "fail with the non-positive waiting time",
+			obj:     &runtime.Unknown{Raw: []byte(`{"permitWaitingTime": "0s"}`)},
+			wantErr: `validate CoschedulingArgs: permitWaitingTime: Invalid value: "0s": must be positive`,
+		},
+		{
+			name:    "fail with args of another type",
+			obj:     &metav1.Status{},
+			wantErr: "want args to be of type CoschedulingArgs, got *v1.Status",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl, err := New(tt.obj, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantArgs, pl.(*Coscheduling).args)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package coscheduling
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// Coscheduling is a plugin that schedules the pods in a pod group all together (gang scheduling).
+// A pod group is the pods labelled with the same PodGroupNameLabel in a namespace,
+// and PodGroupMinAvailableLabel is the number of pods which must be scheduled together.
+//
+// Each pod in a pod group waits in the permit phase until the quorum of the group is reserved,
+// and then all of them are allowed together.
+// If any of them is rejected, for example by the timeout, the other waiting pods in the group are rejected as well,
+// so that the resources reserved for them are released.
+//
+// IMPORTANT NOTE: this plugin counts only pods waiting in the permit phase.
+// Pods already bound aren't counted, so pods added to the group after it is scheduled wait for the quorum again.
+type Coscheduling struct {
+	h    waitingpod.Handle
+	args *CoschedulingArgs
+}
+
+var _ framework.ReservePlugin = &Coscheduling{}
+var _ framework.PermitPlugin = &Coscheduling{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "Coscheduling"
+
+	// PodGroupNameLabel is the label of the pod group name.
+	PodGroupNameLabel = "pod-group.scheduling.sigs.k8s.io/name"
+	// PodGroupMinAvailableLabel is the label of the number of pods which must be scheduled together.
+	PodGroupMinAvailableLabel = "pod-group.scheduling.sigs.k8s.io/min-available"
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (cs *Coscheduling) Name() string {
+	return Name
+}
+
+// Reserve does nothing. It's implemented to get Unreserve called.
+func (cs *Coscheduling) Reserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
+	return nil
+}
+
+// Unreserve rejects the other waiting pods in the pod group of p.
+// It's called when p is rejected in or after the permit phase.
+func (cs *Coscheduling) Unreserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
+	group, _, err := podGroup(p)
+	if err != nil || group == "" {
+		return
+	}
+
+	msg := fmt.Sprintf("rejected because pod %s in pod group %s is rejected", p.Name, group)
+	var rejected []*waitingpod.WaitingPod
+	cs.h.IterateOverWaitingPods(func(wp *waitingpod.WaitingPod) {
+		if wp.GetPod().UID == p.UID || !inPodGroup(wp.GetPod(), p.Namespace, group) {
+			return
+		}
+		rejected = append(rejected, wp)
+	})
+	for _, wp := range rejected {
+		wp.Reject(cs.Name(), msg)
+	}
+}
+
+// Permit makes p wait until the quorum of its pod group is reserved, and allows all of them once it is.
+func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	group, minAvailable, err := podGroup(p)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error()), 0
+	}
+	if group == "" {
+		// p doesn't belong to any pod group.
+		return nil, 0
+	}
+
+	// the pods reserved in the group are the waiting ones and p.
+	var waiting []*waitingpod.WaitingPod
+	cs.h.IterateOverWaitingPods(func(wp *waitingpod.WaitingPod) {
+		if inPodGroup(wp.GetPod(), p.Namespace, group) {
+			waiting = append(waiting, wp)
+		}
+	})
+	if len(waiting)+1 < minAvailable {
+		klog.V(3).InfoS("Pod is waiting for the quorum of the pod group", "pod", klog.KObj(p), "podGroup", group, "reserved", len(waiting)+1, "minAvailable", minAvailable)
+		return framework.NewStatus(framework.Wait, ""), cs.args.PermitWaitingTime.Duration
+	}
+
+	klog.V(3).InfoS("The quorum of the pod group is reserved; allow all pods in it", "podGroup", group, "minAvailable", minAvailable)
+	for _, wp := range waiting {
+		wp.Allow(cs.Name())
+	}
+	return nil, 0
+}
+
+// podGroup returns the name of the pod group and its min-available of the pod.
+// The name is empty if the pod doesn't belong to any pod group.
+func podGroup(p *v1.Pod) (string, int, error) {
+	group := p.Labels[PodGroupNameLabel]
+	if group == "" {
+		return "", 0, nil
+	}
+
+	minAvailable, err := strconv.Atoi(p.Labels[PodGroupMinAvailableLabel])
+	if err != nil || minAvailable < 1 {
+		return "", 0, fmt.Errorf("label %s of pod group %s must be a positive integer: %q", PodGroupMinAvailableLabel, group, p.Labels[PodGroupMinAvailableLabel])
+	}
+	return group, minAvailable, nil
+}
+
+func inPodGroup(p *v1.Pod, namespace, group string) bool {
+	return p.Namespace == namespace && p.Labels[PodGroupNameLabel] == group
+}
+
+// New initializes a new plugin and returns it.
+func New(obj runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArgs(args); err != nil {
+		return nil, fmt.Errorf("validate CoschedulingArgs: %w", err)
+	}
+
+	return &Coscheduling{h: h, args: args}, nil
+}
@@ -0,0 +1,124 @@
+package coscheduling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+func newPod(name, group, minAvailable string) *v1.Pod {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)}}
+	if group != "" {
+		pod.Labels = map[string]string{
+			PodGroupNameLabel:         group,
+			PodGroupMinAvailableLabel: minAvailable,
+		}
+	}
+	return pod
+}
+
+func newWaitingPod(pod *v1.Pod) *waitingpod.WaitingPod {
+	return waitingpod.NewWaitingPod(pod, map[string]time.Duration{Name: time.Minute})
+}
+
+func TestCoscheduling_Permit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		pod         *v1.Pod
+		waitingPods []*v1.Pod
+		wantCode    framework.Code
+		// wantAllowed is the waiting pods allowed by Permit.
+		wantAllowed []string
+	}{
+		{
+			name:     "allow the pod which doesn't belong to any pod group",
+			pod:      newPod("pod1", "", ""),
+			wantCode: framework.Success,
+		},
+		{
+			name:     "reject the pod with invalid min-available",
+			pod:      newPod("pod1", "group1", "zero"),
+			wantCode: framework.UnschedulableAndUnresolvable,
+		},
+		{
+			name: "wait until the quorum of the pod group is reserved",
+			pod:  newPod("pod2", "group1", "3"),
+			waitingPods: []*v1.Pod{
+				newPod("pod1", "group1", "3"),
+				newPod("pod3", "group2", "3"),
+			},
+			wantCode: framework.Wait,
+		},
+		{
+			name: "allow all pods in the pod group once the quorum is reserved",
+			pod:  newPod("pod3", "group1", "3"),
+			waitingPods: []*v1.Pod{
+				newPod("pod1", "group1", "3"),
+				newPod("pod2", "group1", "3"),
+				newPod("pod4", "group2", "3"),
+			},
+			wantCode:    framework.Success,
+			wantAllowed: []string{"pod1", "pod2"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := fake.NewHandle(nil)
+			for _, p := range tt.waitingPods {
+				h.WaitingPods.Add(newWaitingPod(p))
+			}
+			pl, err := New(nil, h)
+			assert.NoError(t, err)
+
+			status, _ := pl.(framework.PermitPlugin).Permit(context.Background(), nil, tt.pod, "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			for _, p := range tt.waitingPods {
+				allowed := len(h.WaitingPods.Get(p.UID).GetPendingPlugins()) == 0
+				assert.Equal(t, contains(tt.wantAllowed, p.Name), allowed, "pod %s", p.Name)
+			}
+		})
+	}
+}
+
+func TestCoscheduling_Unreserve(t *testing.T) {
+	t.Parallel()
+	h := fake.NewHandle(nil)
+	pod := newPod("pod1", "group1", "3")
+	sibling := newWaitingPod(newPod("pod2", "group1", "3"))
+	other := newWaitingPod(newPod("pod3", "group2", "3"))
+	h.WaitingPods.Add(newWaitingPod(pod))
+	h.WaitingPods.Add(sibling)
+	h.WaitingPods.Add(other)
+	pl, err := New(nil, h)
+	assert.NoError(t, err)
+
+	pl.(framework.ReservePlugin).Unreserve(context.Background(), nil, pod, "node1")
+
+	s := sibling.GetSignal()
+	assert.Equal(t, framework.Unschedulable, s.Code())
+	assert.Equal(t, Name, s.FailedPlugin())
+	assert.Empty(t, other.GetHistory())
+	assert.Empty(t, h.WaitingPods.Get(pod.UID).GetHistory())
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
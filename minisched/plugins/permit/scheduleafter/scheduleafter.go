@@ -0,0 +1,74 @@
+package scheduleafter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// ScheduleAfter is a sample permit plugin.
+// It delays the binding of the pod until the time in ScheduleAfterAnnotation of the pod,
+// e.g. `minisched/schedule-after: "2021-10-01T00:00:00Z"`.
+// The pod is rejected if the time is later than MaxWaitTime from now.
+//
+// The pod waits in the permit phase, and the plugin allows it with the handle at the time.
+type ScheduleAfter struct {
+	h handle.Handle
+	// now returns the current time. It's replaced in tests.
+	now func() time.Time
+}
+
+var _ framework.PermitPlugin = &ScheduleAfter{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "ScheduleAfter"
+
+	// ScheduleAfterAnnotation is the annotation of the time in RFC 3339 after which the pod can be bound.
+	ScheduleAfterAnnotation = "minisched/schedule-after"
+
+	// MaxWaitTime is how long the pod can wait in the permit phase.
+	MaxWaitTime = 10 * time.Minute
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *ScheduleAfter) Name() string {
+	return Name
+}
+
+// Permit makes the pod wait until the time in ScheduleAfterAnnotation.
+func (pl *ScheduleAfter) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	v, ok := p.Annotations[ScheduleAfterAnnotation]
+	if !ok {
+		return nil, 0
+	}
+	after, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("annotation %s must be RFC 3339 time: %q", ScheduleAfterAnnotation, v)), 0
+	}
+
+	delay := after.Sub(pl.now())
+	if delay <= 0 {
+		// no need to delay.
+		return nil, 0
+	}
+	if delay > MaxWaitTime {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("the pod can't wait until %s longer than %s", v, MaxWaitTime)), 0
+	}
+
+	waitingpod.AllowAfter(pl.h, p.GetUID(), pl.Name(), delay, MaxWaitTime)
+
+	return framework.NewStatus(framework.Wait, ""), MaxWaitTime
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	return &ScheduleAfter{h: h, now: time.Now}, nil
+}
@@ -0,0 +1,89 @@
+package scheduleafter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+func TestScheduleAfter_Permit(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantCode    framework.Code
+		// addAfter is how long the pod takes to become a waiting pod after Permit returns.
+		addAfter time.Duration
+		// wantAllowed is true if the waiting pod should be allowed later.
+		wantAllowed bool
+	}{
+		{
+			name:     "allow the pod without the annotation",
+			wantCode: framework.Success,
+		},
+		{
+			name:        "allow the pod whose time has passed",
+			annotations: map[string]string{ScheduleAfterAnnotation: "2021-09-30T23:59:59Z"},
+			wantCode:    framework.Success,
+		},
+		{
+			name:        "make the pod wait until the time",
+			annotations: map[string]string{ScheduleAfterAnnotation: now.Add(100 * time.Millisecond).Format(time.RFC3339Nano)},
+			wantCode:    framework.Wait,
+			wantAllowed: true,
+		},
+		{
+			name:        "allow the pod which becomes a waiting pod after the time",
+			annotations: map[string]string{ScheduleAfterAnnotation: now.Add(10 * time.Millisecond).Format(time.RFC3339Nano)},
+			addAfter:    100 * time.Millisecond,
+			wantCode:    framework.Wait,
+			wantAllowed: true,
+		},
+		{
+			name:        "reject the pod which waits too long",
+			annotations: map[string]string{ScheduleAfterAnnotation: "2021-10-02T00:00:00Z"},
+			wantCode:    framework.Unschedulable,
+		},
+		{
+			name:        "reject the pod with the invalid time",
+			annotations: map[string]string{ScheduleAfterAnnotation: "tomorrow"},
+			wantCode:    framework.UnschedulableAndUnresolvable,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := fake.NewHandle(nil)
+			p, err := New(nil, h)
+			assert.NoError(t, err)
+			pl := p.(*ScheduleAfter)
+			pl.now = func() time.Time { return now }
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", UID: types.UID("pod1"), Annotations: tt.annotations}}
+
+			status, timeout := pl.Permit(context.Background(), nil, pod, "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			if !tt.wantAllowed {
+				return
+			}
+			time.Sleep(tt.addAfter)
+			wp := waitingpod.NewWaitingPod(pod, map[string]time.Duration{Name: timeout})
+			h.WaitingPods.Add(wp)
+			assert.Eventually(t, func() bool {
+				return len(wp.GetPendingPlugins()) == 0
+			}, wait.ForeverTestTimeout, 10*time.Millisecond)
+		})
+	}
+}
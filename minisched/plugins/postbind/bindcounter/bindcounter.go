@@ -0,0 +1,55 @@
+package bindcounter
+
+import (
+	"context"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// BindCounter is a sample post bind plugin.
+// It counts the pods bound to each node by the scheduler, and logs the count.
+//
+// Post bind plugins are informational. They are called after the pod is bound, and can't fail.
+type BindCounter struct {
+	mu sync.Mutex
+	// count is the number of the pods bound to each node.
+	count map[string]int
+}
+
+var _ framework.PostBindPlugin = &BindCounter{}
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = "BindCounter"
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *BindCounter) Name() string {
+	return Name
+}
+
+// PostBind counts the pod bound to nodeName.
+func (pl *BindCounter) PostBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	pl.count[nodeName]++
+	klog.InfoS("Pod is bound", "pod", klog.KObj(p), "node", nodeName, "boundPods", pl.count[nodeName])
+}
+
+// Count returns the number of the pods bound to nodeName.
+func (pl *BindCounter) Count(nodeName string) int {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	return pl.count[nodeName]
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	return &BindCounter{count: map[string]int{}}, nil
+}
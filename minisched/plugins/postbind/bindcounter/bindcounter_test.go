@@ -0,0 +1,27 @@
+package bindcounter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func TestBindCounter_PostBind(t *testing.T) {
+	t.Parallel()
+	p, err := New(nil, fake.NewHandle(nil))
+	assert.NoError(t, err)
+	pl := p.(*BindCounter)
+
+	for _, nodeName := range []string{"node1", "node2", "node1"} {
+		pl.PostBind(context.Background(), nil, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}}, nodeName)
+	}
+
+	assert.Equal(t, 2, pl.Count("node1"))
+	assert.Equal(t, 1, pl.Count("node2"))
+	assert.Equal(t, 0, pl.Count("node3"))
+}
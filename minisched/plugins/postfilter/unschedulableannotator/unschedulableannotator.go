@@ -0,0 +1,87 @@
+package unschedulableannotator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// UnschedulableAnnotator is a sample post filter plugin.
+// It records why no node fits the pod to UnschedulableReasonsAnnotation of the pod,
+// e.g. `minisched/unschedulable-reasons: "2 node(s): node(s) were unschedulable"`.
+//
+// Post filter plugins run only when the pod is unschedulable in the filter phase.
+// This plugin never makes the pod schedulable, unlike the preemption of the original kube-scheduler.
+type UnschedulableAnnotator struct {
+	h handle.Handle
+}
+
+var _ framework.PostFilterPlugin = &UnschedulableAnnotator{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "UnschedulableAnnotator"
+
+	// UnschedulableReasonsAnnotation is the annotation of the reasons why no node fits the pod.
+	UnschedulableReasonsAnnotation = "minisched/unschedulable-reasons"
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *UnschedulableAnnotator) Name() string {
+	return Name
+}
+
+// PostFilter annotates the pod with the summary of filteredNodeStatusMap.
+func (pl *UnschedulableAnnotator) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{UnschedulableReasonsAnnotation: summarize(filteredNodeStatusMap)},
+		},
+	})
+	if err != nil {
+		return nil, framework.AsStatus(fmt.Errorf("marshal patch: %w", err))
+	}
+
+	if _, err := pl.h.ClientSet().CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return nil, framework.AsStatus(fmt.Errorf("patch pod %s/%s: %w", pod.Namespace, pod.Name, err))
+	}
+
+	return nil, framework.NewStatus(framework.Unschedulable, "the pod is annotated with the unschedulable reasons")
+}
+
+// summarize returns how many nodes fail for each reason, in the alphabetical order of the reasons.
+func summarize(m framework.NodeToStatusMap) string {
+	count := map[string]int{}
+	for _, status := range m {
+		for _, reason := range status.Reasons() {
+			count[reason]++
+		}
+	}
+
+	reasons := make([]string, 0, len(count))
+	for reason := range count {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	summary := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		summary = append(summary, fmt.Sprintf("%d node(s): %s", count[reason], reason))
+	}
+	return strings.Join(summary, ", ")
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	return &UnschedulableAnnotator{h: h}, nil
+}
@@ -0,0 +1,56 @@
+package unschedulableannotator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func TestUnschedulableAnnotator_PostFilter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name                  string
+		filteredNodeStatusMap framework.NodeToStatusMap
+		want                  string
+	}{
+		{
+			name:                  "annotate the pod without reasons",
+			filteredNodeStatusMap: framework.NodeToStatusMap{},
+			want:                  "",
+		},
+		{
+			name: "annotate the pod with the number of nodes for each reason",
+			filteredNodeStatusMap: framework.NodeToStatusMap{
+				"node1": framework.NewStatus(framework.Unschedulable, "reason2"),
+				"node2": framework.NewStatus(framework.Unschedulable, "reason1", "reason2"),
+				"node3": framework.NewStatus(framework.UnschedulableAndUnresolvable, "reason2"),
+			},
+			want: "1 node(s): reason1, 3 node(s): reason2",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}}
+			client := clientsetfake.NewSimpleClientset(pod)
+			pl, err := New(nil, fake.NewHandle(client))
+			assert.NoError(t, err)
+
+			result, status := pl.(framework.PostFilterPlugin).PostFilter(context.Background(), nil, pod, tt.filteredNodeStatusMap)
+
+			assert.Nil(t, result)
+			assert.Equal(t, framework.Unschedulable, status.Code())
+			got, err := client.CoreV1().Pods("default").Get(context.Background(), "pod1", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Annotations[UnschedulableReasonsAnnotation])
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package nodeannotator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// NodeAnnotator is a sample pre bind plugin.
+// It annotates the pod with the node selected for it, i.e. `minisched/selected-node: node1`, before the pod is bound.
+//
+// Pre bind plugins prepare the node or the pod for the binding,
+// and the pod isn't bound if any of them fails.
+type NodeAnnotator struct {
+	h handle.Handle
+}
+
+var _ framework.PreBindPlugin = &NodeAnnotator{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "NodeAnnotator"
+
+	// SelectedNodeAnnotation is the annotation of the node selected for the pod.
+	SelectedNodeAnnotation = "minisched/selected-node"
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *NodeAnnotator) Name() string {
+	return Name
+}
+
+// PreBind annotates the pod with nodeName.
+func (pl *NodeAnnotator) PreBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{SelectedNodeAnnotation: nodeName},
+		},
+	})
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("marshal patch: %w", err))
+	}
+
+	if _, err := pl.h.ClientSet().CoreV1().Pods(p.Namespace).Patch(ctx, p.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return framework.AsStatus(fmt.Errorf("patch pod %s/%s: %w", p.Namespace, p.Name, err))
+	}
+	return nil
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	return &NodeAnnotator{h: h}, nil
+}
@@ -0,0 +1,57 @@
+package nodeannotator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newPod() *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}}
+}
+
+func TestNodeAnnotator_PreBind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		objects  []runtime.Object
+		wantCode framework.Code
+	}{
+		{
+			name:     "annotate the pod with the node",
+			objects:  []runtime.Object{newPod()},
+			wantCode: framework.Success,
+		},
+		{
+			name:     "fail if the pod doesn't exist",
+			wantCode: framework.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := clientsetfake.NewSimpleClientset(tt.objects...)
+			pl, err := New(nil, fake.NewHandle(client))
+			assert.NoError(t, err)
+
+			status := pl.(framework.PreBindPlugin).PreBind(context.Background(), nil, newPod(), "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			if !status.IsSuccess() {
+				return
+			}
+			got, err := client.CoreV1().Pods("default").Get(context.Background(), "pod1", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "node1", got.Annotations[SelectedNodeAnnotation])
+		})
+	}
+}
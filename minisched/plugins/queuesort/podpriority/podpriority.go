@@ -0,0 +1,37 @@
+package podpriority
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// PodPriority is a sample queue sort plugin.
+// It pops the pod with the highest priority first, and the pod added to the queue earlier first among the same priority.
+//
+// Only one queue sort plugin can be enabled, and the queue is FIFO without it.
+type PodPriority struct{}
+
+var _ framework.QueueSortPlugin = &PodPriority{}
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = "PodPriority"
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *PodPriority) Name() string {
+	return Name
+}
+
+// Less returns true if pInfo1 should be popped before pInfo2.
+func (pl *PodPriority) Less(pInfo1, pInfo2 *framework.QueuedPodInfo) bool {
+	p1 := corev1helpers.PodPriority(pInfo1.Pod)
+	p2 := corev1helpers.PodPriority(pInfo2.Pod)
+	return p1 > p2 || (p1 == p2 && pInfo1.Timestamp.Before(pInfo2.Timestamp))
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	return &PodPriority{}, nil
+}
@@ -0,0 +1,58 @@
+package podpriority
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newPodInfo(priority int32, timestamp time.Time) *framework.QueuedPodInfo {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}, Spec: v1.PodSpec{Priority: &priority}}
+	return &framework.QueuedPodInfo{PodInfo: framework.NewPodInfo(pod), Timestamp: timestamp}
+}
+
+func TestPodPriority_Less(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	tests := []struct {
+		name   string
+		pInfo1 *framework.QueuedPodInfo
+		pInfo2 *framework.QueuedPodInfo
+		want   bool
+	}{
+		{
+			name:   "the pod with the higher priority goes first",
+			pInfo1: newPodInfo(10, now),
+			pInfo2: newPodInfo(1, now.Add(-time.Second)),
+			want:   true,
+		},
+		{
+			name:   "the pod with the lower priority goes later",
+			pInfo1: newPodInfo(1, now.Add(-time.Second)),
+			pInfo2: newPodInfo(10, now),
+			want:   false,
+		},
+		{
+			name:   "the pod added earlier goes first among the same priority",
+			pInfo1: newPodInfo(1, now.Add(-time.Second)),
+			pInfo2: newPodInfo(1, now),
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl, err := New(nil, fake.NewHandle(nil))
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.want, pl.(framework.QueueSortPlugin).Less(tt.pInfo1, tt.pInfo2))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package inflightpods
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// InFlightPods is a sample reserve plugin.
+// It keeps track of the pods reserved on each node but not bound yet, i.e. the pods in the binding cycles,
+// and rejects the pod when the node already has MaxInFlightPods of them.
+//
+// Reserve adds the pod to the node, and Unreserve or PostBind removes it.
+// They are called from different goroutines, so the pods are guarded by a lock.
+type InFlightPods struct {
+	mu sync.Mutex
+	// pods is the UIDs of the in-flight pods on each node.
+	pods map[string]sets.String
+}
+
+var _ framework.ReservePlugin = &InFlightPods{}
+var _ framework.PostBindPlugin = &InFlightPods{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "InFlightPods"
+
+	// MaxInFlightPods is the max number of the in-flight pods on a node.
+	MaxInFlightPods = 3
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *InFlightPods) Name() string {
+	return Name
+}
+
+// Reserve adds the pod to the in-flight pods on the node.
+func (pl *InFlightPods) Reserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	if pl.pods[nodeName] == nil {
+		pl.pods[nodeName] = sets.NewString()
+	}
+	if pl.pods[nodeName].Len() >= MaxInFlightPods {
+		return framework.NewStatus(framework.Unschedulable, fmt.Sprintf("node %s already has %d in-flight pods", nodeName, MaxInFlightPods))
+	}
+	pl.pods[nodeName].Insert(string(p.UID))
+	return nil
+}
+
+// Unreserve removes the pod from the in-flight pods on the node.
+func (pl *InFlightPods) Unreserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
+	pl.remove(p.UID, nodeName)
+}
+
+// PostBind removes the pod from the in-flight pods on the node since it has been bound.
+func (pl *InFlightPods) PostBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
+	pl.remove(p.UID, nodeName)
+}
+
+// Count returns the number of the in-flight pods on the node.
+func (pl *InFlightPods) Count(nodeName string) int {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	return pl.pods[nodeName].Len()
+}
+
+func (pl *InFlightPods) remove(uid types.UID, nodeName string) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	pl.pods[nodeName].Delete(string(uid))
+	if pl.pods[nodeName].Len() == 0 {
+		delete(pl.pods, nodeName)
+	}
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	return &InFlightPods{pods: map[string]sets.String{}}, nil
+}
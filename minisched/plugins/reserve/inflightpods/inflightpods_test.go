@@ -0,0 +1,50 @@
+package inflightpods
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newPod(name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)}}
+}
+
+func TestInFlightPods(t *testing.T) {
+	t.Parallel()
+	p, err := New(nil, fake.NewHandle(nil))
+	assert.NoError(t, err)
+	pl := p.(*InFlightPods)
+	ctx := context.Background()
+
+	for i := 0; i < MaxInFlightPods; i++ {
+		status := pl.Reserve(ctx, nil, newPod(fmt.Sprintf("pod%d", i)), "node1")
+		assert.True(t, status.IsSuccess())
+	}
+	assert.Equal(t, MaxInFlightPods, pl.Count("node1"))
+
+	// the node is full, but the other node isn't.
+	status := pl.Reserve(ctx, nil, newPod("extra"), "node1")
+	assert.Equal(t, framework.Unschedulable, status.Code())
+	status = pl.Reserve(ctx, nil, newPod("extra"), "node2")
+	assert.True(t, status.IsSuccess())
+
+	// unreserved or bound pods aren't in-flight anymore.
+	pl.Unreserve(ctx, nil, newPod("pod0"), "node1")
+	pl.PostBind(ctx, nil, newPod("pod1"), "node1")
+	assert.Equal(t, MaxInFlightPods-2, pl.Count("node1"))
+	status = pl.Reserve(ctx, nil, newPod("extra"), "node1")
+	assert.True(t, status.IsSuccess())
+
+	// removing the pod which isn't in-flight is no-op.
+	pl.Unreserve(ctx, nil, newPod("unknown"), "node3")
+	assert.Equal(t, 0, pl.Count("node3"))
+}
@@ -0,0 +1,89 @@
+package nodenumber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// NodeNumberArgs holds arguments used to configure NodeNumber plugin.
+type NodeNumberArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// MatchScore is the score of nodes whose number suffix matches the pod's one.
+	// Defaults to 10.
+	MatchScore *int64 `json:"matchScore,omitempty"`
+	// DelayPerUnit is how long the binding is delayed per the number suffix of the node.
+	// Defaults to 1s.
+	DelayPerUnit *metav1.Duration `json:"delayPerUnit,omitempty"`
+	// PermitTimeout is how long pods can wait in the permit phase.
+	// Pods are rejected if the delay is longer than it.
+	// Defaults to 10s.
+	PermitTimeout *metav1.Duration `json:"permitTimeout,omitempty"`
+	// UseFullNumberSuffix makes the plugin use all trailing digits of names as the number suffix.
+	// e.g. the number suffix of "node10" is 10 with it, and 0 without it.
+	UseFullNumberSuffix bool `json:"useFullNumberSuffix,omitempty"`
+	// DisablePermitDelay disables the delay of the binding in the permit phase.
+	DisablePermitDelay bool `json:"disablePermitDelay,omitempty"`
+}
+
+const (
+	defaultMatchScore    int64 = 10
+	defaultDelayPerUnit        = time.Second
+	defaultPermitTimeout       = 10 * time.Second
+)
+
+// decodeArgs decodes the args of the plugin in PluginConfig, and sets the default values.
+// nil args means all default values.
+func decodeArgs(obj runtime.Object) (*NodeNumberArgs, error) {
+	args := &NodeNumberArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(args); err != nil {
+			return nil, fmt.Errorf("decode args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type NodeNumberArgs, got %T", obj)
+	}
+
+	setDefaults(args)
+	return args, nil
+}
+
+func setDefaults(args *NodeNumberArgs) {
+	if args.MatchScore == nil {
+		s := defaultMatchScore
+		args.MatchScore = &s
+	}
+	if args.DelayPerUnit == nil {
+		args.DelayPerUnit = &metav1.Duration{Duration: defaultDelayPerUnit}
+	}
+	if args.PermitTimeout == nil {
+		args.PermitTimeout = &metav1.Duration{Duration: defaultPermitTimeout}
+	}
+}
+
+// validateArgs validates the defaulted args. It reports all invalid fields at once.
+func validateArgs(args *NodeNumberArgs) error {
+	var allErrs field.ErrorList
+	if *args.MatchScore < framework.MinNodeScore || *args.MatchScore > framework.MaxNodeScore {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("matchScore"), *args.MatchScore,
+			fmt.Sprintf("must be in the range [%d, %d]", framework.MinNodeScore, framework.MaxNodeScore)))
+	}
+	if args.DelayPerUnit.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("delayPerUnit"), args.DelayPerUnit.Duration.String(), "must not be negative"))
+	}
+	if args.PermitTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("permitTimeout"), args.PermitTimeout.Duration.String(), "must be positive"))
+	}
+	return allErrs.ToAggregate()
+}
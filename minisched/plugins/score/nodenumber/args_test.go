@@ -0,0 +1,81 @@
+package nodenumber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestNew_args(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		wantArgs *NodeNumberArgs
+		wantErr  string
+	}{
+		{
+			name: "default values without args",
+			obj:  nil,
+			wantArgs: &NodeNumberArgs{
+				MatchScore:    int64Ptr(10),
+				DelayPerUnit:  &metav1.Duration{Duration: time.Second},
+				PermitTimeout: &metav1.Duration{Duration: 10 * time.Second},
+			},
+		},
+		{
+			name: "decode args in PluginConfig",
+			obj: &runtime.Unknown{Raw: []byte(`{
+				"apiVersion": "kubescheduler.config.k8s.io/v1beta2",
+				"kind": "NodeNumberArgs",
+				"matchScore": 50,
+				"delayPerUnit": "100ms",
+				"useFullNumberSuffix": true,
+				"disablePermitDelay": true
+			}`)},
+			wantArgs: &NodeNumberArgs{
+				TypeMeta:            metav1.TypeMeta{APIVersion: "kubescheduler.config.k8s.io/v1beta2", Kind: "NodeNumberArgs"},
+				MatchScore:          int64Ptr(50),
+				DelayPerUnit:        &metav1.Duration{Duration: 100 * time.Millisecond},
+				PermitTimeout:       &metav1.Duration{Duration: 10 * time.Second},
+				UseFullNumberSuffix: true,
+				DisablePermitDelay:  true,
+			},
+		},
+		{
+			name:    "fail with unknown fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"matchScores": 50}`)},
+			wantErr: `decode args: json: unknown field "matchScores"`,
+		},
+		{
+			name:    "report all invalid fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"matchScore": 101, "delayPerUnit": "-1s", "permitTimeout": "0s"}`)},
+			wantErr: `validate NodeNumberArgs: [matchScore: Invalid value: 101: must be in the range [0, 100], delayPerUnit: Invalid value: "-1s": must not be negative, permitTimeout: Invalid value: "0s": must be positive]`,
+		},
+		{
+			name:    "fail with args of another type",
+			obj:     &metav1.Status{},
+			wantErr: "want args to be of type NodeNumberArgs, got *v1.Status",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl, err := New(tt.obj, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantArgs, pl.(*NodeNumber).args)
+		})
+	}
+}
@@ -2,9 +2,12 @@ package nodenumber
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
 	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
 
 	v1 "k8s.io/api/core/v1"
@@ -18,9 +21,12 @@ import (
 // When schedule a pod named Pod1, a Node named Node9 gets a higher score than a node named Node1.
 // And if it is decided that Pod1 will go to Node9, this plugin delay the binding by 9 seconds.
 //
-// IMPORTANT NOTE: this plugin only handle single digit numbers only.
+// IMPORTANT NOTE: this plugin only handle single digit numbers by default. NodeNumberArgs.UseFullNumberSuffix makes it handle multi-digit numbers.
+//
+// The score, the delay and the timeout are configurable with NodeNumberArgs.
 type NodeNumber struct {
-	h waitingpod.Handle
+	h    waitingpod.Handle
+	args *NodeNumberArgs
 }
 
 var _ framework.ScorePlugin = &NodeNumber{}
@@ -48,8 +54,7 @@ func (s *preScoreState) Clone() framework.StateData {
 }
 
 func (pl *NodeNumber) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
-	podNameLastChar := pod.Name[len(pod.Name)-1:]
-	podnum, err := strconv.Atoi(podNameLastChar)
+	podnum, err := pl.numberSuffix(pod.Name)
 	if err != nil {
 		// return success even if its suffix is non-number.
 		return nil
@@ -72,15 +77,17 @@ func (pl *NodeNumber) EventsToRegister() []framework.ClusterEvent {
 // Score invoked at the score extension point.
 func (pl *NodeNumber) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
 	data, err := state.Read(preScoreStateKey)
+	if errors.Is(err, framework.ErrNotFound) {
+		// PreScore writes no state for the pod without number suffix, e.g. the pods created by ReplicaSets.
+		return 0, nil
+	}
 	if err != nil {
 		return 0, framework.AsStatus(err)
 	}
 
 	s := data.(*preScoreState)
 
-	nodeNameLastChar := nodeName[len(nodeName)-1:]
-
-	nodenum, err := strconv.Atoi(nodeNameLastChar)
+	nodenum, err := pl.numberSuffix(nodeName)
 	if err != nil {
 		// return success even if its suffix is non-number.
 		return 0, nil
@@ -88,7 +95,7 @@ func (pl *NodeNumber) Score(ctx context.Context, state *framework.CycleState, po
 
 	if s.podSuffixNumber == nodenum {
 		// if match, node get high score.
-		return 10, nil
+		return *pl.args.MatchScore, nil
 	}
 
 	return 0, nil
@@ -100,25 +107,51 @@ func (pl *NodeNumber) ScoreExtensions() framework.ScoreExtensions {
 }
 
 func (pl *NodeNumber) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
-	nodeNameLastChar := nodeName[len(nodeName)-1:]
+	if pl.args.DisablePermitDelay {
+		return nil, 0
+	}
 
-	nodenum, err := strconv.Atoi(nodeNameLastChar)
+	nodenum, err := pl.numberSuffix(nodeName)
 	if err != nil {
 		// return allow(success) even if its suffix is non-number.
 		return nil, 0
 	}
 
-	// allow pod after {nodenum} seconds
-	time.AfterFunc(time.Duration(nodenum)*time.Second, func() {
-		wp := pl.h.GetWaitingPod(p.GetUID())
-		wp.Allow(pl.Name())
-	})
+	delay := time.Duration(nodenum) * pl.args.DelayPerUnit.Duration
+	if delay == 0 {
+		// no need to delay.
+		return nil, 0
+	}
+
+	// allow pod after {nodenum} * {delay per unit}
+	waitingpod.AllowAfter(pl.h, p.GetUID(), pl.Name(), delay, pl.args.PermitTimeout.Duration)
 
-	timeout := time.Duration(10) * time.Second
-	return framework.NewStatus(framework.Wait, ""), timeout
+	return framework.NewStatus(framework.Wait, ""), pl.args.PermitTimeout.Duration
+}
+
+// numberSuffix returns the number suffix of the name.
+// It's the last digit, or all trailing digits with NodeNumberArgs.UseFullNumberSuffix.
+func (pl *NodeNumber) numberSuffix(name string) (int, error) {
+	if !pl.args.UseFullNumberSuffix {
+		return strconv.Atoi(name[len(name)-1:])
+	}
+
+	i := len(name)
+	for i > 0 && '0' <= name[i-1] && name[i-1] <= '9' {
+		i--
+	}
+	return strconv.Atoi(name[i:])
 }
 
 // New initializes a new plugin and returns it.
-func New(_ runtime.Object, h waitingpod.Handle) (framework.Plugin, error) {
-	return &NodeNumber{h: h}, nil
+func New(obj runtime.Object, h handle.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArgs(args); err != nil {
+		return nil, fmt.Errorf("validate NodeNumberArgs: %w", err)
+	}
+
+	return &NodeNumber{h: h, args: args}, nil
 }
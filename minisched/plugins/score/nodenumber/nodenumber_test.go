@@ -0,0 +1,138 @@
+package nodenumber
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func TestNodeNumber_Score(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     string
+		podName  string
+		nodeName string
+		want     int64
+	}{
+		{
+			name:     "matched by the last digit",
+			podName:  "pod1",
+			nodeName: "node11",
+			want:     10,
+		},
+		{
+			name:     "matched by all trailing digits",
+			args:     `{"useFullNumberSuffix": true, "matchScore": 30}`,
+			podName:  "pod11",
+			nodeName: "node11",
+			want:     30,
+		},
+		{
+			name:     "not matched by all trailing digits",
+			args:     `{"useFullNumberSuffix": true}`,
+			podName:  "pod1",
+			nodeName: "node11",
+			want:     0,
+		},
+		{
+			name:     "pod without number suffix",
+			podName:  "pod-7d4b9c-xkzvq",
+			nodeName: "node1",
+			want:     0,
+		},
+		{
+			name:     "node without number suffix",
+			podName:  "pod1",
+			nodeName: "node",
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, tt.args)
+			state := framework.NewCycleState()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: tt.podName}}
+
+			assert.True(t, pl.PreScore(context.Background(), state, pod, nil).IsSuccess())
+			got, status := pl.Score(context.Background(), state, pod, tt.nodeName)
+			assert.True(t, status.IsSuccess())
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNodeNumber_Permit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		args        string
+		nodeName    string
+		wantCode    framework.Code
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "wait with the default timeout",
+			nodeName:    "node9",
+			wantCode:    framework.Wait,
+			wantTimeout: 10 * time.Second,
+		},
+		{
+			name:        "wait with the configured timeout",
+			args:        `{"permitTimeout": "1m"}`,
+			nodeName:    "node9",
+			wantCode:    framework.Wait,
+			wantTimeout: time.Minute,
+		},
+		{
+			name:     "allow without delay on the node with number 0",
+			nodeName: "node10",
+			wantCode: framework.Success,
+		},
+		{
+			name:     "allow with the permit delay disabled",
+			args:     `{"disablePermitDelay": true}`,
+			nodeName: "node9",
+			wantCode: framework.Success,
+		},
+		{
+			name:     "allow with zero delay per unit",
+			args:     `{"delayPerUnit": "0s"}`,
+			nodeName: "node9",
+			wantCode: framework.Success,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, tt.args)
+
+			status, timeout := pl.Permit(context.Background(), nil, &v1.Pod{}, tt.nodeName)
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Equal(t, tt.wantTimeout, timeout)
+		})
+	}
+}
+
+func newPlugin(t *testing.T, args string) *NodeNumber {
+	t.Helper()
+	var obj runtime.Object
+	if args != "" {
+		obj = &runtime.Unknown{Raw: []byte(args)}
+	}
+	// the delayed Allow doesn't find the waiting pod in the handle.
+	pl, err := New(obj, fake.NewHandle(nil))
+	assert.NoError(t, err)
+	return pl.(*NodeNumber)
+}
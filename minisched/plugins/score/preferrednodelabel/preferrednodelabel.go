@@ -0,0 +1,115 @@
+package preferrednodelabel
+
+import (
+	"context"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// PreferredNodeLabel is a sample pre score and score plugin.
+// It favors nodes which have more label keys listed in PreferredNodeLabelsAnnotation of the pod.
+// For example, when the pod is annotated with `minisched/preferred-node-labels: "disktype,zone"`,
+// the node which has both "disktype" and "zone" labels gets the highest score.
+//
+// PreScore counts the matched labels of all nodes, Score returns the count,
+// and NormalizeScore scales the counts into [framework.MinNodeScore, framework.MaxNodeScore].
+type PreferredNodeLabel struct{}
+
+var _ framework.PreScorePlugin = &PreferredNodeLabel{}
+var _ framework.ScorePlugin = &PreferredNodeLabel{}
+var _ framework.ScoreExtensions = &PreferredNodeLabel{}
+
+const (
+	// Name is the name of the plugin used in the plugin registry and configurations.
+	Name = "PreferredNodeLabel"
+
+	// PreferredNodeLabelsAnnotation is the annotation of the comma separated label keys which nodes preferably have.
+	PreferredNodeLabelsAnnotation = "minisched/preferred-node-labels"
+
+	preScoreStateKey = "PreScore" + Name
+)
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *PreferredNodeLabel) Name() string {
+	return Name
+}
+
+// preScoreState computed at PreScore and used at Score.
+type preScoreState struct {
+	// matchedLabels is the number of the preferred labels each node has.
+	matchedLabels map[string]int64
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore counts the preferred labels each node has.
+func (pl *PreferredNodeLabel) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	var keys []string
+	for _, key := range strings.Split(pod.Annotations[PreferredNodeLabelsAnnotation], ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	s := &preScoreState{matchedLabels: make(map[string]int64, len(nodes))}
+	for _, n := range nodes {
+		for _, key := range keys {
+			if _, ok := n.Labels[key]; ok {
+				s.matchedLabels[n.Name]++
+			}
+		}
+	}
+	state.Write(preScoreStateKey, s)
+
+	return nil
+}
+
+// Score invoked at the score extension point.
+func (pl *PreferredNodeLabel) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	data, err := state.Read(preScoreStateKey)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	s := data.(*preScoreState)
+
+	return s.matchedLabels[nodeName], nil
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *PreferredNodeLabel) ScoreExtensions() framework.ScoreExtensions {
+	return pl
+}
+
+// NormalizeScore scales the scores so that the node with the most preferred labels gets framework.MaxNodeScore.
+func (pl *PreferredNodeLabel) NormalizeScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, scores framework.NodeScoreList) *framework.Status {
+	var highest int64
+	for _, s := range scores {
+		if s.Score > highest {
+			highest = s.Score
+		}
+	}
+	if highest == 0 {
+		// no node has the preferred labels.
+		return nil
+	}
+
+	for i := range scores {
+		scores[i].Score = scores[i].Score * framework.MaxNodeScore / highest
+	}
+	return nil
+}
+
+// New initializes a new plugin and returns it.
+func New(_ runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	return &PreferredNodeLabel{}, nil
+}
@@ -0,0 +1,81 @@
+package preferrednodelabel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+func newNode(name string, labelKeys ...string) *v1.Node {
+	labels := map[string]string{}
+	for _, k := range labelKeys {
+		labels[k] = ""
+	}
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestPreferredNodeLabel(t *testing.T) {
+	t.Parallel()
+	nodes := []*v1.Node{
+		newNode("node1"),
+		newNode("node2", "disktype"),
+		newNode("node3", "disktype", "zone", "other"),
+	}
+	tests := []struct {
+		name       string
+		annotation string
+		wantScores framework.NodeScoreList
+	}{
+		{
+			name: "all nodes get the lowest score without the annotation",
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 0},
+				{Name: "node2", Score: 0},
+				{Name: "node3", Score: 0},
+			},
+		},
+		{
+			name:       "the node with more preferred labels gets the higher score",
+			annotation: "disktype, zone",
+			wantScores: framework.NodeScoreList{
+				{Name: "node1", Score: 0},
+				{Name: "node2", Score: 50},
+				{Name: "node3", Score: 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := New(nil, fake.NewHandle(nil))
+			assert.NoError(t, err)
+			pl := p.(framework.ScorePlugin)
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod1",
+				Annotations: map[string]string{PreferredNodeLabelsAnnotation: tt.annotation},
+			}}
+			state := framework.NewCycleState()
+
+			status := p.(framework.PreScorePlugin).PreScore(context.Background(), state, pod, nodes)
+			assert.True(t, status.IsSuccess())
+
+			scores := make(framework.NodeScoreList, 0, len(nodes))
+			for _, n := range nodes {
+				score, status := pl.Score(context.Background(), state, pod, n.Name)
+				assert.True(t, status.IsSuccess())
+				scores = append(scores, framework.NodeScore{Name: n.Name, Score: score})
+			}
+			status = pl.ScoreExtensions().NormalizeScore(context.Background(), state, pod, scores)
+			assert.True(t, status.IsSuccess())
+
+			assert.Equal(t, tt.wantScores, scores)
+		})
+	}
+}
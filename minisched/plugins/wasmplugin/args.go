@@ -0,0 +1,59 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WasmPluginArgs holds arguments used to configure WasmPlugin.
+type WasmPluginArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Path is the path of the .wasm file of the plugin. Required.
+	Path string `json:"path"`
+	// Timeout is how long each call of filter or score can run.
+	// The call which doesn't return in time results in the Error status.
+	// Defaults to 1s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+const defaultTimeout = time.Second
+
+// decodeArgs decodes the args of the plugin in PluginConfig, and sets the default values.
+func decodeArgs(obj runtime.Object) (*WasmPluginArgs, error) {
+	args := &WasmPluginArgs{}
+	switch t := obj.(type) {
+	case nil:
+	case *runtime.Unknown:
+		d := json.NewDecoder(bytes.NewReader(t.Raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(args); err != nil {
+			return nil, fmt.Errorf("decode args: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("want args to be of type WasmPluginArgs, got %T", obj)
+	}
+
+	if args.Timeout == nil {
+		args.Timeout = &metav1.Duration{Duration: defaultTimeout}
+	}
+	return args, nil
+}
+
+// validateArgs validates the defaulted args. It reports all invalid fields at once.
+func validateArgs(args *WasmPluginArgs) error {
+	var allErrs field.ErrorList
+	if args.Path == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("path"), ""))
+	}
+	if args.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("timeout"), args.Timeout.Duration.String(), "must be positive"))
+	}
+	return allErrs.ToAggregate()
+}
@@ -0,0 +1,338 @@
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/go-interpreter/wagon/exec"
+	"github.com/go-interpreter/wagon/wasm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+)
+
+// WasmPlugin is a plugin that runs Filter and Score compiled to WebAssembly.
+// The module is run by a pure-Go interpreter, and it can't import any function,
+// so it can only compute the result from the input.
+//
+// ABI: the module exports the following functions.
+//
+//	alloc(size i32) i32               returns the address of size bytes where the input is written.
+//	filter(ptr i32, len i32) i64      takes FilterInput in JSON, and returns Status in JSON.
+//	score(ptr i32, len i32) i64       takes ScoreInput in JSON, and returns ScoreOutput in JSON.
+//
+// filter and score return the address of the output in the upper 32 bits and its length in the lower 32 bits.
+// The module may export only one of filter and score.
+// alloc is called once before each call of filter or score, and it invalidates the input and the output of the previous call.
+// So the module can reuse its memory from there, e.g. reset its bump allocator in alloc.
+//
+// Each call runs until WasmPluginArgs.Timeout or the deadline of the context.
+// The module which doesn't return in time is instantiated again, so its memory and globals are reset.
+//
+// IMPORTANT NOTE: the interpreter supports only the WebAssembly MVP.
+type WasmPlugin struct {
+	path    string
+	module  *wasm.Module
+	timeout time.Duration
+
+	// mu guards vm since it isn't thread-safe.
+	mu sync.Mutex
+	vm *exec.VM
+
+	// the indexes of the exported functions. filter and score are -1 if they aren't exported.
+	alloc  int64
+	filter int64
+	score  int64
+}
+
+var _ framework.FilterPlugin = &WasmPlugin{}
+var _ framework.PreScorePlugin = &WasmPlugin{}
+var _ framework.ScorePlugin = &WasmPlugin{}
+
+// Name is the name of the plugin used in the plugin registry and configurations.
+const Name = "WasmPlugin"
+const preScoreStateKey = "PreScore" + Name
+
+// Status is the result of filter. Code is the name of framework.Code, e.g. "Success" or "Unschedulable".
+type Status struct {
+	Code    string   `json:"code"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// FilterInput is the input of filter.
+type FilterInput struct {
+	Pod  *v1.Pod  `json:"pod"`
+	Node *v1.Node `json:"node"`
+	// Pods are the pods on the node.
+	Pods []*v1.Pod `json:"pods,omitempty"`
+}
+
+// ScoreInput is the input of score.
+type ScoreInput struct {
+	Pod  *v1.Pod  `json:"pod"`
+	Node *v1.Node `json:"node"`
+}
+
+// ScoreOutput is the output of score. Score must be in [framework.MinNodeScore, framework.MaxNodeScore].
+type ScoreOutput struct {
+	Score  int64  `json:"score"`
+	Status Status `json:"status"`
+}
+
+// Name returns name of the plugin. It is used in logs, etc.
+func (pl *WasmPlugin) Name() string {
+	return Name
+}
+
+// Filter runs filter of the module.
+func (pl *WasmPlugin) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	in := &FilterInput{Pod: pod, Node: nodeInfo.Node()}
+	for _, p := range nodeInfo.Pods {
+		in.Pods = append(in.Pods, p.Pod)
+	}
+	out := &Status{}
+	if err := pl.call(ctx, "filter", pl.filter, in, out); err != nil {
+		return framework.AsStatus(err)
+	}
+
+	s, err := toStatus(*out, framework.Success, framework.Unschedulable, framework.UnschedulableAndUnresolvable)
+	if err != nil {
+		return framework.AsStatus(fmt.Errorf("filter of %s: %w", pl.path, err))
+	}
+	return s
+}
+
+// preScoreState computed at PreScore and used at Score.
+type preScoreState struct {
+	nodes map[string]*v1.Node
+}
+
+// Clone implements the mandatory Clone interface. We don't really copy the data since
+// there is no need for that.
+func (s *preScoreState) Clone() framework.StateData {
+	return s
+}
+
+// PreScore keeps the nodes so that Score can pass them to the module.
+func (pl *WasmPlugin) PreScore(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodes []*v1.Node) *framework.Status {
+	s := &preScoreState{nodes: make(map[string]*v1.Node, len(nodes))}
+	for _, n := range nodes {
+		s.nodes[n.Name] = n
+	}
+	state.Write(preScoreStateKey, s)
+
+	return nil
+}
+
+// Score runs score of the module.
+func (pl *WasmPlugin) Score(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	data, err := state.Read(preScoreStateKey)
+	if err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	s := data.(*preScoreState)
+
+	out := &ScoreOutput{}
+	if err := pl.call(ctx, "score", pl.score, &ScoreInput{Pod: pod, Node: s.nodes[nodeName]}, out); err != nil {
+		return 0, framework.AsStatus(err)
+	}
+
+	status, err := toStatus(out.Status, framework.Success)
+	if err != nil {
+		return 0, framework.AsStatus(fmt.Errorf("score of %s: %w", pl.path, err))
+	}
+	if out.Score < framework.MinNodeScore || out.Score > framework.MaxNodeScore {
+		return 0, framework.AsStatus(fmt.Errorf("score of %s: %d is out of the range [%d, %d]", pl.path, out.Score, framework.MinNodeScore, framework.MaxNodeScore))
+	}
+	return out.Score, status
+}
+
+// ScoreExtensions of the Score plugin.
+func (pl *WasmPlugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+func (pl *WasmPlugin) EventsToRegister() []framework.ClusterEvent {
+	return []framework.ClusterEvent{
+		{Resource: framework.Node, ActionType: framework.Add},
+	}
+}
+
+// call writes in to the memory of the module, runs the function fn, and reads the output into out.
+// It returns an error if fn doesn't return until the timeout or ctx is done.
+func (pl *WasmPlugin) call(ctx context.Context, name string, fn int64, in, out interface{}) error {
+	if fn < 0 {
+		return fmt.Errorf("%s doesn't export %s", pl.path, name)
+	}
+	input, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal input of %s: %w", name, err)
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, pl.timeout)
+	defer cancel()
+
+	vm := pl.vm
+	type result struct {
+		output []byte
+		err    error
+	}
+	// buffered so that the goroutine doesn't leak after the timeout.
+	done := make(chan result, 1)
+	go func() {
+		output, err := pl.run(vm, name, fn, input)
+		done <- result{output: output, err: err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		// wagon checks the flag set by Close before each instruction, so the function stops soon.
+		// The VM may be broken in the middle of the function, and the next call uses the new one.
+		_ = vm.Close()
+		newVM, err := newVM(pl.module)
+		if err != nil {
+			return fmt.Errorf("instantiate wasm module %s again after %s didn't return: %w", pl.path, name, err)
+		}
+		pl.vm = newVM
+		return fmt.Errorf("run %s of %s: %w", name, pl.path, ctx.Err())
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	if err := json.Unmarshal(r.output, out); err != nil {
+		return fmt.Errorf("unmarshal output of %s of %s: %w", name, pl.path, err)
+	}
+	return nil
+}
+
+// run writes input to the memory of vm, runs the function fn, and returns the copy of the output.
+func (pl *WasmPlugin) run(vm *exec.VM, name string, fn int64, input []byte) ([]byte, error) {
+	ret, err := vm.ExecCode(pl.alloc, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("run alloc of %s: %w", pl.path, err)
+	}
+	ptr := uint64(ret.(uint32))
+	mem := vm.Memory()
+	if ptr+uint64(len(input)) > uint64(len(mem)) {
+		return nil, fmt.Errorf("alloc of %s returned the address out of the memory: %d", pl.path, ptr)
+	}
+	copy(mem[ptr:], input)
+
+	ret, err = vm.ExecCode(fn, ptr, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("run %s of %s: %w", name, pl.path, err)
+	}
+	outPtr, outLen := ret.(uint64)>>32, ret.(uint64)&0xffffffff
+	// the memory may be grown while running the function.
+	mem = vm.Memory()
+	if outPtr+outLen > uint64(len(mem)) {
+		return nil, fmt.Errorf("%s of %s returned the output out of the memory: address %d, length %d", name, pl.path, outPtr, outLen)
+	}
+	return append([]byte{}, mem[outPtr:outPtr+outLen]...), nil
+}
+
+// newVM instantiates the module.
+func newVM(m *wasm.Module) (*exec.VM, error) {
+	vm, err := exec.NewVM(m)
+	if err != nil {
+		return nil, err
+	}
+	// return the trap as an error instead of panic.
+	vm.RecoverPanic = true
+	return vm, nil
+}
+
+// toStatus converts the status in the output to *framework.Status. The code must be one of allowed.
+func toStatus(s Status, allowed ...framework.Code) (*framework.Status, error) {
+	for _, c := range allowed {
+		if c.String() == s.Code {
+			return framework.NewStatus(c, s.Reasons...), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected status code %q", s.Code)
+}
+
+// exportedFunc returns the index of the function exported as name with the signature, or -1 if it isn't exported.
+func exportedFunc(m *wasm.Module, name string, params []wasm.ValueType, result wasm.ValueType) (int64, error) {
+	e, ok := m.Export.Entries[name]
+	if !ok || e.Kind != wasm.ExternalFunction {
+		return -1, nil
+	}
+	f := m.GetFunction(int(e.Index))
+	if f == nil {
+		return -1, fmt.Errorf("function %s isn't found", name)
+	}
+
+	sig := f.Sig
+	match := len(sig.ParamTypes) == len(params) && len(sig.ReturnTypes) == 1 && sig.ReturnTypes[0] == result
+	for i := 0; match && i < len(params); i++ {
+		match = sig.ParamTypes[i] == params[i]
+	}
+	if !match {
+		return -1, fmt.Errorf("function %s has the signature %s, want (%v) %v", name, sig, params, result)
+	}
+	return int64(e.Index), nil
+}
+
+// New initializes a new plugin and returns it.
+func New(obj runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateArgs(args); err != nil {
+		return nil, fmt.Errorf("validate WasmPluginArgs: %w", err)
+	}
+
+	b, err := ioutil.ReadFile(args.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read wasm file: %w", err)
+	}
+	m, err := wasm.ReadModule(bytes.NewReader(b), func(name string) (*wasm.Module, error) {
+		return nil, fmt.Errorf("module %q can't be imported since wasm plugins are sandboxed", name)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read wasm module %s: %w", args.Path, err)
+	}
+	if m.Export == nil {
+		return nil, fmt.Errorf("wasm module %s exports nothing", args.Path)
+	}
+
+	pl := &WasmPlugin{path: args.Path, module: m, timeout: args.Timeout.Duration}
+	pl.alloc, err = exportedFunc(m, "alloc", []wasm.ValueType{wasm.ValueTypeI32}, wasm.ValueTypeI32)
+	if err != nil {
+		return nil, fmt.Errorf("wasm module %s: %w", args.Path, err)
+	}
+	if pl.alloc < 0 {
+		return nil, fmt.Errorf("wasm module %s doesn't export alloc", args.Path)
+	}
+	pl.filter, err = exportedFunc(m, "filter", []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, wasm.ValueTypeI64)
+	if err != nil {
+		return nil, fmt.Errorf("wasm module %s: %w", args.Path, err)
+	}
+	pl.score, err = exportedFunc(m, "score", []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, wasm.ValueTypeI64)
+	if err != nil {
+		return nil, fmt.Errorf("wasm module %s: %w", args.Path, err)
+	}
+
+	pl.vm, err = newVM(m)
+	if err != nil {
+		return nil, fmt.Errorf("instantiate wasm module %s: %w", args.Path, err)
+	}
+
+	return pl, nil
+}
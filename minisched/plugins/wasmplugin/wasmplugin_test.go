@@ -0,0 +1,351 @@
+package wasmplugin
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle/fake"
+)
+
+// data is put at the address 0 in the memory of the test modules.
+const data = `{"code":"Success"}` +
+	`{"code":"Unschedulable","reasons":["rejected by wasm"]}` +
+	`{"code":"Wait"}` +
+	`{"score":42,"status":{"code":"Success"}}` +
+	`{"score":101,"status":{"code":"Success"}}`
+
+// inputAddr is the address which alloc of the test modules returns.
+const inputAddr = 1024
+
+func uleb(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	b := uleb(uint64(len(items)))
+	for _, i := range items {
+		b = append(b, i...)
+	}
+	return b
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+}
+
+func name(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+// output returns i64.const which points at s in data.
+func output(s string) []byte {
+	off := strings.Index(data, s)
+	if off < 0 {
+		off = len(data)
+	}
+	return append([]byte{0x42}, sleb(int64(off)<<32|int64(len(s)))...)
+}
+
+// returnOutput is the function body which returns s in data.
+func returnOutput(s string) []byte {
+	return append(output(s), 0x0b)
+}
+
+// returnOutputIfInputIsJSON is the function body which returns s in data if the input starts with '{'.
+func returnOutputIfInputIsJSON(s string) []byte {
+	b := append([]byte{0x20, 0x00, 0x2d, 0x00, 0x00, 0x41}, sleb('{')...)
+	b = append(b, 0x46, 0x04, 0x7e)
+	b = append(b, output(s)...)
+	b = append(b, 0x05)
+	b = append(b, output(`{"code":"Success"}`)...)
+	return append(b, 0x0b, 0x0b)
+}
+
+// trap is the function body which traps.
+var trap = []byte{0x00, 0x0b}
+
+// infiniteLoop is the function body which never returns.
+var infiniteLoop = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b}
+
+// newModule writes a module which exports filter and score with the bodies, and returns the path.
+// filter or score isn't exported if the body is nil.
+func newModule(t *testing.T, filter, score []byte) string {
+	t.Helper()
+	i32, i64 := byte(0x7f), byte(0x7e)
+	types := vec(
+		append([]byte{0x60}, append(vec([]byte{i32}), vec([]byte{i32})...)...),
+		append([]byte{0x60}, append(vec([]byte{i32}, []byte{i32}), vec([]byte{i64})...)...),
+	)
+	funcs := [][]byte{{0x00}}
+	exports := [][]byte{append(name("memory"), 0x02, 0x00), append(name("alloc"), 0x00, 0x00)}
+	bodies := [][]byte{append(append([]byte{0x41}, sleb(inputAddr)...), 0x0b)}
+	for _, f := range []struct {
+		name string
+		body []byte
+	}{{"filter", filter}, {"score", score}} {
+		if f.body == nil {
+			continue
+		}
+		exports = append(exports, append(name(f.name), 0x00, byte(len(funcs))))
+		funcs = append(funcs, []byte{0x01})
+		bodies = append(bodies, f.body)
+	}
+	var codes [][]byte
+	for _, b := range bodies {
+		code := append(vec(), b...)
+		codes = append(codes, append(uleb(uint64(len(code))), code...))
+	}
+
+	m := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	m = append(m, section(1, types)...)
+	m = append(m, section(3, vec(funcs...))...)
+	m = append(m, section(5, vec([]byte{0x00, 0x01}))...)
+	m = append(m, section(7, vec(exports...))...)
+	m = append(m, section(10, vec(codes...))...)
+	m = append(m, section(11, vec(append([]byte{0x00, 0x41, 0x00, 0x0b}, name(data)...)))...)
+
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	require.NoError(t, ioutil.WriteFile(path, m, 0o600))
+	return path
+}
+
+func newPlugin(t *testing.T, path string) *WasmPlugin {
+	t.Helper()
+	return newPluginWithArgs(t, `{"path":"`+path+`"}`)
+}
+
+func newPluginWithArgs(t *testing.T, args string) *WasmPlugin {
+	t.Helper()
+	pl, err := New(&runtime.Unknown{Raw: []byte(args)}, fake.NewHandle(nil))
+	require.NoError(t, err)
+	return pl.(*WasmPlugin)
+}
+
+func TestWasmPlugin_Filter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		filter      []byte
+		wantCode    framework.Code
+		wantMessage string
+	}{
+		{
+			name:        "the module rejects the node by the input",
+			filter:      returnOutputIfInputIsJSON(`{"code":"Unschedulable","reasons":["rejected by wasm"]}`),
+			wantCode:    framework.Unschedulable,
+			wantMessage: "rejected by wasm",
+		},
+		{
+			name:     "the module accepts the node",
+			filter:   returnOutput(`{"code":"Success"}`),
+			wantCode: framework.Success,
+		},
+		{
+			name:        "the module returns the unexpected status code",
+			filter:      returnOutput(`{"code":"Wait"}`),
+			wantCode:    framework.Error,
+			wantMessage: `unexpected status code "Wait"`,
+		},
+		{
+			name:        "the module traps",
+			filter:      trap,
+			wantCode:    framework.Error,
+			wantMessage: "run filter of",
+		},
+		{
+			name:        "the module returns the output out of the memory",
+			filter:      append(append([]byte{0x42}, sleb(1<<48|1)...), 0x0b),
+			wantCode:    framework.Error,
+			wantMessage: "out of the memory",
+		},
+		{
+			name:        "the module doesn't export filter",
+			wantCode:    framework.Error,
+			wantMessage: "doesn't export filter",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, newModule(t, tt.filter, returnOutput(`{"score":42,"status":{"code":"Success"}}`)))
+			nodeInfo := framework.NewNodeInfo(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2"}})
+			nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+
+			status := pl.Filter(context.Background(), framework.NewCycleState(), &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}}, nodeInfo)
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Contains(t, status.Message(), tt.wantMessage)
+		})
+	}
+}
+
+func TestWasmPlugin_Score(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		score     []byte
+		wantScore int64
+		wantCode  framework.Code
+	}{
+		{
+			name:      "the module scores the node",
+			score:     returnOutput(`{"score":42,"status":{"code":"Success"}}`),
+			wantScore: 42,
+			wantCode:  framework.Success,
+		},
+		{
+			name:     "the module returns the score out of the range",
+			score:    returnOutput(`{"score":101,"status":{"code":"Success"}}`),
+			wantCode: framework.Error,
+		},
+		{
+			name:     "the module traps",
+			score:    trap,
+			wantCode: framework.Error,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pl := newPlugin(t, newModule(t, nil, tt.score))
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}}
+			state := framework.NewCycleState()
+			require.True(t, pl.PreScore(context.Background(), state, pod, []*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}}).IsSuccess())
+
+			score, status := pl.Score(context.Background(), state, pod, "node1")
+
+			assert.Equal(t, tt.wantCode, status.Code())
+			assert.Equal(t, tt.wantScore, score)
+		})
+	}
+}
+
+func TestWasmPlugin_timeout(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		// newContext returns the context passed to Filter.
+		newContext  func() (context.Context, context.CancelFunc)
+		wantMessage string
+	}{
+		{
+			name: "stop the function at the timeout of the args",
+			newContext: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			wantMessage: "context deadline exceeded",
+		},
+		{
+			name: "stop the function when the context is done",
+			newContext: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantMessage: "context canceled",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := newModule(t, infiniteLoop, returnOutput(`{"score":42,"status":{"code":"Success"}}`))
+			pl := newPluginWithArgs(t, `{"path":"`+path+`","timeout":"200ms"}`)
+			nodeInfo := framework.NewNodeInfo()
+			nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}})
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1"}}
+
+			ctx, cancel := tt.newContext()
+			defer cancel()
+			status := pl.Filter(ctx, framework.NewCycleState(), pod, nodeInfo)
+			assert.Equal(t, framework.Error, status.Code())
+			assert.Contains(t, status.Message(), tt.wantMessage)
+
+			// the module is instantiated again, and the next call runs.
+			state := framework.NewCycleState()
+			require.True(t, pl.PreScore(context.Background(), state, pod, []*v1.Node{nodeInfo.Node()}).IsSuccess())
+			score, status := pl.Score(context.Background(), state, pod, "node1")
+			assert.True(t, status.IsSuccess())
+			assert.Equal(t, int64(42), score)
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+	notWasm := filepath.Join(t.TempDir(), "plugin.wasm")
+	require.NoError(t, ioutil.WriteFile(notWasm, []byte("not wasm"), 0o600))
+	tests := []struct {
+		name    string
+		obj     runtime.Object
+		wantErr string
+	}{
+		{
+			name:    "fail without args",
+			obj:     nil,
+			wantErr: "validate WasmPluginArgs: path: Required value",
+		},
+		{
+			name:    "fail with unknown fields",
+			obj:     &runtime.Unknown{Raw: []byte(`{"file": "/tmp/plugin.wasm"}`)},
+			wantErr: `decode args: json: unknown field "file"`,
+		},
+		{
+			name:    "fail with the non-positive timeout",
+			obj:     &runtime.Unknown{Raw: []byte(`{"path": "/tmp/plugin.wasm", "timeout": "0s"}`)},
+			wantErr: "validate WasmPluginArgs: timeout: Invalid value: \"0s\": must be positive",
+		},
+		{
+			name:    "fail when the file isn't found",
+			obj:     &runtime.Unknown{Raw: []byte(`{"path": "` + filepath.Join(t.TempDir(), "not-found.wasm") + `"}`)},
+			wantErr: "read wasm file",
+		},
+		{
+			name:    "fail when the file isn't a wasm module",
+			obj:     &runtime.Unknown{Raw: []byte(`{"path": "` + notWasm + `"}`)},
+			wantErr: "read wasm module",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := New(tt.obj, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -1,35 +1,60 @@
 package queue
 
 import (
+	"context"
 	"sync"
 	"time"
 
 	"k8s.io/klog/v2"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	v1 "k8s.io/api/core/v1"
 )
 
+// unschedulableTimeout is the event which moves the pods staying in unschedulableQ too long.
+var unschedulableTimeout = framework.ClusterEvent{Resource: framework.WildCard, ActionType: framework.All, Label: "UnschedulableTimeout"}
+
 type SchedulingQueue struct {
 	lock sync.RWMutex
+	// cond is signaled when pods are added to activeQ or the queue is closed.
+	cond *sync.Cond
+	// closed is true after the queue is closed. NextPod doesn't return any pods after that.
+	closed bool
 
 	activeQ        []*framework.QueuedPodInfo
 	podBackoffQ    []*framework.QueuedPodInfo
 	unschedulableQ map[string]*framework.QueuedPodInfo
 
 	clusterEventMap map[framework.ClusterEvent]sets.String
+
+	// schedulingCycle is incremented every time a pod is popped.
+	schedulingCycle int64
+	// moveRequestCycle is the scheduling cycle when the last move request came.
+	// The pod which fails in the cycle or earlier is added to backoffQ instead of unschedulableQ,
+	// since the event may make it schedulable while it's being scheduled.
+	moveRequestCycle int64
+
+	// less decides the order of pods popped from activeQ.
+	// nil means pods are popped in the order they are added to activeQ.
+	less framework.LessFunc
 }
 
-func New(clusterEventMap map[framework.ClusterEvent]sets.String) *SchedulingQueue {
-	return &SchedulingQueue{
-		activeQ:         []*framework.QueuedPodInfo{},
-		podBackoffQ:     []*framework.QueuedPodInfo{},
-		unschedulableQ:  map[string]*framework.QueuedPodInfo{},
-		clusterEventMap: clusterEventMap,
+// New creates a SchedulingQueue. less is the Less of the queue sort plugin, and it can be nil.
+func New(clusterEventMap map[framework.ClusterEvent]sets.String, less framework.LessFunc) *SchedulingQueue {
+	s := &SchedulingQueue{
+		activeQ:          []*framework.QueuedPodInfo{},
+		podBackoffQ:      []*framework.QueuedPodInfo{},
+		unschedulableQ:   map[string]*framework.QueuedPodInfo{},
+		clusterEventMap:  clusterEventMap,
+		moveRequestCycle: -1,
+		less:             less,
 	}
+	s.cond = sync.NewCond(&s.lock)
+	return s
 }
 
 func (s *SchedulingQueue) Add(pod *v1.Pod) error {
@@ -37,8 +62,13 @@ func (s *SchedulingQueue) Add(pod *v1.Pod) error {
 	defer s.lock.Unlock()
 
 	podInfo := s.newQueuedPodInfo(pod)
+	if s.has(podInfo) {
+		// The pod may be handed over from the previous scheduler.
+		return nil
+	}
 
 	s.activeQ = append(s.activeQ, podInfo)
+	s.cond.Broadcast()
 	return nil
 }
 
@@ -59,6 +89,7 @@ func (s *SchedulingQueue) MoveAllToActiveOrBackoffQueue(event framework.ClusterE
 		unschedulablePods = append(unschedulablePods, pInfo)
 	}
 	s.movePodsToActiveOrBackoffQueue(unschedulablePods, event)
+	s.moveRequestCycle = s.schedulingCycle
 }
 
 // NOTE: this function assumes lock has been acquired in caller
@@ -79,26 +110,141 @@ func (s *SchedulingQueue) movePodsToActiveOrBackoffQueue(podInfoList []*framewor
 		}
 		delete(s.unschedulableQ, keyFunc(pInfo))
 	}
+	s.cond.Broadcast()
 }
 
+// NextPod pops the pod at the head of activeQ. The head is the first pod in the order of less if the queue has it.
+// It blocks until a pod is added to activeQ, and returns nil once the queue is closed.
 func (s *SchedulingQueue) NextPod() *v1.Pod {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	// wait
-	for len(s.activeQ) == 0 {
+	for len(s.activeQ) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return nil
 	}
 
-	p := s.activeQ[0]
-	s.activeQ = s.activeQ[1:]
+	head := 0
+	if s.less != nil {
+		for i := range s.activeQ {
+			if s.less(s.activeQ[i], s.activeQ[head]) {
+				head = i
+			}
+		}
+	}
+	p := s.activeQ[head]
+	s.activeQ = append(s.activeQ[:head], s.activeQ[head+1:]...)
+	s.schedulingCycle++
 	return p.Pod
 }
 
+// SchedulingCycle returns the current scheduling cycle, which is the number of pods popped so far.
+func (s *SchedulingQueue) SchedulingCycle() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.schedulingCycle
+}
+
+// Close closes the queue and unblocks NextPod.
+func (s *SchedulingQueue) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// HandOverTo moves all pods in the queue to the same kind of queue in next, and closes the queue.
+// The pods which next already has are left. It returns the moved pods.
+func (s *SchedulingQueue) HandOverTo(next *SchedulingQueue) []*v1.Pod {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	next.lock.Lock()
+	defer next.lock.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+
+	var moved []*v1.Pod
+	for _, pInfo := range s.activeQ {
+		if next.has(pInfo) {
+			continue
+		}
+		next.activeQ = append(next.activeQ, pInfo)
+		moved = append(moved, pInfo.Pod)
+	}
+	for _, pInfo := range s.podBackoffQ {
+		if next.has(pInfo) {
+			continue
+		}
+		next.podBackoffQ = append(next.podBackoffQ, pInfo)
+		moved = append(moved, pInfo.Pod)
+	}
+	for key, pInfo := range s.unschedulableQ {
+		if next.has(pInfo) {
+			continue
+		}
+		next.unschedulableQ[key] = pInfo
+		moved = append(moved, pInfo.Pod)
+	}
+
+	s.activeQ = []*framework.QueuedPodInfo{}
+	s.podBackoffQ = []*framework.QueuedPodInfo{}
+	s.unschedulableQ = map[string]*framework.QueuedPodInfo{}
+	next.cond.Broadcast()
+
+	return moved
+}
+
+// PendingPods has pods in each queue of SchedulingQueue.
+type PendingPods struct {
+	ActiveQ        []*v1.Pod `json:"activeQ"`
+	BackoffQ       []*v1.Pod `json:"backoffQ"`
+	UnschedulableQ []*v1.Pod `json:"unschedulableQ"`
+}
+
+// PendingPods returns all pending pods in the queue.
+func (s *SchedulingQueue) PendingPods() *PendingPods {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	ret := &PendingPods{
+		ActiveQ:        make([]*v1.Pod, 0, len(s.activeQ)),
+		BackoffQ:       make([]*v1.Pod, 0, len(s.podBackoffQ)),
+		UnschedulableQ: make([]*v1.Pod, 0, len(s.unschedulableQ)),
+	}
+	for _, pInfo := range s.activeQ {
+		ret.ActiveQ = append(ret.ActiveQ, pInfo.Pod)
+	}
+	for _, pInfo := range s.podBackoffQ {
+		ret.BackoffQ = append(ret.BackoffQ, pInfo.Pod)
+	}
+	for _, pInfo := range s.unschedulableQ {
+		ret.UnschedulableQ = append(ret.UnschedulableQ, pInfo.Pod)
+	}
+	return ret
+}
+
 // this function is the similar to AddUnschedulableIfNotPresent on original kube-scheduler.
-func (s *SchedulingQueue) AddUnschedulable(pInfo *framework.QueuedPodInfo) error {
+// podSchedulingCycle is the scheduling cycle when the pod was popped.
+// The pod is added to backoffQ if a move request came after that.
+func (s *SchedulingQueue) AddUnschedulable(pInfo *framework.QueuedPodInfo, podSchedulingCycle int64) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	// Refresh the timestamp since the pod is re-added.
 	pInfo.Timestamp = time.Now()
 
+	if s.moveRequestCycle >= podSchedulingCycle {
+		s.podBackoffQ = append(s.podBackoffQ, pInfo)
+		klog.Info("queue: pod added to backoffQ since a move request came while scheduling it: " + pInfo.Pod.Name)
+		return nil
+	}
+
 	// add or update
 	s.unschedulableQ[keyFunc(pInfo)] = pInfo
 
@@ -109,13 +255,11 @@ func (s *SchedulingQueue) AddUnschedulable(pInfo *framework.QueuedPodInfo) error
 func (s *SchedulingQueue) Update(oldPod, newPod *v1.Pod) error {
 	// TODO: implement
 	panic("not implemented")
-	return nil
 }
 
 func (s *SchedulingQueue) Delete(pod *v1.Pod) error {
 	// TODO: implement
 	panic("not implemented")
-	return nil
 }
 
 // AssignedPodAdded is called when a bound pod is added. Creation of this pod
@@ -132,17 +276,50 @@ func (s *SchedulingQueue) AssignedPodUpdated(pod *v1.Pod) {
 	panic("not implemented")
 }
 
+// Run starts the goroutines to pump from podBackoffQ and unschedulableQ to activeQ.
+func (s *SchedulingQueue) Run(ctx context.Context) {
+	go wait.UntilWithContext(ctx, func(_ context.Context) { s.flushBackoffQCompleted() }, 1.0*time.Second)
+	go wait.UntilWithContext(ctx, func(_ context.Context) { s.flushUnschedulableQLeftover() }, 30*time.Second)
+}
+
 // flushBackoffQCompleted Moves all pods from backoffQ which have completed backoff in to activeQ
 func (s *SchedulingQueue) flushBackoffQCompleted() {
-	// TODO: implement
-	panic("note implemented")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	backingoff := make([]*framework.QueuedPodInfo, 0, len(s.podBackoffQ))
+	for _, pInfo := range s.podBackoffQ {
+		if isPodBackingoff(pInfo) {
+			backingoff = append(backingoff, pInfo)
+			continue
+		}
+		s.activeQ = append(s.activeQ, pInfo)
+	}
+	if len(backingoff) == len(s.podBackoffQ) {
+		return
+	}
+	s.podBackoffQ = backingoff
+	s.cond.Broadcast()
 }
 
 // flushUnschedulableQLeftover moves pods which stay in unschedulableQ longer than unschedulableQTimeInterval
 // to backoffQ or activeQ.
+// It retries the pods which no event moves, like the pods which fail by errors.
 func (s *SchedulingQueue) flushUnschedulableQLeftover() {
-	// TODO: implement
-	panic("note implemented")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var podsToMove []*framework.QueuedPodInfo
+	now := time.Now()
+	for _, pInfo := range s.unschedulableQ {
+		if now.Sub(pInfo.Timestamp) > unschedulableQTimeInterval {
+			podsToMove = append(podsToMove, pInfo)
+		}
+	}
+
+	if len(podsToMove) > 0 {
+		s.movePodsToActiveOrBackoffQueue(podsToMove, unschedulableTimeout)
+	}
 }
 
 // =====
@@ -153,6 +330,24 @@ func keyFunc(pInfo *framework.QueuedPodInfo) string {
 	return pInfo.Pod.Name + "_" + pInfo.Pod.Namespace
 }
 
+// has returns true if the pod is in any queue.
+//
+// NOTE: this function assumes lock has been acquired in caller.
+func (s *SchedulingQueue) has(pInfo *framework.QueuedPodInfo) bool {
+	key := keyFunc(pInfo)
+	if _, ok := s.unschedulableQ[key]; ok {
+		return true
+	}
+	for _, q := range [][]*framework.QueuedPodInfo{s.activeQ, s.podBackoffQ} {
+		for _, p := range q {
+			if keyFunc(p) == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *SchedulingQueue) newQueuedPodInfo(pod *v1.Pod, unschedulableplugins ...string) *framework.QueuedPodInfo {
 	now := time.Now()
 	return &framework.QueuedPodInfo{
@@ -216,6 +411,9 @@ func getBackoffTime(podInfo *framework.QueuedPodInfo) time.Time {
 }
 
 const (
+	// unschedulableQTimeInterval is how long pods can stay in unschedulableQ without any events.
+	unschedulableQTimeInterval = 60 * time.Second
+
 	podInitialBackoffDuration = 1 * time.Second
 	podMaxBackoffDuration     = 10 * time.Second
 )
@@ -0,0 +1,205 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func newPod(name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func TestSchedulingQueue_HandOverTo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		prepareFn   func(current, next *SchedulingQueue)
+		wantMoved   []string
+		wantPending *PendingPods
+	}{
+		{
+			name: "move pods to the same kind of queue",
+			prepareFn: func(current, next *SchedulingQueue) {
+				_ = current.Add(newPod("pod1"))
+				current.podBackoffQ = append(current.podBackoffQ, current.newQueuedPodInfo(newPod("pod2")))
+				_ = current.AddUnschedulable(current.newQueuedPodInfo(newPod("pod3"), "plugin1"), 0)
+			},
+			wantMoved: []string{"pod1", "pod2", "pod3"},
+			wantPending: &PendingPods{
+				ActiveQ:        []*v1.Pod{newPod("pod1")},
+				BackoffQ:       []*v1.Pod{newPod("pod2")},
+				UnschedulableQ: []*v1.Pod{newPod("pod3")},
+			},
+		},
+		{
+			name: "leave pods which the next queue already has",
+			prepareFn: func(current, next *SchedulingQueue) {
+				_ = current.AddUnschedulable(current.newQueuedPodInfo(newPod("pod1"), "plugin1"), 0)
+				_ = current.Add(newPod("pod2"))
+				_ = next.Add(newPod("pod1"))
+			},
+			wantMoved: []string{"pod2"},
+			wantPending: &PendingPods{
+				ActiveQ:        []*v1.Pod{newPod("pod1"), newPod("pod2")},
+				BackoffQ:       []*v1.Pod{},
+				UnschedulableQ: []*v1.Pod{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			current := New(map[framework.ClusterEvent]sets.String{}, nil)
+			next := New(map[framework.ClusterEvent]sets.String{}, nil)
+			tt.prepareFn(current, next)
+
+			moved := current.HandOverTo(next)
+
+			movedNames := make([]string, 0, len(moved))
+			for _, p := range moved {
+				movedNames = append(movedNames, p.Name)
+			}
+			assert.ElementsMatch(t, tt.wantMoved, movedNames)
+			assert.Equal(t, tt.wantPending, next.PendingPods())
+			assert.Equal(t, &PendingPods{
+				ActiveQ:        []*v1.Pod{},
+				BackoffQ:       []*v1.Pod{},
+				UnschedulableQ: []*v1.Pod{},
+			}, current.PendingPods())
+			assert.Nil(t, current.NextPod())
+		})
+	}
+}
+
+func TestSchedulingQueue_Close(t *testing.T) {
+	t.Parallel()
+	q := New(map[framework.ClusterEvent]sets.String{}, nil)
+
+	got := make(chan *v1.Pod)
+	go func() {
+		got <- q.NextPod()
+	}()
+
+	q.Close()
+	select {
+	case p := <-got:
+		assert.Nil(t, p)
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatal("NextPod is still blocked after the queue is closed")
+	}
+}
+
+func TestSchedulingQueue_NextPod(t *testing.T) {
+	t.Parallel()
+	byName := func(p1, p2 *framework.QueuedPodInfo) bool {
+		return p1.Pod.Name < p2.Pod.Name
+	}
+	tests := []struct {
+		name string
+		less framework.LessFunc
+		want []string
+	}{
+		{
+			name: "pop pods in the order they are added without less",
+			want: []string{"pod2", "pod3", "pod1"},
+		},
+		{
+			name: "pop pods in the order of less",
+			less: byName,
+			want: []string{"pod1", "pod2", "pod3"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			q := New(map[framework.ClusterEvent]sets.String{}, tt.less)
+			for _, name := range []string{"pod2", "pod3", "pod1"} {
+				_ = q.Add(newPod(name))
+			}
+
+			var got []string
+			for range tt.want {
+				got = append(got, q.NextPod().Name)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSchedulingQueue_AddUnschedulable(t *testing.T) {
+	t.Parallel()
+	event := framework.ClusterEvent{Resource: framework.PersistentVolumeClaim, ActionType: framework.Add, Label: "PvcAdd"}
+	tests := []struct {
+		name string
+		// moveRequest makes a move request while the pod is being scheduled.
+		moveRequest bool
+		wantPending *PendingPods
+	}{
+		{
+			name: "add the pod to unschedulableQ",
+			wantPending: &PendingPods{
+				ActiveQ:        []*v1.Pod{},
+				BackoffQ:       []*v1.Pod{},
+				UnschedulableQ: []*v1.Pod{newPod("pod1")},
+			},
+		},
+		{
+			name:        "add the pod to backoffQ if a move request came while scheduling it",
+			moveRequest: true,
+			wantPending: &PendingPods{
+				ActiveQ:        []*v1.Pod{},
+				BackoffQ:       []*v1.Pod{newPod("pod1")},
+				UnschedulableQ: []*v1.Pod{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			q := New(map[framework.ClusterEvent]sets.String{event: sets.NewString("plugin1")}, nil)
+			_ = q.Add(newPod("pod1"))
+			pod := q.NextPod()
+			cycle := q.SchedulingCycle()
+
+			if tt.moveRequest {
+				q.MoveAllToActiveOrBackoffQueue(event)
+			}
+			require.NoError(t, q.AddUnschedulable(q.newQueuedPodInfo(pod, "plugin1"), cycle))
+
+			assert.Equal(t, tt.wantPending, q.PendingPods())
+		})
+	}
+}
+
+func TestSchedulingQueue_flushUnschedulableQLeftover(t *testing.T) {
+	t.Parallel()
+	q := New(map[framework.ClusterEvent]sets.String{}, nil)
+	for name, timestamp := range map[string]time.Time{
+		"pod1": time.Now().Add(-2 * unschedulableQTimeInterval),
+		"pod2": time.Now(),
+	} {
+		pInfo := q.newQueuedPodInfo(newPod(name), "plugin1")
+		pInfo.Timestamp = timestamp
+		q.unschedulableQ[keyFunc(pInfo)] = pInfo
+	}
+
+	q.flushUnschedulableQLeftover()
+
+	assert.Equal(t, &PendingPods{
+		ActiveQ:        []*v1.Pod{newPod("pod1")},
+		BackoffQ:       []*v1.Pod{},
+		UnschedulableQ: []*v1.Pod{newPod("pod2")},
+	}, q.PendingPods())
+}
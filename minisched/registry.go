@@ -0,0 +1,185 @@
+package minisched
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/nodeunschedulable"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/nodevolumelimits"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumebinding"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumerestrictions"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/volumezone"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/handle"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/bind/simplebinder"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/celpolicy"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/filter/requirednodelabel"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/grpcplugin"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/permit/coscheduling"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/permit/scheduleafter"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/postbind/bindcounter"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/postfilter/unschedulableannotator"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/prebind/nodeannotator"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/queuesort/podpriority"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/reserve/inflightpods"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/score/nodenumber"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/score/preferrednodelabel"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/plugins/wasmplugin"
+)
+
+// PluginFactory is a function that builds a plugin.
+type PluginFactory = func(configuration runtime.Object, h handle.Handle) (framework.Plugin, error)
+
+// Registry is a collection of all available plugins.
+type Registry map[string]PluginFactory
+
+// NewRegistry returns the registry of all plugins minisched supports.
+// The sample plugins aren't enabled by default. They are enabled in the profile of the scheduler configuration.
+func NewRegistry() Registry {
+	return Registry{
+		nodeunschedulable.Name: func(configuration runtime.Object, _ handle.Handle) (framework.Plugin, error) {
+			return nodeunschedulable.New(configuration, nil)
+		},
+		nodenumber.Name:   nodenumber.New,
+		coscheduling.Name: coscheduling.New,
+		grpcplugin.Name:   grpcplugin.New,
+		wasmplugin.Name:   wasmplugin.New,
+		celpolicy.Name:    celpolicy.New,
+
+		// in-tree volume plugins
+		volumebinding.Name:       inTreePlugin(newVolumeBinding),
+		volumerestrictions.Name:  inTreePlugin(newVolumeRestrictions),
+		volumezone.Name:          inTreePlugin(volumezone.New),
+		nodevolumelimits.CSIName: inTreePlugin(nodevolumelimits.NewCSI),
+
+		// sample plugins
+		podpriority.Name:            podpriority.New,
+		requirednodelabel.Name:      requirednodelabel.New,
+		unschedulableannotator.Name: unschedulableannotator.New,
+		preferrednodelabel.Name:     preferrednodelabel.New,
+		inflightpods.Name:           inflightpods.New,
+		scheduleafter.Name:          scheduleafter.New,
+		nodeannotator.Name:          nodeannotator.New,
+		simplebinder.Name:           simplebinder.New,
+		bindcounter.Name:            bindcounter.New,
+	}
+}
+
+// defaultPlugins returns the plugins enabled when the profile doesn't configure them.
+func defaultPlugins() *v1beta2config.Plugins {
+	return &v1beta2config.Plugins{
+		Filter: v1beta2config.PluginSet{
+			Enabled: []v1beta2config.Plugin{
+				{Name: nodeunschedulable.Name},
+			},
+		},
+		PreScore: v1beta2config.PluginSet{
+			Enabled: []v1beta2config.Plugin{
+				{Name: nodenumber.Name},
+			},
+		},
+		Score: v1beta2config.PluginSet{
+			Enabled: []v1beta2config.Plugin{
+				{Name: nodenumber.Name},
+			},
+		},
+		Permit: v1beta2config.PluginSet{
+			Enabled: []v1beta2config.Plugin{
+				{Name: nodenumber.Name},
+			},
+		},
+	}
+}
+
+// mergePlugins merges the plugins configured in the profile into the default plugins.
+// It works in the same way as the original kube-scheduler's one.
+func mergePlugins(defaultPlugins, customPlugins *v1beta2config.Plugins) *v1beta2config.Plugins {
+	if customPlugins == nil {
+		return defaultPlugins
+	}
+
+	defaultPlugins.QueueSort = mergePluginSet(defaultPlugins.QueueSort, customPlugins.QueueSort)
+	defaultPlugins.PreFilter = mergePluginSet(defaultPlugins.PreFilter, customPlugins.PreFilter)
+	defaultPlugins.Filter = mergePluginSet(defaultPlugins.Filter, customPlugins.Filter)
+	defaultPlugins.PostFilter = mergePluginSet(defaultPlugins.PostFilter, customPlugins.PostFilter)
+	defaultPlugins.PreScore = mergePluginSet(defaultPlugins.PreScore, customPlugins.PreScore)
+	defaultPlugins.Score = mergePluginSet(defaultPlugins.Score, customPlugins.Score)
+	defaultPlugins.Reserve = mergePluginSet(defaultPlugins.Reserve, customPlugins.Reserve)
+	defaultPlugins.Permit = mergePluginSet(defaultPlugins.Permit, customPlugins.Permit)
+	defaultPlugins.PreBind = mergePluginSet(defaultPlugins.PreBind, customPlugins.PreBind)
+	defaultPlugins.Bind = mergePluginSet(defaultPlugins.Bind, customPlugins.Bind)
+	defaultPlugins.PostBind = mergePluginSet(defaultPlugins.PostBind, customPlugins.PostBind)
+	return defaultPlugins
+}
+
+// mergePluginSet disables the default plugins disabled in customPluginSet,
+// and then enables the plugins in customPluginSet.
+// The default plugin enabled in customPluginSet again is re-configured in place to preserve its order.
+func mergePluginSet(defaultPluginSet, customPluginSet v1beta2config.PluginSet) v1beta2config.PluginSet {
+	disabledPlugins := sets.NewString()
+	for _, p := range customPluginSet.Disabled {
+		disabledPlugins.Insert(p.Name)
+	}
+	enabledCustomPlugins := make(map[string]v1beta2config.Plugin, len(customPluginSet.Enabled))
+	for _, p := range customPluginSet.Enabled {
+		enabledCustomPlugins[p.Name] = p
+	}
+
+	var enabledPlugins []v1beta2config.Plugin
+	replacedPlugins := sets.NewString()
+	if !disabledPlugins.Has("*") {
+		for _, p := range defaultPluginSet.Enabled {
+			if disabledPlugins.Has(p.Name) {
+				continue
+			}
+			if customPlugin, ok := enabledCustomPlugins[p.Name]; ok {
+				p = customPlugin
+				replacedPlugins.Insert(p.Name)
+			}
+			enabledPlugins = append(enabledPlugins, p)
+		}
+	}
+
+	for _, p := range customPluginSet.Enabled {
+		if !replacedPlugins.Has(p.Name) {
+			enabledPlugins = append(enabledPlugins, p)
+		}
+	}
+	return v1beta2config.PluginSet{Enabled: enabledPlugins}
+}
+
+// filterUnsupportedPlugins removes the plugins which isn't in the registry from the plugin set.
+// The configuration of the simulator enables all in-tree plugins by default, but minisched supports only some of them.
+func filterUnsupportedPlugins(r Registry, set v1beta2config.PluginSet) v1beta2config.PluginSet {
+	ret := v1beta2config.PluginSet{}
+	for _, p := range set.Enabled {
+		if _, ok := r[p.Name]; !ok {
+			klog.V(2).InfoS("minischeduler: ignore the plugin which minisched doesn't support", "plugin", p.Name)
+			continue
+		}
+		ret.Enabled = append(ret.Enabled, p)
+	}
+	return ret
+}
+
+// pluginArgs returns the args of the plugin in the profile.
+// The args are decoded by each plugin since minisched doesn't have the typed args of its own plugins in the scheme.
+func pluginArgs(profile *v1beta2config.KubeSchedulerProfile, name string) runtime.Object {
+	if profile == nil {
+		return nil
+	}
+	for _, pc := range profile.PluginConfig {
+		if pc.Name != name {
+			continue
+		}
+		if pc.Args.Object != nil {
+			return pc.Args.Object
+		}
+		if len(pc.Args.Raw) != 0 {
+			return &runtime.Unknown{Raw: pc.Args.Raw, ContentType: runtime.ContentTypeJSON}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package minisched
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// bindingCycles tracks the binding cycles running in goroutines.
+type bindingCycles struct {
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	pods map[types.UID]*v1.Pod
+}
+
+func newBindingCycles() *bindingCycles {
+	return &bindingCycles{pods: map[types.UID]*v1.Pod{}}
+}
+
+func (b *bindingCycles) start(pod *v1.Pod) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.wg.Add(1)
+	b.pods[pod.UID] = pod
+}
+
+func (b *bindingCycles) finish(pod *v1.Pod) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pods, pod.UID)
+	b.wg.Done()
+}
+
+// wait blocks until all binding cycles finish or ctx is done.
+// It returns "<namespace>/<name>" of the pods whose binding cycle didn't finish.
+func (b *bindingCycles) wait(ctx context.Context) []string {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ret := make([]string, 0, len(b.pods))
+	for _, p := range b.pods {
+		ret = append(ret, p.Namespace+"/"+p.Name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// shutdownPluginName is the plugin name recorded on the waiting pods rejected by Shutdown.
+const shutdownPluginName = "Shutdown"
+
+// Shutdown stops the scheduler gracefully.
+// It stops the scheduling loop, rejects all waiting pods, and waits for the binding cycles in flight until ctx is done.
+// The binding cycles which don't finish by then are cancelled. Then, the plugins which implement io.Closer are closed.
+// The context passed to Run must be cancelled before calling it, which also stops the informers.
+// The returned error summarizes what didn't finish in time.
+func (sched *Scheduler) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	if err := sched.stopScheduling(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	sched.rejectWaitingPods(shutdownPluginName, "rejected because the scheduler is shut down")
+
+	if pods := sched.bindingCycles.wait(ctx); len(pods) != 0 {
+		errs = append(errs, fmt.Errorf("binding cycles of %d pods didn't finish and are cancelled: %v: %w", len(pods), pods, ctx.Err()))
+	}
+	sched.cancelBinding()
+
+	if err := sched.closePlugins(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// closePlugins closes the plugins which implement io.Closer.
+func (sched *Scheduler) closePlugins() error {
+	names := make([]string, 0, len(sched.closers))
+	for name := range sched.closers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := sched.closers[name].Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s plugin: %w", name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// stopScheduling closes the scheduling queue, and waits for the scheduling cycle in progress until ctx is done.
+func (sched *Scheduler) stopScheduling(ctx context.Context) error {
+	// unblock NextPod.
+	sched.SchedulingQueue.Close()
+
+	select {
+	case <-sched.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduling loop didn't stop: %w", ctx.Err())
+	}
+}
+
+// rejectWaitingPods rejects all waiting pods, and returns them.
+func (sched *Scheduler) rejectWaitingPods(pluginName, msg string) []*v1.Pod {
+	// take a snapshot first so that rejecting pods doesn't block their binding goroutines removing them.
+	var wps []*waitingpod.WaitingPod
+	sched.IterateOverWaitingPods(func(wp *waitingpod.WaitingPod) {
+		wps = append(wps, wp)
+	})
+
+	ret := make([]*v1.Pod, 0, len(wps))
+	for _, wp := range wps {
+		klog.InfoS("Reject the waiting pod", "pod", klog.KObj(wp.GetPod()), "reason", msg)
+		wp.Reject(pluginName, msg)
+		ret = append(ret, wp.GetPod())
+	}
+	return ret
+}
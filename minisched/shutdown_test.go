@@ -0,0 +1,269 @@
+package minisched
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestScheduler_Shutdown(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		nodeName string
+		// shutdownOnBinding shuts down the scheduler after the binding API call starts.
+		shutdownOnBinding bool
+		// blockBinding blocks the binding API call until the test finishes.
+		blockBinding bool
+		timeout      time.Duration
+		wantBound    bool
+		wantErr      bool
+	}{
+		{
+			name: "reject the waiting pod",
+			// NodeNumber plugin makes the pod wait for 9 seconds.
+			nodeName: "node9",
+			timeout:  wait.ForeverTestTimeout,
+		},
+		{
+			name: "wait for the binding cycle in flight",
+			// NodeNumber plugin makes the pod wait for 1 second.
+			nodeName:          "node1",
+			shutdownOnBinding: true,
+			timeout:           wait.ForeverTestTimeout,
+			wantBound:         true,
+		},
+		{
+			name:              "cancel the binding cycle which doesn't finish by the deadline",
+			nodeName:          "node1",
+			shutdownOnBinding: true,
+			blockBinding:      true,
+			timeout:           100 * time.Millisecond,
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"}}
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: tt.nodeName}}
+			client := fake.NewSimpleClientset(pod, node)
+
+			bindingStarted := make(chan struct{})
+			unblock := make(chan struct{})
+			defer close(unblock)
+			bound := false
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "binding" {
+					return false, nil, nil
+				}
+				close(bindingStarted)
+				if tt.blockBinding {
+					<-unblock
+				} else {
+					time.Sleep(100 * time.Millisecond)
+				}
+				bound = true
+				return true, nil, nil
+			})
+
+			sched, err := New(client, informers.NewSharedInformerFactory(client, 0), nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, sched.SchedulingQueue.Add(pod))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go sched.Run(ctx)
+
+			// wait for the pod to reach the binding cycle.
+			err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+				sched.bindingCycles.mu.Lock()
+				defer sched.bindingCycles.mu.Unlock()
+				return len(sched.bindingCycles.pods) != 0, nil
+			})
+			require.NoError(t, err)
+			if tt.shutdownOnBinding {
+				<-bindingStarted
+			}
+
+			cancel()
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer shutdownCancel()
+			err = sched.Shutdown(shutdownCtx)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "default/pod1")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBound, bound)
+		})
+	}
+}
+
+// blockingPreBind is the pre bind plugin which blocks until unblock is closed or ctx is done.
+type blockingPreBind struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (pl *blockingPreBind) Name() string { return "BlockingPreBind" }
+
+func (pl *blockingPreBind) PreBind(ctx context.Context, _ *framework.CycleState, _ *v1.Pod, _ string) *framework.Status {
+	close(pl.started)
+	select {
+	case <-pl.unblock:
+		return nil
+	case <-ctx.Done():
+		return framework.AsStatus(ctx.Err())
+	}
+}
+
+func TestScheduler_HandOver(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		// unblockAfter unblocks the pre bind plugin after the duration. Zero keeps it blocked.
+		unblockAfter  time.Duration
+		timeout       time.Duration
+		wantBound     int32
+		wantCancelled []string
+	}{
+		{
+			name:         "wait for the pod in the pre bind phase before returning",
+			unblockAfter: 100 * time.Millisecond,
+			timeout:      wait.ForeverTestTimeout,
+			wantBound:    1,
+		},
+		{
+			name:          "cancel the binding cycle which doesn't finish by the deadline",
+			timeout:       100 * time.Millisecond,
+			wantCancelled: []string{"default/pod1"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"}}
+			// NodeNumber plugin doesn't make the pod wait on node0.
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node0"}}
+			client := fake.NewSimpleClientset(pod, node)
+
+			var bound int32
+			client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "binding" {
+					return false, nil, nil
+				}
+				atomic.AddInt32(&bound, 1)
+				return true, nil, nil
+			})
+
+			sched, err := New(client, informers.NewSharedInformerFactory(client, 0), nil, nil)
+			require.NoError(t, err)
+			pl := &blockingPreBind{started: make(chan struct{}), unblock: make(chan struct{})}
+			sched.preBindPlugins = []framework.PreBindPlugin{pl}
+			require.NoError(t, sched.SchedulingQueue.Add(pod))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go sched.Run(ctx)
+
+			select {
+			case <-pl.started:
+			case <-time.After(wait.ForeverTestTimeout):
+				t.Fatal("the pod doesn't reach the pre bind phase")
+			}
+
+			cancel()
+			if tt.unblockAfter != 0 {
+				time.AfterFunc(tt.unblockAfter, func() { close(pl.unblock) })
+			}
+			client2 := fake.NewSimpleClientset()
+			next, err := New(client2, informers.NewSharedInformerFactory(client2, 0), nil, nil)
+			require.NoError(t, err)
+
+			handOverCtx, handOverCancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer handOverCancel()
+			ret := sched.HandOver(handOverCtx, next)
+
+			// the binding cycle has returned, so the next scheduler can't bind the pod at the same time.
+			sched.bindingCycles.mu.Lock()
+			assert.Empty(t, sched.bindingCycles.pods)
+			sched.bindingCycles.mu.Unlock()
+			assert.Equal(t, tt.wantBound, atomic.LoadInt32(&bound))
+			assert.Equal(t, tt.wantCancelled, ret.CancelledBindingPods)
+			assert.Empty(t, ret.QueuedPods)
+			assert.Empty(t, ret.RejectedWaitingPods)
+		})
+	}
+}
+
+// fakeCloser is the plugin which records whether it's closed.
+type fakeCloser struct {
+	closed chan struct{}
+}
+
+func (c *fakeCloser) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestScheduler_closePlugins(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		// stop stops sched, whose Run has returned.
+		stop func(t *testing.T, sched *Scheduler)
+	}{
+		{
+			name: "close plugins on shutdown",
+			stop: func(t *testing.T, sched *Scheduler) {
+				assert.NoError(t, sched.Shutdown(context.Background()))
+			},
+		},
+		{
+			name: "close plugins after handing over",
+			stop: func(t *testing.T, sched *Scheduler) {
+				client := fake.NewSimpleClientset()
+				next, err := New(client, informers.NewSharedInformerFactory(client, 0), nil, nil)
+				require.NoError(t, err)
+				sched.HandOver(context.Background(), next)
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := fake.NewSimpleClientset()
+			sched, err := New(client, informers.NewSharedInformerFactory(client, 0), nil, nil)
+			require.NoError(t, err)
+			c := &fakeCloser{closed: make(chan struct{})}
+			sched.closers = map[string]io.Closer{"FakeCloser": c}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go sched.Run(ctx)
+			cancel()
+			tt.stop(t, sched)
+
+			select {
+			case <-c.closed:
+			case <-time.After(wait.ForeverTestTimeout):
+				t.Fatal("the plugin isn't closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package waitingpod
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Map is a thread-safe map of pods waiting in the permit phase.
+// The scheduling goroutine adds pods, the binding goroutines remove them, and plugins look them up from any goroutine.
+type Map struct {
+	pods map[types.UID]*WaitingPod
+	mu   sync.RWMutex
+}
+
+// NewMap returns an empty Map.
+func NewMap() *Map {
+	return &Map{
+		pods: make(map[types.UID]*WaitingPod),
+	}
+}
+
+// Add adds the waiting pod.
+func (m *Map) Add(wp *WaitingPod) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pods[wp.GetPod().UID] = wp
+}
+
+// Remove removes the waiting pod given its UID.
+func (m *Map) Remove(uid types.UID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pods, uid)
+}
+
+// Get returns the waiting pod given its UID, or nil if the pod isn't waiting.
+func (m *Map) Get(uid types.UID) *WaitingPod {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pods[uid]
+}
+
+// Iterate acquires a read lock and iterates over the waiting pods.
+// callback must not add or remove waiting pods.
+func (m *Map) Iterate(callback func(*WaitingPod)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, wp := range m.pods {
+		callback(wp)
+	}
+}
@@ -0,0 +1,67 @@
+package waitingpod
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newPod(uid string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod" + uid, Namespace: "default", UID: types.UID(uid)}}
+}
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+	m := NewMap()
+	wp1 := NewWaitingPod(newPod("1"), map[string]time.Duration{"plugin1": time.Minute})
+	wp2 := NewWaitingPod(newPod("2"), map[string]time.Duration{"plugin1": time.Minute})
+	m.Add(wp1)
+	m.Add(wp2)
+
+	assert.Equal(t, wp1, m.Get("1"))
+	assert.Nil(t, m.Get("3"))
+
+	var got []*WaitingPod
+	m.Iterate(func(wp *WaitingPod) {
+		got = append(got, wp)
+	})
+	assert.ElementsMatch(t, []*WaitingPod{wp1, wp2}, got)
+
+	m.Remove("1")
+	assert.Nil(t, m.Get("1"))
+	assert.Equal(t, wp2, m.Get("2"))
+}
+
+func TestMap_concurrentAccess(t *testing.T) {
+	t.Parallel()
+	m := NewMap()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		uid := strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// behave like the scheduling goroutine, plugins and the binding goroutine.
+			m.Add(NewWaitingPod(newPod(uid), map[string]time.Duration{"plugin1": time.Minute}))
+			m.Iterate(func(wp *WaitingPod) {
+				wp.GetPendingPlugins()
+			})
+			if wp := m.Get(types.UID(uid)); wp != nil {
+				wp.Allow("plugin1")
+			}
+			m.Remove(types.UID(uid))
+		}()
+	}
+	wg.Wait()
+
+	count := 0
+	m.Iterate(func(*WaitingPod) { count++ })
+	assert.Equal(t, 0, count)
+}
@@ -6,14 +6,22 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+// Handle provides plugins with the access to waiting pods. It is the subset of framework.Handle.
 type Handle interface {
+	// IterateOverWaitingPods acquires a read lock and iterates over the waiting pods.
+	IterateOverWaitingPods(callback func(*WaitingPod))
+
 	// GetWaitingPod returns a waiting pod given its UID.
 	GetWaitingPod(uid types.UID) *WaitingPod
+
+	// RejectWaitingPod rejects a waiting pod given its UID.
+	RejectWaitingPod(uid types.UID)
 }
 
 // WaitingPod represents a pod waiting in the permit phase.
@@ -21,7 +29,18 @@ type WaitingPod struct {
 	pod            *v1.Pod
 	pendingPlugins map[string]*time.Timer
 	s              chan *framework.Status
-	mu             sync.RWMutex
+	// signaled is true after the signal is sent. Allow and Reject after that are ignored.
+	signaled bool
+	history  []Decision
+	mu       sync.RWMutex
+}
+
+// Decision is the allow or the reject of the waiting pod by a plugin.
+type Decision struct {
+	Plugin  string    `json:"plugin"`
+	Allowed bool      `json:"allowed"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
 }
 
 // NewWaitingPod returns a new WaitingPod instance.
@@ -74,42 +93,93 @@ func (w *WaitingPod) GetPendingPlugins() []string {
 	return plugins
 }
 
+// GetHistory returns the allows and the reject of the waiting pod in chronological order.
+func (w *WaitingPod) GetHistory() []Decision {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]Decision(nil), w.history...)
+}
+
 // Allow declares the waiting pod is allowed to be scheduled by plugin pluginName.
 // If this is the last remaining plugin to allow, then a success signal is delivered
 // to unblock the pod.
+// It's no-op if the pod has already been allowed by all plugins or rejected.
 func (w *WaitingPod) Allow(pluginName string) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	if timer, exist := w.pendingPlugins[pluginName]; exist {
-		timer.Stop()
-		delete(w.pendingPlugins, pluginName)
+	if w.signaled {
+		return
 	}
 
+	timer, exist := w.pendingPlugins[pluginName]
+	if !exist {
+		// the plugin doesn't wait for the pod, or has already allowed it.
+		return
+	}
+	timer.Stop()
+	delete(w.pendingPlugins, pluginName)
+	w.history = append(w.history, Decision{Plugin: pluginName, Allowed: true, Time: time.Now()})
+
 	// Only signal success status after all plugins have allowed
 	if len(w.pendingPlugins) != 0 {
 		return
 	}
 
-	// The select clause works as a non-blocking send.
-	// If there is no receiver, it's a no-op (default case).
-	select {
-	case w.s <- framework.NewStatus(framework.Success, ""):
-	default:
-	}
+	w.signal(framework.NewStatus(framework.Success, ""))
 }
 
 // Reject declares the waiting pod unschedulable.
+// It's no-op if the pod has already been allowed by all plugins or rejected.
 func (w *WaitingPod) Reject(pluginName, msg string) {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.signaled {
+		return
+	}
+
 	for _, timer := range w.pendingPlugins {
 		timer.Stop()
 	}
+	w.history = append(w.history, Decision{Plugin: pluginName, Allowed: false, Message: msg, Time: time.Now()})
+
+	w.signal(framework.NewStatus(framework.Unschedulable, msg).WithFailedPlugin(pluginName))
+}
+
+// allowPollInterval is how often AllowAfter looks up the pod until it becomes a waiting pod.
+const allowPollInterval = 10 * time.Millisecond
+
+// AllowAfter allows the pod of uid by plugin pluginName after delay, without blocking.
+// Permit plugins call it before returning Wait, and the pod becomes a waiting pod only after that.
+// So the pod is looked up until it's found, or timeout, the max wait time of the plugin, passes from now.
+func AllowAfter(h Handle, uid types.UID, pluginName string, delay, timeout time.Duration) {
+	if delay >= timeout {
+		// the pod is rejected by the timeout before it's allowed.
+		return
+	}
+
+	time.AfterFunc(delay, func() {
+		// the error is returned only on the timeout, when the pod has been rejected or removed.
+		_ = wait.PollImmediate(allowPollInterval, timeout-delay, func() (bool, error) {
+			wp := h.GetWaitingPod(uid)
+			if wp == nil {
+				return false, nil
+			}
+			wp.Allow(pluginName)
+			return true, nil
+		})
+	})
+}
+
+// signal sends the status to the pod.
+//
+// NOTE: this function assumes lock has been acquired in caller.
+func (w *WaitingPod) signal(s *framework.Status) {
+	w.signaled = true
 
 	// The select clause works as a non-blocking send.
 	// If there is no receiver, it's a no-op (default case).
 	select {
-	case w.s <- framework.NewStatus(framework.Unschedulable, msg).WithFailedPlugin(pluginName):
+	case w.s <- s:
 	default:
 	}
 }
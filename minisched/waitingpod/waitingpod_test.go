@@ -0,0 +1,190 @@
+package waitingpod
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestWaitingPod(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		waitTime    map[string]time.Duration
+		operateFn   func(wp *WaitingPod)
+		wantCode    framework.Code
+		wantHistory []Decision
+	}{
+		{
+			name:     "allowed by all plugins",
+			waitTime: map[string]time.Duration{"plugin1": time.Minute, "plugin2": time.Minute},
+			operateFn: func(wp *WaitingPod) {
+				wp.Allow("plugin1")
+				wp.Allow("plugin2")
+			},
+			wantCode: framework.Success,
+			wantHistory: []Decision{
+				{Plugin: "plugin1", Allowed: true},
+				{Plugin: "plugin2", Allowed: true},
+			},
+		},
+		{
+			name:     "ignore Allow and Reject after the pod is rejected",
+			waitTime: map[string]time.Duration{"plugin1": time.Minute},
+			operateFn: func(wp *WaitingPod) {
+				wp.Reject("plugin2", "rejected")
+				wp.Allow("plugin1")
+				wp.Reject("plugin3", "rejected again")
+			},
+			wantCode: framework.Unschedulable,
+			wantHistory: []Decision{
+				{Plugin: "plugin2", Allowed: false, Message: "rejected"},
+			},
+		},
+		{
+			name:     "ignore Reject after the pod is allowed",
+			waitTime: map[string]time.Duration{"plugin1": time.Minute},
+			operateFn: func(wp *WaitingPod) {
+				wp.Allow("plugin1")
+				wp.Allow("plugin1")
+				wp.Reject("plugin2", "rejected")
+			},
+			wantCode: framework.Success,
+			wantHistory: []Decision{
+				{Plugin: "plugin1", Allowed: true},
+			},
+		},
+		{
+			name:     "ignore Allow by the plugin which doesn't wait for the pod",
+			waitTime: map[string]time.Duration{"plugin1": time.Minute},
+			operateFn: func(wp *WaitingPod) {
+				wp.Allow("plugin2")
+				wp.Allow("plugin1")
+			},
+			wantCode: framework.Success,
+			wantHistory: []Decision{
+				{Plugin: "plugin1", Allowed: true},
+			},
+		},
+		{
+			name:      "rejected by timeout",
+			waitTime:  map[string]time.Duration{"plugin1": 10 * time.Millisecond},
+			operateFn: func(wp *WaitingPod) {},
+			wantCode:  framework.Unschedulable,
+			wantHistory: []Decision{
+				{Plugin: "plugin1", Allowed: false, Message: "rejected due to timeout after waiting 10ms at plugin plugin1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			wp := NewWaitingPod(newPod("1"), tt.waitTime)
+
+			tt.operateFn(wp)
+
+			assert.Equal(t, tt.wantCode, wp.GetSignal().Code())
+			history := wp.GetHistory()
+			for i := range history {
+				assert.False(t, history[i].Time.IsZero())
+				history[i].Time = time.Time{}
+			}
+			assert.Equal(t, tt.wantHistory, history)
+		})
+	}
+}
+
+func TestWaitingPod_concurrentAllowAndReject(t *testing.T) {
+	t.Parallel()
+	wp := NewWaitingPod(newPod("1"), map[string]time.Duration{"plugin1": time.Minute, "plugin2": time.Millisecond})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		wp.Allow("plugin1")
+	}()
+	go func() {
+		defer wg.Done()
+		wp.Reject("plugin3", "rejected")
+	}()
+	wg.Wait()
+
+	// only one decision is delivered even though the timeout of plugin2 also rejects the pod.
+	s := wp.GetSignal()
+	assert.Equal(t, framework.Unschedulable, s.Code())
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case s := <-wp.s:
+		t.Fatalf("unexpected second signal: %v", s)
+	default:
+	}
+}
+
+// mapHandle is Handle backed by Map.
+type mapHandle struct {
+	*Map
+}
+
+func (h mapHandle) IterateOverWaitingPods(callback func(*WaitingPod)) { h.Iterate(callback) }
+func (h mapHandle) GetWaitingPod(uid types.UID) *WaitingPod           { return h.Get(uid) }
+func (h mapHandle) RejectWaitingPod(uid types.UID)                    {}
+
+func TestAllowAfter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		timeout time.Duration
+		// addAfter is when the pod becomes a waiting pod, which is rejected in 300ms unless it's allowed.
+		addAfter time.Duration
+		wantCode framework.Code
+	}{
+		{
+			name:     "allow the waiting pod after the delay",
+			delay:    10 * time.Millisecond,
+			timeout:  time.Minute,
+			wantCode: framework.Success,
+		},
+		{
+			name:     "allow the pod which becomes a waiting pod after the delay",
+			delay:    10 * time.Millisecond,
+			timeout:  time.Minute,
+			addAfter: 100 * time.Millisecond,
+			wantCode: framework.Success,
+		},
+		{
+			name:     "don't allow the pod which becomes a waiting pod after the timeout",
+			delay:    10 * time.Millisecond,
+			timeout:  50 * time.Millisecond,
+			addAfter: 100 * time.Millisecond,
+			wantCode: framework.Unschedulable,
+		},
+		{
+			name:     "don't allow the pod if the delay isn't shorter than the timeout",
+			delay:    50 * time.Millisecond,
+			timeout:  50 * time.Millisecond,
+			wantCode: framework.Unschedulable,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m := NewMap()
+			pod := newPod("1")
+
+			AllowAfter(mapHandle{m}, pod.UID, "plugin1", tt.delay, tt.timeout)
+			time.Sleep(tt.addAfter)
+			wp := NewWaitingPod(pod, map[string]time.Duration{"plugin1": 300 * time.Millisecond})
+			m.Add(wp)
+
+			assert.Equal(t, tt.wantCode, wp.GetSignal().Code())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package nodelifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/pkg/controller/nodelifecycle"
+)
+
+// The defaults of Options, the same as kube-controller-manager's defaults.
+const (
+	DefaultNodeMonitorGracePeriod = 40 * time.Second
+	DefaultNodeStartupGracePeriod = 60 * time.Second
+	DefaultNodeEvictionRate       = 0.1
+)
+
+const (
+	// nodeMonitorPeriod is how often the controller checks the heartbeats of the nodes.
+	nodeMonitorPeriod = 5 * time.Second
+	// podEvictionTimeout is only used without the taint manager, which is always enabled here.
+	podEvictionTimeout        = 5 * time.Minute
+	secondaryNodeEvictionRate = 0.01
+	largeClusterSizeThreshold = 50
+	unhealthyZoneThreshold    = 0.55
+	enableTaintBasedEvictions = true
+)
+
+// Options configures the node lifecycle controller.
+type Options struct {
+	// NodeMonitorGracePeriod is how long the node can stop its heartbeats before it's marked as unreachable.
+	NodeMonitorGracePeriod time.Duration
+	// NodeStartupGracePeriod is how long the created node can be without heartbeats before it's marked as unreachable.
+	NodeStartupGracePeriod time.Duration
+	// NodeEvictionRate is the number of nodes per second which are tainted in a healthy zone.
+	NodeEvictionRate float32
+}
+
+// StartNodeLifecycleController starts the node lifecycle controller like kube-controller-manager does.
+// It taints the nodes whose heartbeats stop with node.kubernetes.io/not-ready or node.kubernetes.io/unreachable,
+// and evicts the pods on them which don't tolerate the taints after their tolerationSeconds.
+// The zero values in opts are replaced with the defaults.
+func StartNodeLifecycleController(client clientset.Interface, opts Options) (
+	func(), // function to shutdown node lifecycle controller
+	error,
+) {
+	if opts.NodeMonitorGracePeriod == 0 {
+		opts.NodeMonitorGracePeriod = DefaultNodeMonitorGracePeriod
+	}
+	if opts.NodeStartupGracePeriod == 0 {
+		opts.NodeStartupGracePeriod = DefaultNodeStartupGracePeriod
+	}
+	if opts.NodeEvictionRate == 0 {
+		opts.NodeEvictionRate = DefaultNodeEvictionRate
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	lifecycleController, err := nodelifecycle.NewNodeLifecycleController(
+		informerFactory.Coordination().V1().Leases(),
+		informerFactory.Core().V1().Pods(),
+		informerFactory.Core().V1().Nodes(),
+		informerFactory.Apps().V1().DaemonSets(),
+		client,
+		nodeMonitorPeriod,
+		opts.NodeStartupGracePeriod,
+		opts.NodeMonitorGracePeriod,
+		podEvictionTimeout,
+		opts.NodeEvictionRate,
+		secondaryNodeEvictionRate,
+		largeClusterSizeThreshold,
+		unhealthyZoneThreshold,
+		enableTaintBasedEvictions,
+	)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("construct node lifecycle controller: %w", err)
+	}
+
+	go lifecycleController.Run(ctx.Done())
+	informerFactory.Start(ctx.Done())
+	informerFactory.WaitForCacheSync(ctx.Done())
+
+	return cancel, nil
+}
@@ -0,0 +1,108 @@
+package nodelifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+	"github.com/sanposhiho/mini-kube-scheduler/kubelet"
+)
+
+func TestStartNodeLifecycleController(t *testing.T) {
+	t.Parallel()
+	cfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{})
+	require.NoError(t, err)
+	defer apiShutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	kubeletShutdown, err := kubelet.StartFakeKubelet(client, kubelet.Options{HeartbeatInterval: time.Second})
+	require.NoError(t, err)
+	defer kubeletShutdown()
+	shutdown, err := StartNodeLifecycleController(client, Options{NodeMonitorGracePeriod: 5 * time.Second, NodeEvictionRate: 100})
+	require.NoError(t, err)
+	defer shutdown()
+
+	for _, name := range []string{"node1", "node2"} {
+		_, err := client.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	newPod := func(name string, tolerationSeconds *int64) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName:   "node1",
+				Containers: []v1.Container{{Name: "container1", Image: "k8s.gcr.io/pause:3.5"}},
+				Tolerations: []v1.Toleration{
+					{Key: v1.TaintNodeNotReady, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute, TolerationSeconds: tolerationSeconds},
+					{Key: v1.TaintNodeUnreachable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute, TolerationSeconds: tolerationSeconds},
+				},
+			},
+		}
+	}
+	_, err = client.CoreV1().Pods("default").Create(ctx, newPod("pod1", pointer.Int64Ptr(1)), metav1.CreateOptions{})
+	require.NoError(t, err)
+	// pod2 tolerates the taints forever.
+	_, err = client.CoreV1().Pods("default").Create(ctx, newPod("pod2", nil), metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		pods, err := client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false
+		}
+		for _, p := range pods.Items {
+			if p.Status.Phase != v1.PodRunning {
+				return false
+			}
+		}
+		return len(pods.Items) == 2
+	}, 10*time.Second, 100*time.Millisecond)
+
+	setKubeletStopped := func(stopped string) {
+		patch := []byte(`{"metadata":{"annotations":{"` + kubelet.KubeletStoppedAnnotation + `":"` + stopped + `"}}}`)
+		_, err := client.CoreV1().Nodes().Patch(ctx, "node1", types.MergePatchType, patch, metav1.PatchOptions{})
+		require.NoError(t, err)
+	}
+	hasUnreachableTaint := func(name string) bool {
+		node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == v1.TaintNodeUnreachable && taint.Effect == v1.TaintEffectNoExecute {
+				return true
+			}
+		}
+		return false
+	}
+
+	setKubeletStopped("true")
+	assert.Eventually(t, func() bool { return hasUnreachableTaint("node1") }, 30*time.Second, 100*time.Millisecond)
+	assert.False(t, hasUnreachableTaint("node2"))
+	// pod1 is evicted, but it's terminating while the kubelet is stopped.
+	assert.Eventually(t, func() bool {
+		pod, err := client.CoreV1().Pods("default").Get(ctx, "pod1", metav1.GetOptions{})
+		return err == nil && pod.DeletionTimestamp != nil
+	}, 10*time.Second, 100*time.Millisecond)
+	pod, err := client.CoreV1().Pods("default").Get(ctx, "pod2", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, pod.DeletionTimestamp)
+
+	// the kubelet restarts.
+	setKubeletStopped("false")
+	assert.Eventually(t, func() bool { return !hasUnreachableTaint("node1") }, 30*time.Second, 100*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, err := client.CoreV1().Pods("default").Get(ctx, "pod1", metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,434 @@
+package pvcontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	storagev1beta1listers "k8s.io/client-go/listers/storage/v1beta1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	pvutil "k8s.io/kubernetes/pkg/controller/volume/persistentvolume/util"
+)
+
+const (
+	// StorageCapacityAnnotation is the annotation of the provisioned PV which has the CSIStorageCapacity
+	// in "<namespace>/<name>" consumed by it. The capacity is given back when the PV is deleted.
+	StorageCapacityAnnotation = "scheduler-simulator/storage-capacity"
+
+	// DefaultTopologyKey is the default of Options.TopologyKey.
+	DefaultTopologyKey = v1.LabelTopologyZone
+
+	provisionerName = "fake-provisioner"
+)
+
+// fakeProvisioner plays the external provisioners of the StorageClasses which the in-tree volume plugins don't provision,
+// like CSI drivers do. It creates the PVs without any storage behind them.
+type fakeProvisioner struct {
+	client         clientset.Interface
+	topologyKey    string
+	recorder       record.EventRecorder
+	claimLister    corelisters.PersistentVolumeClaimLister
+	volumeLister   corelisters.PersistentVolumeLister
+	classLister    storagelisters.StorageClassLister
+	nodeLister     corelisters.NodeLister
+	capacityLister storagev1beta1listers.CSIStorageCapacityLister
+	claimQueue     workqueue.RateLimitingInterface
+	volumeQueue    workqueue.RateLimitingInterface
+}
+
+// startFakeProvisioner starts the fake provisioner with the informers in informerFactory.
+// The informers are started by the caller.
+func startFakeProvisioner(ctx context.Context, client clientset.Interface, informerFactory informers.SharedInformerFactory, topologyKey string) {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		eventBroadcaster.Shutdown()
+	}()
+
+	claimInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	volumeInformer := informerFactory.Core().V1().PersistentVolumes()
+	p := &fakeProvisioner{
+		client:         client,
+		topologyKey:    topologyKey,
+		recorder:       eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: provisionerName}),
+		claimLister:    claimInformer.Lister(),
+		volumeLister:   volumeInformer.Lister(),
+		classLister:    informerFactory.Storage().V1().StorageClasses().Lister(),
+		nodeLister:     informerFactory.Core().V1().Nodes().Lister(),
+		capacityLister: informerFactory.Storage().V1beta1().CSIStorageCapacities().Lister(),
+		claimQueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "fake-provisioner-claim"),
+		volumeQueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "fake-provisioner-volume"),
+	}
+
+	claimInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { enqueue(p.claimQueue, obj) },
+		UpdateFunc: func(_, newObj interface{}) { enqueue(p.claimQueue, newObj) },
+	})
+	volumeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { enqueue(p.volumeQueue, obj) },
+		UpdateFunc: func(_, newObj interface{}) { enqueue(p.volumeQueue, newObj) },
+	})
+	// the informers are needed to be started by the caller.
+	informerFactory.Storage().V1().StorageClasses().Informer()
+	informerFactory.Core().V1().Nodes().Informer()
+	informerFactory.Storage().V1beta1().CSIStorageCapacities().Informer()
+
+	// only one worker provisions the volumes so that the capacities aren't overcommitted.
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for processNext(ctx, p.claimQueue, p.syncClaim) {
+		}
+	}, time.Second)
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for processNext(ctx, p.volumeQueue, p.syncVolume) {
+		}
+	}, time.Second)
+	go func() {
+		<-ctx.Done()
+		p.claimQueue.ShutDown()
+		p.volumeQueue.ShutDown()
+	}()
+}
+
+func enqueue(queue workqueue.Interface, obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	queue.Add(key)
+}
+
+func processNext(ctx context.Context, queue workqueue.RateLimitingInterface, sync func(context.Context, string) error) bool {
+	key, quit := queue.Get()
+	if quit {
+		return false
+	}
+	defer queue.Done(key)
+
+	k, ok := key.(string)
+	if !ok {
+		queue.Forget(key)
+		return true
+	}
+	if err := sync(ctx, k); err != nil {
+		utilruntime.HandleError(fmt.Errorf("sync %s: %w", k, err))
+		queue.AddRateLimited(key)
+		return true
+	}
+	queue.Forget(key)
+	return true
+}
+
+// syncClaim provisions the PV for the claim which the PV controller leaves to the external provisioner.
+// The claim of the WaitForFirstConsumer StorageClass is provisioned in the topology of the node selected by the scheduler.
+// If no CSIStorageCapacity of the StorageClass in the topology has enough capacity, the provisioning fails
+// and the scheduler is asked to select another node.
+func (p *fakeProvisioner) syncClaim(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	claim, err := p.claimLister.PersistentVolumeClaims(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get claim: %w", err)
+	}
+	if claim.Spec.VolumeName != "" || claim.DeletionTimestamp != nil || claim.Spec.StorageClassName == nil {
+		return nil
+	}
+	provisioner, ok := claim.Annotations[pvutil.AnnStorageProvisioner]
+	if !ok {
+		// the PV controller provisions it with the in-tree volume plugin, or waits for the scheduler.
+		return nil
+	}
+	if _, err := p.volumeLister.Get(volumeName(claim)); err == nil {
+		// it's been provisioned, and waits to be bound by the PV controller.
+		return nil
+	}
+	class, err := p.classLister.Get(*claim.Spec.StorageClassName)
+	if err != nil {
+		return fmt.Errorf("get StorageClass: %w", err)
+	}
+	if class.Provisioner != provisioner {
+		return nil
+	}
+	if isDelayBinding(class) && claim.Annotations[pvutil.AnnSelectedNode] == "" {
+		// it waits for the scheduler to select the node again.
+		return nil
+	}
+
+	topologies, err := p.topologies(claim, class)
+	if err != nil {
+		return err
+	}
+	size := claim.Spec.Resources.Requests[v1.ResourceStorage]
+	for _, topology := range topologies {
+		capacity, ok, err := p.findCapacity(class.Name, topology, size)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		return p.provision(ctx, claim, class, topology[p.topologyKey], capacity)
+	}
+
+	p.recorder.Eventf(claim, v1.EventTypeWarning, "ProvisioningFailed", "no CSIStorageCapacity of StorageClass %q has %s in the topology", class.Name, size.String())
+	if _, ok := claim.Annotations[pvutil.AnnSelectedNode]; ok {
+		// the scheduler selects another node, like external-provisioner does on the final errors.
+		claim = claim.DeepCopy()
+		delete(claim.Annotations, pvutil.AnnSelectedNode)
+		if _, err := p.client.CoreV1().PersistentVolumeClaims(claim.Namespace).Update(ctx, claim, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("remove selected node annotation: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no capacity for claim")
+}
+
+// topologies returns the candidate topologies of the claim's volume.
+// For the WaitForFirstConsumer StorageClass, it's the labels of the node selected by the scheduler.
+// Otherwise, it's each value of the topology key allowed by the StorageClass, or found in the nodes.
+func (p *fakeProvisioner) topologies(claim *v1.PersistentVolumeClaim, class *storagev1.StorageClass) ([]labels.Set, error) {
+	if isDelayBinding(class) {
+		node, err := p.nodeLister.Get(claim.Annotations[pvutil.AnnSelectedNode])
+		if apierrors.IsNotFound(err) {
+			// the scheduler needs to select another node.
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get selected node: %w", err)
+		}
+		return []labels.Set{node.Labels}, nil
+	}
+
+	values := sets.NewString()
+	for _, term := range class.AllowedTopologies {
+		for _, e := range term.MatchLabelExpressions {
+			if e.Key == p.topologyKey {
+				values.Insert(e.Values...)
+			}
+		}
+	}
+	if values.Len() == 0 {
+		nodes, err := p.nodeLister.List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("list nodes: %w", err)
+		}
+		for _, node := range nodes {
+			if v, ok := node.Labels[p.topologyKey]; ok {
+				values.Insert(v)
+			}
+		}
+	}
+	if values.Len() == 0 {
+		// the volume is accessible from all nodes.
+		return []labels.Set{{}}, nil
+	}
+
+	topologies := make([]labels.Set, 0, values.Len())
+	for _, v := range values.List() {
+		topologies = append(topologies, labels.Set{p.topologyKey: v})
+	}
+	return topologies, nil
+}
+
+// findCapacity returns the CSIStorageCapacity of the StorageClass in the topology which has size.
+// It returns nil and true if the StorageClass has no CSIStorageCapacity, which means the unlimited capacity.
+func (p *fakeProvisioner) findCapacity(className string, topology labels.Set, size resource.Quantity) (*storagev1beta1.CSIStorageCapacity, bool, error) {
+	capacities, err := p.capacityLister.List(labels.Everything())
+	if err != nil {
+		return nil, false, fmt.Errorf("list CSIStorageCapacities: %w", err)
+	}
+	// sort them to consume the capacities in the stable order.
+	sort.Slice(capacities, func(i, j int) bool {
+		return capacities[i].Namespace+"/"+capacities[i].Name < capacities[j].Namespace+"/"+capacities[j].Name
+	})
+
+	found := false
+	for _, c := range capacities {
+		if c.StorageClassName != className {
+			continue
+		}
+		found = true
+		if c.NodeTopology == nil || c.Capacity == nil || c.Capacity.Cmp(size) < 0 {
+			continue
+		}
+		if c.MaximumVolumeSize != nil && c.MaximumVolumeSize.Cmp(size) < 0 {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(c.NodeTopology)
+		if err != nil {
+			return nil, false, fmt.Errorf("convert node topology of CSIStorageCapacity %s/%s: %w", c.Namespace, c.Name, err)
+		}
+		if selector.Matches(topology) {
+			return c, true, nil
+		}
+	}
+	return nil, !found, nil
+}
+
+// provision creates the PV bound to the claim in the topology, and consumes the capacity if it isn't nil.
+func (p *fakeProvisioner) provision(ctx context.Context, claim *v1.PersistentVolumeClaim, class *storagev1.StorageClass, topologyValue string, capacity *storagev1beta1.CSIStorageCapacity) error {
+	size := claim.Spec.Resources.Requests[v1.ResourceStorage]
+	reclaimPolicy := v1.PersistentVolumeReclaimDelete
+	if class.ReclaimPolicy != nil {
+		reclaimPolicy = *class.ReclaimPolicy
+	}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        volumeName(claim),
+			Annotations: map[string]string{pvutil.AnnDynamicallyProvisioned: class.Provisioner},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:                      v1.ResourceList{v1.ResourceStorage: size},
+			AccessModes:                   claim.Spec.AccessModes,
+			VolumeMode:                    claim.Spec.VolumeMode,
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			StorageClassName:              class.Name,
+			MountOptions:                  class.MountOptions,
+			// the PV controller binds the claim to it.
+			ClaimRef: &v1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Namespace:  claim.Namespace,
+				Name:       claim.Name,
+				UID:        claim.UID,
+			},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{Driver: class.Provisioner, VolumeHandle: volumeName(claim)},
+			},
+		},
+	}
+	if topologyValue != "" {
+		pv.Spec.NodeAffinity = &v1.VolumeNodeAffinity{Required: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{{MatchExpressions: []v1.NodeSelectorRequirement{
+				{Key: p.topologyKey, Operator: v1.NodeSelectorOpIn, Values: []string{topologyValue}},
+			}}},
+		}}
+	}
+
+	if capacity != nil {
+		pv.Annotations[StorageCapacityAnnotation] = capacity.Namespace + "/" + capacity.Name
+	}
+	if _, err := p.client.CoreV1().PersistentVolumes().Create(ctx, pv, metav1.CreateOptions{}); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// the claim is requeued before the informer sees the PV, which has consumed the capacity.
+			return nil
+		}
+		return fmt.Errorf("create PV: %w", err)
+	}
+	// the capacity is consumed only by the PV created, so that the requeued claims don't consume it twice.
+	if capacity != nil {
+		if err := p.addCapacity(ctx, capacity.Namespace, capacity.Name, size, false); err != nil {
+			err = fmt.Errorf("consume CSIStorageCapacity: %w", err)
+			// the claim is provisioned again with the PV deleted.
+			if derr := p.client.CoreV1().PersistentVolumes().Delete(ctx, pv.Name, metav1.DeleteOptions{}); derr != nil && !apierrors.IsNotFound(derr) {
+				utilruntime.HandleError(fmt.Errorf("delete PV %s whose capacity isn't consumed: %w", pv.Name, derr))
+			}
+			return err
+		}
+	}
+
+	p.recorder.Eventf(claim, v1.EventTypeNormal, "ProvisioningSucceeded", "Successfully provisioned volume %s", pv.Name)
+	return nil
+}
+
+// syncVolume deletes the released PV provisioned by the fake provisioner if its reclaim policy is Delete,
+// and gives back its capacity.
+func (p *fakeProvisioner) syncVolume(ctx context.Context, name string) error {
+	pv, err := p.volumeLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get PV: %w", err)
+	}
+	provisioner, ok := pv.Annotations[pvutil.AnnDynamicallyProvisioned]
+	if !ok || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != provisioner {
+		// it's not provisioned by the fake provisioner.
+		return nil
+	}
+	if pv.Status.Phase != v1.VolumeReleased || pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete || pv.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if c, ok := pv.Annotations[StorageCapacityAnnotation]; ok {
+		namespace, name, err := cache.SplitMetaNamespaceKey(c)
+		if err != nil {
+			return fmt.Errorf("parse annotation %s: %w", StorageCapacityAnnotation, err)
+		}
+		// the annotation is removed before the capacity is given back, so that the retries don't give it back twice.
+		// The update fails with conflict if another sync has removed it.
+		// If giving back fails, the capacity is lost rather than given back twice.
+		pv = pv.DeepCopy()
+		delete(pv.Annotations, StorageCapacityAnnotation)
+		if pv, err = p.client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("remove annotation %s: %w", StorageCapacityAnnotation, err)
+		}
+		err = p.addCapacity(ctx, namespace, name, pv.Spec.Capacity[v1.ResourceStorage], true)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("give back CSIStorageCapacity: %w", err)
+		}
+	}
+	err = p.client.CoreV1().PersistentVolumes().Delete(ctx, pv.Name, metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(pv.UID))})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete PV: %w", err)
+	}
+	return nil
+}
+
+// addCapacity adds size to the capacity of the CSIStorageCapacity, or subtracts size from it if add is false.
+func (p *fakeProvisioner) addCapacity(ctx context.Context, namespace, name string, size resource.Quantity, add bool) error {
+	capacities := p.client.StorageV1beta1().CSIStorageCapacities(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		c, err := capacities.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if c.Capacity == nil {
+			return nil
+		}
+		q := c.Capacity.DeepCopy()
+		if add {
+			q.Add(size)
+		} else {
+			q.Sub(size)
+		}
+		c.Capacity = &q
+		_, err = capacities.Update(ctx, c, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func isDelayBinding(class *storagev1.StorageClass) bool {
+	return class.VolumeBindingMode != nil && *class.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
+
+// volumeName returns the name of the PV provisioned for the claim, in the same format as external-provisioner.
+func volumeName(claim *v1.PersistentVolumeClaim) string {
+	return "pvc-" + string(claim.UID)
+}
@@ -0,0 +1,207 @@
+package pvcontroller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	pvutil "k8s.io/kubernetes/pkg/controller/volume/persistentvolume/util"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+)
+
+func TestFakeProvisioner(t *testing.T) {
+	t.Parallel()
+	cfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{})
+	require.NoError(t, err)
+	defer apiShutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	shutdown, err := StartPersistentVolumeController(client, Options{})
+	require.NoError(t, err)
+	defer shutdown()
+
+	for _, zone := range []string{"zone-a", "zone-b"} {
+		_, err := client.CoreV1().Nodes().Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-" + zone,
+			Labels: map[string]string{v1.LabelTopologyZone: zone},
+		}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	waitForFirstConsumer := storagev1.VolumeBindingWaitForFirstConsumer
+	for _, class := range []*storagev1.StorageClass{
+		{ObjectMeta: metav1.ObjectMeta{Name: "immediate"}, Provisioner: "csi.example.com"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "wffc"}, Provisioner: "csi.example.com", VolumeBindingMode: &waitForFirstConsumer},
+	} {
+		_, err := client.StorageV1().StorageClasses().Create(ctx, class, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	for name, c := range map[string]struct {
+		class    string
+		zone     string
+		capacity string
+	}{
+		"immediate-a": {class: "immediate", zone: "zone-a", capacity: "1Gi"},
+		"immediate-b": {class: "immediate", zone: "zone-b", capacity: "10Gi"},
+		"wffc-a":      {class: "wffc", zone: "zone-a", capacity: "1Gi"},
+	} {
+		capacity := resource.MustParse(c.capacity)
+		_, err := client.StorageV1beta1().CSIStorageCapacities("default").Create(ctx, &storagev1beta1.CSIStorageCapacity{
+			ObjectMeta:       metav1.ObjectMeta{Name: name},
+			StorageClassName: c.class,
+			NodeTopology:     &metav1.LabelSelector{MatchLabels: map[string]string{v1.LabelTopologyZone: c.zone}},
+			Capacity:         &capacity,
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	createClaim := func(name, class, size, selectedNode string) {
+		claim := &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PersistentVolumeClaimSpec{
+				StorageClassName: &class,
+				AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+				Resources:        v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(size)}},
+			},
+		}
+		if selectedNode != "" {
+			claim.Annotations = map[string]string{pvutil.AnnSelectedNode: selectedNode}
+		}
+		_, err := client.CoreV1().PersistentVolumeClaims("default").Create(ctx, claim, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	// boundZone returns the zone of the volume bound to the claim.
+	boundZone := func(name string) string {
+		claim, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, name, metav1.GetOptions{})
+		if err != nil || claim.Status.Phase != v1.ClaimBound {
+			return ""
+		}
+		pv, err := client.CoreV1().PersistentVolumes().Get(ctx, claim.Spec.VolumeName, metav1.GetOptions{})
+		if err != nil || pv.Spec.NodeAffinity == nil {
+			return ""
+		}
+		return pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Values[0]
+	}
+	capacity := func(name string) string {
+		c, err := client.StorageV1beta1().CSIStorageCapacities("default").Get(ctx, name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return c.Capacity.String()
+	}
+
+	// the volume is provisioned in zone-b, which has enough capacity.
+	createClaim("claim1", "immediate", "5Gi", "")
+	assert.Eventually(t, func() bool { return boundZone("claim1") == "zone-b" }, 30*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "5Gi", capacity("immediate-b"))
+	assert.Equal(t, "1Gi", capacity("immediate-a"))
+
+	// the capacity is given back when the volume is deleted.
+	claim1, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "claim1", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, client.CoreV1().PersistentVolumeClaims("default").Delete(ctx, "claim1", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		_, err := client.CoreV1().PersistentVolumes().Get(ctx, claim1.Spec.VolumeName, metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	}, 30*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "10Gi", capacity("immediate-b"))
+
+	// the volume is provisioned in the zone of the selected node.
+	createClaim("claim2", "wffc", "500Mi", "node-zone-a")
+	assert.Eventually(t, func() bool { return boundZone("claim2") == "zone-a" }, 30*time.Second, 100*time.Millisecond)
+	assert.Equal(t, "524Mi", capacity("wffc-a"))
+
+	// the scheduler is asked to select another node if the selected node doesn't have enough capacity.
+	createClaim("claim3", "wffc", "1Gi", "node-zone-a")
+	assert.Eventually(t, func() bool {
+		claim, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "claim3", metav1.GetOptions{})
+		if err != nil {
+			return false
+		}
+		_, selected := claim.Annotations[pvutil.AnnSelectedNode]
+		return !selected
+	}, 30*time.Second, 100*time.Millisecond)
+	claim3, err := client.CoreV1().PersistentVolumeClaims("default").Get(ctx, "claim3", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, v1.ClaimPending, claim3.Status.Phase)
+}
+
+func TestFakeProvisioner_requeue(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	capacity := resource.MustParse("10Gi")
+	class := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "immediate"}, Provisioner: "csi.example.com"}
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim1", Namespace: "default", UID: "uid1"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &class.Name,
+			Resources:        v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("4Gi")}},
+		},
+	}
+	client := fake.NewSimpleClientset(&storagev1beta1.CSIStorageCapacity{
+		ObjectMeta:       metav1.ObjectMeta{Name: "capacity1", Namespace: "default"},
+		StorageClassName: class.Name,
+		NodeTopology:     &metav1.LabelSelector{},
+		Capacity:         &capacity,
+	})
+	// the first deletion of the PV fails.
+	deleteCalled := false
+	client.PrependReactor("delete", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if deleteCalled {
+			return false, nil, nil
+		}
+		deleteCalled = true
+		return true, nil, errors.New("injected error")
+	})
+	volumeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	p := &fakeProvisioner{
+		client:       client,
+		topologyKey:  DefaultTopologyKey,
+		recorder:     record.NewFakeRecorder(10),
+		volumeLister: corelisters.NewPersistentVolumeLister(volumeIndexer),
+	}
+	capacityOf := func() string {
+		c, err := client.StorageV1beta1().CSIStorageCapacities("default").Get(ctx, "capacity1", metav1.GetOptions{})
+		require.NoError(t, err)
+		return c.Capacity.String()
+	}
+	c, err := client.StorageV1beta1().CSIStorageCapacities("default").Get(ctx, "capacity1", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	// the claim is requeued before the informer sees the PV.
+	require.NoError(t, p.provision(ctx, claim, class, "", c))
+	require.NoError(t, p.provision(ctx, claim, class, "", c))
+	assert.Equal(t, "6Gi", capacityOf())
+
+	// the PV is released, and the deletion is retried.
+	pv, err := client.CoreV1().PersistentVolumes().Get(ctx, volumeName(claim), metav1.GetOptions{})
+	require.NoError(t, err)
+	pv.Status.Phase = v1.VolumeReleased
+	pv, err = client.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, volumeIndexer.Add(pv))
+	require.Error(t, p.syncVolume(ctx, pv.Name))
+	pv, err = client.CoreV1().PersistentVolumes().Get(ctx, volumeName(claim), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, volumeIndexer.Update(pv))
+	require.NoError(t, p.syncVolume(ctx, pv.Name))
+
+	_, err = client.CoreV1().PersistentVolumes().Get(ctx, volumeName(claim), metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.Equal(t, "10Gi", capacityOf())
+}
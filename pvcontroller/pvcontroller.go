@@ -13,10 +13,24 @@ import (
 	"k8s.io/kubernetes/pkg/volume/local"
 )
 
-func StartPersistentVolumeController(client clientset.Interface) (
+// Options configures the PV controller.
+type Options struct {
+	// TopologyKey is the label of the nodes which the fake provisioner uses as the topology of the volumes,
+	// e.g. the volume provisioned in the zone "zone-a" can be used only from the nodes with the label "<TopologyKey>: zone-a".
+	// It defaults to DefaultTopologyKey.
+	TopologyKey string
+}
+
+// StartPersistentVolumeController starts the PV controller, and the fake provisioner
+// which dynamically provisions the volumes of the StorageClasses the in-tree volume plugins don't provision.
+func StartPersistentVolumeController(client clientset.Interface, opts Options) (
 	func(), // function to shutdown PV controller
 	error,
 ) {
+	if opts.TopologyKey == "" {
+		opts.TopologyKey = DefaultTopologyKey
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	informerFactory := informers.NewSharedInformerFactory(client, 0)
 	params := persistentvolume.ControllerParameters{
@@ -35,6 +49,7 @@ func StartPersistentVolumeController(client clientset.Interface) (
 		cancel()
 		return nil, fmt.Errorf("construct persistentvolume controller: %w", err)
 	}
+	startFakeProvisioner(ctx, client, informerFactory, opts.TopologyKey)
 
 	go volumeController.Run(ctx.Done())
 	informerFactory.Start(ctx.Done())
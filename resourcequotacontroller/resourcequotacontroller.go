@@ -0,0 +1,72 @@
+package resourcequotacontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apiserver/pkg/quota/v1/generic"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/controller-manager/pkg/informerfactory"
+	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/controller/resourcequota"
+	"k8s.io/kubernetes/pkg/quota/v1/install"
+)
+
+const (
+	// resyncPeriod is how often the usage of all quotas is recalculated.
+	// The usage is also updated on the changes of the objects, so it's just a safety net.
+	resyncPeriod = 30 * time.Second
+	// discoveryPeriod is how often the resources tracked by quotas are discovered.
+	discoveryPeriod = 30 * time.Second
+	workers         = 5
+)
+
+// StartResourceQuotaController starts the resource quota controller, which calculates the usage of ResourceQuotas
+// so that the ResourceQuota admission plugin can enforce them like kube-controller-manager does.
+func StartResourceQuotaController(client clientset.Interface, restclientCfg *restclient.Config) (
+	func(), // function to shutdown resource quota controller
+	error,
+) {
+	metadataClient, err := metadata.NewForConfig(restclientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create metadata client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	metadataInformers := metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+	informersStarted := make(chan struct{})
+
+	discoveryFunc := client.Discovery().ServerPreferredNamespacedResources
+	listerFuncForResource := generic.ListerFuncForResourceFunc(informerFactory.ForResource)
+	quotaConfiguration := install.NewQuotaConfigurationForControllers(listerFuncForResource)
+	quotaController, err := resourcequota.NewController(&resourcequota.ControllerOptions{
+		QuotaClient:               client.CoreV1(),
+		ResourceQuotaInformer:     informerFactory.Core().V1().ResourceQuotas(),
+		ResyncPeriod:              controller.StaticResyncPeriodFunc(resyncPeriod),
+		InformerFactory:           informerfactory.NewInformerFactory(informerFactory, metadataInformers),
+		ReplenishmentResyncPeriod: controller.StaticResyncPeriodFunc(resyncPeriod),
+		DiscoveryFunc:             discoveryFunc,
+		IgnoredResourcesFunc:      quotaConfiguration.IgnoredResources,
+		InformersStarted:          informersStarted,
+		Registry:                  generic.NewRegistry(quotaConfiguration.Evaluators()),
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("construct resource quota controller: %w", err)
+	}
+
+	go quotaController.Run(workers, ctx.Done())
+	// the resources tracked by quotas may be added later, e.g. with CustomResourceDefinitions.
+	go quotaController.Sync(discoveryFunc, discoveryPeriod, ctx.Done())
+	informerFactory.Start(ctx.Done())
+	metadataInformers.Start(ctx.Done())
+	close(informersStarted)
+
+	return cancel, nil
+}
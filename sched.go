@@ -2,71 +2,204 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler"
+	"github.com/spf13/pflag"
 	"golang.org/x/xerrors"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 
 	"github.com/sanposhiho/mini-kube-scheduler/config"
 	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+	"github.com/sanposhiho/mini-kube-scheduler/kubelet"
+	"github.com/sanposhiho/mini-kube-scheduler/nodelifecycle"
 	"github.com/sanposhiho/mini-kube-scheduler/pvcontroller"
-	"github.com/sanposhiho/mini-kube-scheduler/scheduler/defaultconfig"
+	"github.com/sanposhiho/mini-kube-scheduler/resourcequotacontroller"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
+	"github.com/sanposhiho/mini-kube-scheduler/server"
+	"github.com/sanposhiho/mini-kube-scheduler/workloadcontroller"
 )
 
+// schedulerShutdownTimeout is how long to wait for the binding cycles in flight on shutdown.
+const schedulerShutdownTimeout = 15 * time.Second
+
 // entry point.
 func main() {
 	if err := start(); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
 		klog.Fatalf("failed with error on running scheduler: %+v", err)
 	}
 }
 
 // start starts scheduler and needed k8s components.
 func start() error {
-	cfg, err := config.NewConfig()
+	cfg, err := config.NewConfig(os.Args[1:])
 	if err != nil {
 		return xerrors.Errorf("get config: %w", err)
 	}
 
-	restclientCfg, apiShutdown, err := k8sapiserver.StartAPIServer(cfg.EtcdURL)
+	if err := setLogVerbosity(cfg.LogVerbosity); err != nil {
+		return xerrors.Errorf("set log verbosity: %w", err)
+	}
+
+	restclientCfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{
+		EtcdURL:          cfg.EtcdURL,
+		Address:          cfg.APIServerAddress,
+		TokenFile:        cfg.TokenFile,
+		AdmissionPlugins: cfg.AdmissionPlugins,
+	})
 	if err != nil {
 		return xerrors.Errorf("start API server: %w", err)
 	}
 	defer apiShutdown()
 
+	if cfg.APIServerAddress != "" {
+		if err := writeKubeconfig(restclientCfg, cfg.KubeconfigPath, cfg.TokenFile != ""); err != nil {
+			return xerrors.Errorf("write kubeconfig: %w", err)
+		}
+		defer os.Remove(cfg.KubeconfigPath)
+	}
+
 	client := clientset.NewForConfigOrDie(restclientCfg)
 
-	pvshutdown, err := pvcontroller.StartPersistentVolumeController(client)
+	pvshutdown, err := pvcontroller.StartPersistentVolumeController(client, pvcontroller.Options{TopologyKey: cfg.ProvisionerTopologyKey})
 	if err != nil {
 		return xerrors.Errorf("start pv controller: %w", err)
 	}
 	defer pvshutdown()
 
-	sched := scheduler.NewSchedulerService(client, restclientCfg)
+	rqshutdown, err := resourcequotacontroller.StartResourceQuotaController(client, restclientCfg)
+	if err != nil {
+		return xerrors.Errorf("start resource quota controller: %w", err)
+	}
+	defer rqshutdown()
+
+	controllers := cfg.Controllers
+	if controllers == nil {
+		controllers = workloadcontroller.AllControllers
+	}
+	wcshutdown, err := workloadcontroller.StartWorkloadControllers(client, restclientCfg, controllers)
+	if err != nil {
+		return xerrors.Errorf("start workload controllers: %w", err)
+	}
+	defer wcshutdown()
+
+	if cfg.FakeKubelet {
+		kubeletshutdown, err := kubelet.StartFakeKubelet(client, kubelet.Options{})
+		if err != nil {
+			return xerrors.Errorf("start fake kubelet: %w", err)
+		}
+		defer kubeletshutdown()
+	}
 
-	sc, err := defaultconfig.DefaultSchedulerConfig()
+	if cfg.NodeLifecycle {
+		nlshutdown, err := nodelifecycle.StartNodeLifecycleController(client, nodelifecycle.Options{})
+		if err != nil {
+			return xerrors.Errorf("start node lifecycle controller: %w", err)
+		}
+		defer nlshutdown()
+	}
+
+	resultOpts, dynamicClient, err := newResultOptions(cfg, restclientCfg)
 	if err != nil {
-		return xerrors.Errorf("create scheduler config")
+		return xerrors.Errorf("configure scheduling results: %w", err)
 	}
+	sched := scheduler.NewSchedulerService(client, restclientCfg, resultOpts...)
 
-	if err := sched.StartScheduler(sc); err != nil {
+	if err := sched.StartScheduler(cfg.SchedulerConfig); err != nil {
 		return xerrors.Errorf("start scheduler: %w", err)
 	}
-	defer sched.ShutdownScheduler()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), schedulerShutdownTimeout)
+		defer cancel()
+		if err := sched.ShutdownScheduler(ctx); err != nil {
+			klog.Errorf("failed to shutdown scheduler gracefully: %+v", err)
+		}
+	}()
+
+	srv := server.NewSimulatorServer(cfg, sched, client, dynamicClient)
+	shutdownServer := srv.Start(cfg.Port)
+	defer shutdownServer()
 
 	err = scenario(client)
 	if err != nil {
 		return xerrors.Errorf("start scenario: %w", err)
 	}
 
+	// keep the HTTP server and the simulated cluster running so that their state can be checked after the scenario.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	klog.Info("scenario finished; the simulator keeps running until interrupted")
+	<-ctx.Done()
+
+	return nil
+}
+
+// setLogVerbosity sets the verbosity of klog.
+func setLogVerbosity(v int) error {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	if err := fs.Set("v", strconv.Itoa(v)); err != nil {
+		return xerrors.Errorf("set klog flag v: %w", err)
+	}
+	return nil
+}
+
+// writeKubeconfig writes the kubeconfig to access the API server to path, and prints it
+// so that users can access the simulated cluster with kubectl, etc.
+// When the API server is secured, users pass their tokens with the kubeconfig.
+func writeKubeconfig(cfg *restclient.Config, path string, secured bool) error {
+	kubeconfig, err := k8sapiserver.Kubeconfig(cfg)
+	if err != nil {
+		return xerrors.Errorf("create kubeconfig: %w", err)
+	}
+	if err := ioutil.WriteFile(path, kubeconfig, 0o600); err != nil {
+		return xerrors.Errorf("write kubeconfig to %s: %w", path, err)
+	}
+
+	fmt.Printf("The API server is running on %s. The kubeconfig is written to %s:\n\n%s\n", cfg.Host, path, kubeconfig)
+	if secured {
+		fmt.Printf("Try: kubectl --kubeconfig %s --token <your token in the token file> get nodes\n", path)
+		return nil
+	}
+	fmt.Printf("Try: kubectl --kubeconfig %s get nodes\n", path)
 	return nil
 }
 
+// newResultOptions converts the settings of the scheduling results to the options of the result store.
+// It returns the dynamic client to access v1alpha1.SchedulingResult, which is nil when the results are recorded on the pod annotations.
+func newResultOptions(cfg *config.Config, restclientCfg *restclient.Config) ([]resultstore.Option, dynamic.Interface, error) {
+	verbosityOpt, err := resultstore.WithVerbosity(cfg.ResultVerbosity, cfg.ResultTopN)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("configure verbosity: %w", err)
+	}
+	opts := []resultstore.Option{verbosityOpt}
+
+	if cfg.ResultOutput != config.ResultOutputSchedulingResult {
+		return opts, nil, nil
+	}
+	dynamicClient, err := dynamic.NewForConfig(restclientCfg)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("create dynamic client: %w", err)
+	}
+	return append(opts, resultstore.WithSchedulingResultOutput(dynamicClient)), dynamicClient, nil
+}
+
 func scenario(client clientset.Interface) error {
 	ctx := context.Background()
 
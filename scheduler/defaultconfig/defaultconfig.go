@@ -1,6 +1,8 @@
 package defaultconfig
 
 import (
+	"io/ioutil"
+
 	"golang.org/x/xerrors"
 	"k8s.io/kube-scheduler/config/v1beta2"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
@@ -14,6 +16,26 @@ func DefaultSchedulerConfig() (*v1beta2.KubeSchedulerConfiguration, error) {
 	return &versionedCfg, nil
 }
 
+// LoadSchedulerConfig reads KubeSchedulerConfiguration from the file, and sets the default values as DefaultSchedulerConfig does.
+// The file is in YAML or JSON, and the older versions are converted to v1beta2.
+func LoadSchedulerConfig(path string) (*v1beta2.KubeSchedulerConfiguration, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("read scheduler config file: %w", err)
+	}
+
+	obj, gvk, err := scheme.Codecs.UniversalDecoder(v1beta2.SchemeGroupVersion).Decode(data, nil, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("decode scheduler config file: %w", err)
+	}
+	versionedCfg, ok := obj.(*v1beta2.KubeSchedulerConfiguration)
+	if !ok {
+		return nil, xerrors.Errorf("scheduler config file has %s instead of KubeSchedulerConfiguration", gvk)
+	}
+
+	return versionedCfg, nil
+}
+
 func DefaultFilterPlugins() ([]v1beta2.Plugin, error) {
 	defaultConfig, err := DefaultSchedulerConfig()
 	if err != nil || len(defaultConfig.Profiles) != 1 {
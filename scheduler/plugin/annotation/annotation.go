@@ -7,4 +7,9 @@ const (
 	ScoreResultAnnotationKey = "scheduler-simulator/score-result"
 	// FinalScoreResultAnnotationKey has the final score(= normalized and applied score plugin weight).
 	FinalScoreResultAnnotationKey = "scheduler-simulator/finalscore-result"
+	// FilterSummaryAnnotationKey has the filtering failures aggregated by reason.
+	// It's recorded only when the results are compacted.
+	FilterSummaryAnnotationKey = "scheduler-simulator/filter-summary"
+	// PermitResultAnnotationKey has the allows and the reject of the pod waiting in the permit phase.
+	PermitResultAnnotationKey = "scheduler-simulator/permit-result"
 )
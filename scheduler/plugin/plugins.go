@@ -21,7 +21,7 @@ import (
 
 //go:generate mockgen -destination=./mock/$GOFILE -source=$GOFILE
 
-func NewRegistry(informerFactory informers.SharedInformerFactory, client clientset.Interface) (map[string]schedulerRuntime.PluginFactory, error) {
+func NewRegistry(informerFactory informers.SharedInformerFactory, client clientset.Interface, opts ...schedulingresultstore.Option) (map[string]schedulerRuntime.PluginFactory, error) {
 	defaultScorePluginWeight := map[string]int32{}
 	defaultScorePlugin, err := defaultconfig.DefaultScorePlugins()
 	if err != nil {
@@ -39,7 +39,7 @@ func NewRegistry(informerFactory informers.SharedInformerFactory, client clients
 		return nil, xerrors.Errorf("get default score/filter plugins: %w", err)
 	}
 
-	store := schedulingresultstore.New(informerFactory, client, defaultScorePluginWeight)
+	store := schedulingresultstore.New(informerFactory, client, defaultScorePluginWeight, opts...)
 	rs := plugins.NewInTreeRegistry()
 	ret := map[string]schedulerRuntime.PluginFactory{}
 	for _, pl := range defaultpls {
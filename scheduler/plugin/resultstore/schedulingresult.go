@@ -0,0 +1,173 @@
+package resultstore
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1"
+	"github.com/sanposhiho/mini-kube-scheduler/util"
+)
+
+// applySchedulingResult creates or updates v1alpha1.SchedulingResult for the pod.
+// It returns false if Store doesn't have scheduling result of the pod.
+func (s *Store) applySchedulingResult(ctx context.Context, pod *v1.Pod) (bool, error) {
+	sr, ok, err := s.newSchedulingResult(pod)
+	if err != nil {
+		return false, xerrors.Errorf("create SchedulingResult: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(sr)
+	if err != nil {
+		return false, xerrors.Errorf("convert SchedulingResult to unstructured: %w", err)
+	}
+	desired := &unstructured.Unstructured{Object: obj}
+
+	client := s.dynamicClient.Resource(v1alpha1.SchedulingResultsResource).Namespace(pod.Namespace)
+	applyFunc := func() error {
+		_, err := client.Create(ctx, desired, metav1.CreateOptions{})
+		if err == nil {
+			return nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return xerrors.Errorf("create SchedulingResult: %w", err)
+		}
+
+		// SchedulingResult remains from the previous scheduling of the pod.
+		current, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return xerrors.Errorf("get SchedulingResult: %w", err)
+		}
+		updated := desired.DeepCopy()
+		updated.SetResourceVersion(current.GetResourceVersion())
+		if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			return xerrors.Errorf("update SchedulingResult: %w", err)
+		}
+
+		return nil
+	}
+	if err := util.RetryOnErrorWithExponentialBackOff(isRetriableError, applyFunc); err != nil {
+		return false, xerrors.Errorf("apply SchedulingResult with retry: %w", err)
+	}
+
+	return true, nil
+}
+
+// newSchedulingResult converts the scheduling result of the pod on Store to v1alpha1.SchedulingResult.
+// It returns false if Store doesn't have scheduling result of the pod.
+func (s *Store) newSchedulingResult(pod *v1.Pod) (*v1alpha1.SchedulingResult, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.results[newKey(pod.Namespace, pod.Name)]
+	if !ok {
+		return nil, false, nil
+	}
+	r, summary := s.compact(r)
+
+	nodeNames := map[string]struct{}{}
+	for _, m := range []map[string]map[string]string{r.filter, r.score, r.finalscore} {
+		for n := range m {
+			nodeNames[n] = struct{}{}
+		}
+	}
+	sortedNodeNames := make([]string, 0, len(nodeNames))
+	for n := range nodeNames {
+		sortedNodeNames = append(sortedNodeNames, n)
+	}
+	sort.Strings(sortedNodeNames)
+
+	nodes := make([]v1alpha1.NodeResult, 0, len(sortedNodeNames))
+	for _, n := range sortedNodeNames {
+		nr := v1alpha1.NodeResult{NodeName: n}
+
+		for _, pl := range r.filterPlugins {
+			reason, ok := r.filter[n][pl]
+			if !ok {
+				continue
+			}
+			fr := v1alpha1.FilterResult{Plugin: pl, Passed: reason == PassedFilterMessage}
+			if !fr.Passed {
+				fr.Reason = reason
+			}
+			nr.Filter = append(nr.Filter, fr)
+		}
+
+		for _, pl := range r.scorePlugins {
+			score, scoreOK := r.score[n][pl]
+			finalscore, finalscoreOK := r.finalscore[n][pl]
+			if !scoreOK && !finalscoreOK {
+				continue
+			}
+			sr := v1alpha1.ScoreResult{Plugin: pl}
+			if scoreOK {
+				i, err := strconv.ParseInt(score, 10, 64)
+				if err != nil {
+					return nil, false, xerrors.Errorf("parse score of plugin %s on node %s: %w", pl, n, err)
+				}
+				sr.Score = i
+			}
+			if finalscoreOK {
+				i, err := strconv.ParseInt(finalscore, 10, 64)
+				if err != nil {
+					return nil, false, xerrors.Errorf("parse final score of plugin %s on node %s: %w", pl, n, err)
+				}
+				sr.FinalScore = i
+			}
+			nr.Score = append(nr.Score, sr)
+		}
+
+		nodes = append(nodes, nr)
+	}
+
+	var permit []v1alpha1.PermitDecision
+	for _, d := range r.permit {
+		permit = append(permit, v1alpha1.PermitDecision{
+			Plugin:  d.Plugin,
+			Allowed: d.Allowed,
+			Message: d.Message,
+			Time:    metav1.NewTime(d.Time),
+		})
+	}
+
+	return &v1alpha1.SchedulingResult{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "SchedulingResult",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       pod.Name,
+					UID:        pod.UID,
+				},
+			},
+		},
+		Spec: v1alpha1.SchedulingResultSpec{
+			PodRef: v1alpha1.PodReference{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				UID:       pod.UID,
+			},
+			FilterPlugins: append([]string(nil), r.filterPlugins...),
+			ScorePlugins:  append([]string(nil), r.scorePlugins...),
+			Nodes:         nodes,
+			Summary:       summary,
+			Permit:        permit,
+		},
+	}, true, nil
+}
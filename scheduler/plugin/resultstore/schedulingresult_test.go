@@ -0,0 +1,255 @@
+package resultstore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+func TestStore_newSchedulingResult(t *testing.T) {
+	t.Parallel()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+			UID:       "uid1",
+		},
+	}
+	permitTime := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		result  map[key]*result
+		want    *v1alpha1.SchedulingResult
+		wantOK  bool
+		wantErr bool
+	}{
+		{
+			name: "success",
+			result: map[key]*result{
+				"default/pod1": {
+					filter: map[string]map[string]string{
+						"node1": {
+							"plugin1": PassedFilterMessage,
+							"plugin2": "node(s) were unschedulable",
+						},
+						"node0": {
+							"plugin1": PassedFilterMessage,
+							"plugin2": PassedFilterMessage,
+						},
+					},
+					score: map[string]map[string]string{
+						"node0": {
+							"plugin3": "10",
+						},
+					},
+					finalscore: map[string]map[string]string{
+						"node0": {
+							"plugin3": "20",
+						},
+					},
+					filterPlugins: []string{"plugin2", "plugin1"},
+					scorePlugins:  []string{"plugin3"},
+				},
+			},
+			want: &v1alpha1.SchedulingResult{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "simulator.mini-kube-scheduler.io/v1alpha1",
+					Kind:       "SchedulingResult",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "v1", Kind: "Pod", Name: "pod1", UID: "uid1"},
+					},
+				},
+				Spec: v1alpha1.SchedulingResultSpec{
+					PodRef:        v1alpha1.PodReference{Namespace: "default", Name: "pod1", UID: "uid1"},
+					FilterPlugins: []string{"plugin2", "plugin1"},
+					ScorePlugins:  []string{"plugin3"},
+					Nodes: []v1alpha1.NodeResult{
+						{
+							NodeName: "node0",
+							Filter: []v1alpha1.FilterResult{
+								{Plugin: "plugin2", Passed: true},
+								{Plugin: "plugin1", Passed: true},
+							},
+							Score: []v1alpha1.ScoreResult{
+								{Plugin: "plugin3", Score: 10, FinalScore: 20},
+							},
+						},
+						{
+							NodeName: "node1",
+							Filter: []v1alpha1.FilterResult{
+								{Plugin: "plugin2", Passed: false, Reason: "node(s) were unschedulable"},
+								{Plugin: "plugin1", Passed: true},
+							},
+						},
+					},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name: "success with permit history",
+			result: map[key]*result{
+				"default/pod1": {
+					filter:     map[string]map[string]string{},
+					score:      map[string]map[string]string{},
+					finalscore: map[string]map[string]string{},
+					permit: []waitingpod.Decision{
+						{Plugin: "plugin4", Allowed: true, Time: permitTime},
+						{Plugin: "plugin5", Allowed: false, Message: "rejected", Time: permitTime},
+					},
+				},
+			},
+			want: &v1alpha1.SchedulingResult{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "simulator.mini-kube-scheduler.io/v1alpha1",
+					Kind:       "SchedulingResult",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{
+						{APIVersion: "v1", Kind: "Pod", Name: "pod1", UID: "uid1"},
+					},
+				},
+				Spec: v1alpha1.SchedulingResultSpec{
+					PodRef: v1alpha1.PodReference{Namespace: "default", Name: "pod1", UID: "uid1"},
+					Nodes:  []v1alpha1.NodeResult{},
+					Permit: []v1alpha1.PermitDecision{
+						{Plugin: "plugin4", Allowed: true, Time: metav1.NewTime(permitTime)},
+						{Plugin: "plugin5", Allowed: false, Message: "rejected", Time: metav1.NewTime(permitTime)},
+					},
+				},
+			},
+			wantOK: true,
+		},
+		{
+			name:   "return false if store doesn't have data",
+			result: map[key]*result{},
+			wantOK: false,
+		},
+		{
+			name: "fail if score is not a number",
+			result: map[key]*result{
+				"default/pod1": {
+					filter: map[string]map[string]string{},
+					score: map[string]map[string]string{
+						"node0": {
+							"plugin3": "ten",
+						},
+					},
+					finalscore:   map[string]map[string]string{},
+					scorePlugins: []string{"plugin3"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &Store{
+				mu:      new(sync.Mutex),
+				results: tt.result,
+			}
+			got, ok, err := s.newSchedulingResult(pod)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSchedulingResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStore_addSchedulingResultToPod_withSchedulingResultOutput(t *testing.T) {
+	t.Parallel()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+			UID:       "uid2",
+		},
+	}
+	tests := []struct {
+		name     string
+		existing []runtime.Object
+	}{
+		{
+			name: "create SchedulingResult",
+		},
+		{
+			name: "update SchedulingResult remaining from the previous pod",
+			existing: []runtime.Object{
+				&v1alpha1.SchedulingResult{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+					Spec: v1alpha1.SchedulingResultSpec{
+						PodRef: v1alpha1.PodReference{Namespace: "default", Name: "pod1", UID: "uid1"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			c := dynamicfake.NewSimpleDynamicClient(scheme, tt.existing...)
+			s := &Store{
+				mu: new(sync.Mutex),
+				results: map[key]*result{
+					"default/pod1": {
+						filter: map[string]map[string]string{
+							"node0": {
+								"plugin1": PassedFilterMessage,
+							},
+						},
+						score:         map[string]map[string]string{},
+						finalscore:    map[string]map[string]string{},
+						filterPlugins: []string{"plugin1"},
+					},
+				},
+				dynamicClient: c,
+			}
+			s.addSchedulingResultToPod(nil, pod)
+
+			u, err := c.Resource(v1alpha1.SchedulingResultsResource).Namespace("default").Get(context.Background(), "pod1", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("get SchedulingResult: %v", err)
+			}
+			var got v1alpha1.SchedulingResult
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &got); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, v1alpha1.PodReference{Namespace: "default", Name: "pod1", UID: "uid2"}, got.Spec.PodRef)
+			assert.Equal(t, []v1alpha1.NodeResult{
+				{
+					NodeName: "node0",
+					Filter:   []v1alpha1.FilterResult{{Plugin: "plugin1", Passed: true}},
+				},
+			}, got.Spec.Nodes)
+
+			if _, ok := s.results["default/pod1"]; ok {
+				t.Fatal("result should be deleted")
+			}
+		})
+	}
+}
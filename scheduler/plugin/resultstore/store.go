@@ -10,23 +10,49 @@ import (
 
 	"golang.org/x/xerrors"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/annotation"
 )
 
 // Store has results of scheduling.
 // It manages all scheduling results and reflects all results on the pod annotation when the scheduling is finished.
+// When it's configured with WithSchedulingResultOutput, it reflects results on v1alpha1.SchedulingResult instead.
 type Store struct {
 	mu *sync.Mutex
 
 	client            clientset.Interface
 	results           map[key]*result
 	scorePluginWeight map[string]int32
+
+	// dynamicClient is used to record results as v1alpha1.SchedulingResult.
+	// It's nil when results are recorded on the pod annotation.
+	dynamicClient dynamic.Interface
+
+	// verbosity is how much of the results Store records.
+	// The results are recorded as VerbosityFull if it's empty.
+	verbosity Verbosity
+	// topN is the number of nodes whose results are recorded with VerbosityTopN.
+	topN int
+}
+
+// Option configures Store.
+type Option func(*Store)
+
+// WithSchedulingResultOutput makes Store record results as v1alpha1.SchedulingResult objects instead of the pod annotations.
+// The annotations hit the size limit on clusters with many nodes.
+func WithSchedulingResultOutput(client dynamic.Interface) Option {
+	return func(s *Store) {
+		s.dynamicClient = client
+	}
 }
 
 const (
@@ -47,15 +73,25 @@ type result struct {
 	// When node pass the filter, filtering result will be PassedFilterMessage.
 	// When node blocked by the filter, filtering result is blocked reason.
 	filter map[string]map[string]string
+
+	// permit has the allows and the reject of the pod waiting in the permit phase.
+	permit []waitingpod.Decision
+
+	// filterPlugins and scorePlugins have plugin names in the order they ran for the pod.
+	filterPlugins []string
+	scorePlugins  []string
 }
 
-func New(informerFactory informers.SharedInformerFactory, client clientset.Interface, scorePluginWeight map[string]int32) *Store {
+func New(informerFactory informers.SharedInformerFactory, client clientset.Interface, scorePluginWeight map[string]int32, opts ...Option) *Store {
 	s := &Store{
 		mu:                new(sync.Mutex),
 		client:            client,
 		results:           map[key]*result{},
 		scorePluginWeight: scorePluginWeight,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
 	// Store adds scheduling results when pod is updating.
 	// This is because scheduling framework doesn’t have any phase to hook scheduling finished. (both successfully and non-successfully)
@@ -87,6 +123,12 @@ func newData() *result {
 	return d
 }
 
+// Flush records the scheduling result of the pod now.
+// The scheduler calls it when the scheduling of the pod fails, since the pod may not be updated until the next try.
+func (s *Store) Flush(pod *v1.Pod) {
+	s.addSchedulingResultToPod(nil, pod)
+}
+
 func (s *Store) addSchedulingResultToPod(_, newObj interface{}) {
 	ctx := context.Background()
 
@@ -97,74 +139,125 @@ func (s *Store) addSchedulingResultToPod(_, newObj interface{}) {
 	}
 
 	k := newKey(pod.Namespace, pod.Name)
-	if _, ok := s.results[k]; !ok {
-		// Store doesn't have scheduling result of pod.
-		return
-	}
 
-	if err := s.addFilterResultToPod(pod); err != nil {
-		klog.Errorf("failed to add filtering result to pod: %+v", err)
+	var (
+		recorded bool
+		err      error
+	)
+	if s.dynamicClient != nil {
+		recorded, err = s.applySchedulingResult(ctx, pod)
+	} else {
+		recorded, err = s.patchResultAnnotations(ctx, pod)
+	}
+	if err != nil {
+		klog.Errorf("failed to record scheduling result of pod %s: %+v", k, err)
 		return
 	}
-
-	if err := s.addScoreResultToPod(pod); err != nil {
-		klog.Errorf("failed to add scoring result to pod: %+v", err)
+	if !recorded {
+		// Store doesn't have scheduling result of pod.
 		return
 	}
 
-	if err := s.addFinalScoreResultToPod(pod); err != nil {
-		klog.Errorf("failed to add final score result to pod: %+v", err)
-		return
+	// delete data from Store only if data is successfully recorded.
+	s.DeleteData(k)
+}
+
+// patchResultAnnotations adds the scheduling result to the pod annotations.
+// It returns false if Store doesn't have scheduling result of the pod.
+func (s *Store) patchResultAnnotations(ctx context.Context, pod *v1.Pod) (bool, error) {
+	patch, ok, err := s.newResultPatch(newKey(pod.Namespace, pod.Name))
+	if err != nil {
+		return false, xerrors.Errorf("create patch to record scheduling result: %w", err)
+	}
+	if !ok {
+		return false, nil
 	}
 
-	updateFunc := func() (bool, error) {
-		_, err := s.client.CoreV1().Pods(pod.Namespace).Update(ctx, pod, metav1.UpdateOptions{})
+	// We use patch instead of update so that we don't have to touch the pod object on the informer's cache,
+	// and so that the request isn't conflicted with other updates to the pod. (e.g. binding, status updates)
+	patchFunc := func() error {
+		_, err := s.client.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
 		if err != nil {
-			return false, xerrors.Errorf("update pod: %v", err)
+			return xerrors.Errorf("patch pod: %w", err)
 		}
 
-		return true, nil
+		return nil
 	}
-	if err := util.RetryWithExponentialBackOff(updateFunc); err != nil {
-		klog.Errorf("failed to update pod with retry to record score: %+v", err)
-		return
+	if err := util.RetryOnErrorWithExponentialBackOff(isRetriableError, patchFunc); err != nil {
+		return false, xerrors.Errorf("patch pod with retry to record score: %w", err)
 	}
 
-	// delete data from Store only if data is successfully added on pod's annotations.
-	s.DeleteData(k)
+	return true, nil
 }
 
-func (s *Store) addFilterResultToPod(pod *v1.Pod) error {
-	k := newKey(pod.Namespace, pod.Name)
-	scores, err := json.Marshal(s.results[k].filter)
-	if err != nil {
-		return xerrors.Errorf("encode json to record scores: %w", err)
-	}
-
-	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, annotation.FilterResultAnnotationKey, string(scores))
-	return nil
+// isRetriableError reports whether the request to record scheduling results may succeed by retrying.
+func isRetriableError(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err)
 }
 
-func (s *Store) addScoreResultToPod(pod *v1.Pod) error {
-	k := newKey(pod.Namespace, pod.Name)
-	scores, err := json.Marshal(s.results[k].score)
-	if err != nil {
-		return xerrors.Errorf("encode json to record scores: %w", err)
+// newResultPatch creates the strategic merge patch to add scheduling results to pod annotations.
+// It returns false if Store doesn't have scheduling result of the pod.
+func (s *Store) newResultPatch(k key) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.results[k]
+	if !ok {
+		return nil, false, nil
 	}
+	r, summary := s.compact(r)
+
+	annotations := map[string]interface{}{}
+	if s.verbosity == VerbositySummary {
+		// remove the results recorded with other verbosity.
+		annotations[annotation.FilterResultAnnotationKey] = nil
+		annotations[annotation.ScoreResultAnnotationKey] = nil
+		annotations[annotation.FinalScoreResultAnnotationKey] = nil
+	} else {
+		filter, err := json.Marshal(r.filter)
+		if err != nil {
+			return nil, false, xerrors.Errorf("encode json to record filtering results: %w", err)
+		}
+		annotations[annotation.FilterResultAnnotationKey] = string(filter)
 
-	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, annotation.ScoreResultAnnotationKey, string(scores))
-	return nil
-}
+		score, err := json.Marshal(r.score)
+		if err != nil {
+			return nil, false, xerrors.Errorf("encode json to record scores: %w", err)
+		}
+		annotations[annotation.ScoreResultAnnotationKey] = string(score)
 
-func (s *Store) addFinalScoreResultToPod(pod *v1.Pod) error {
-	k := newKey(pod.Namespace, pod.Name)
-	scores, err := json.Marshal(s.results[k].finalscore)
+		finalscore, err := json.Marshal(r.finalscore)
+		if err != nil {
+			return nil, false, xerrors.Errorf("encode json to record final scores: %w", err)
+		}
+		annotations[annotation.FinalScoreResultAnnotationKey] = string(finalscore)
+	}
+	if summary != "" {
+		annotations[annotation.FilterSummaryAnnotationKey] = summary
+	}
+	if len(r.permit) != 0 {
+		permit, err := json.Marshal(r.permit)
+		if err != nil {
+			return nil, false, xerrors.Errorf("encode json to record permit results: %w", err)
+		}
+		annotations[annotation.PermitResultAnnotationKey] = string(permit)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
 	if err != nil {
-		return xerrors.Errorf("encode json to record scores: %w", err)
+		return nil, false, xerrors.Errorf("encode json to create patch: %w", err)
 	}
 
-	metav1.SetMetaDataAnnotation(&pod.ObjectMeta, annotation.FinalScoreResultAnnotationKey, string(scores))
-	return nil
+	return patch, true, nil
 }
 
 // AddFilterResult adds filtering result to pod annotation.
@@ -181,6 +274,8 @@ func (s *Store) AddFilterResult(namespace, podName, nodeName, pluginName, reason
 		s.results[k].filter[nodeName] = map[string]string{}
 	}
 
+	s.results[k].filterPlugins = appendIfMissing(s.results[k].filterPlugins, pluginName)
+
 	s.results[k].filter[nodeName][pluginName] = reason
 }
 
@@ -212,6 +307,39 @@ func (s *Store) AddNormalizedScoreResult(namespace, podName, nodeName, pluginNam
 	s.addNormalizedScoreResultWithoutLock(namespace, podName, nodeName, pluginName, normalizedscore)
 }
 
+// AddFinalScoreResult adds final score result to pod annotation.
+// Unlike AddNormalizedScoreResult, the weight has been already applied to finalscore by the scheduler.
+func (s *Store) AddFinalScoreResult(namespace, podName, nodeName, pluginName string, finalscore int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := newKey(namespace, podName)
+	if _, ok := s.results[k]; !ok {
+		s.results[k] = newData()
+	}
+
+	if _, ok := s.results[k].finalscore[nodeName]; !ok {
+		s.results[k].finalscore[nodeName] = map[string]string{}
+	}
+
+	s.results[k].scorePlugins = appendIfMissing(s.results[k].scorePlugins, pluginName)
+
+	s.results[k].finalscore[nodeName][pluginName] = strconv.FormatInt(finalscore, 10)
+}
+
+// AddPermitResult adds the allows and the reject of the waiting pod to pod annotation.
+func (s *Store) AddPermitResult(namespace, podName string, history []waitingpod.Decision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := newKey(namespace, podName)
+	if _, ok := s.results[k]; !ok {
+		s.results[k] = newData()
+	}
+
+	s.results[k].permit = append([]waitingpod.Decision(nil), history...)
+}
+
 func (s *Store) addNormalizedScoreResultWithoutLock(namespace, podName, nodeName, pluginName string, normalizedscore int64) {
 	k := newKey(namespace, podName)
 	if _, ok := s.results[k]; !ok {
@@ -222,6 +350,8 @@ func (s *Store) addNormalizedScoreResultWithoutLock(namespace, podName, nodeName
 		s.results[k].finalscore[nodeName] = map[string]string{}
 	}
 
+	s.results[k].scorePlugins = appendIfMissing(s.results[k].scorePlugins, pluginName)
+
 	finalscore := s.applyWeightOnScore(pluginName, normalizedscore)
 
 	// apply weight to calculate final score.
@@ -233,6 +363,19 @@ func (s *Store) applyWeightOnScore(pluginName string, score int64) int64 {
 	return score * int64(weight)
 }
 
+// appendIfMissing appends the name to names if names doesn't have it yet.
+func appendIfMissing(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
 func (s *Store) DeleteData(k key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	delete(s.results, k)
 }
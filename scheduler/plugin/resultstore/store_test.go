@@ -3,13 +3,17 @@ package resultstore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/annotation"
 )
@@ -48,6 +52,7 @@ func TestStore_AddFilterResult(t *testing.T) {
 							"plugin1": PassedFilterMessage,
 						},
 					},
+					filterPlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -62,6 +67,7 @@ func TestStore_AddFilterResult(t *testing.T) {
 							"plugin1": PassedFilterMessage,
 						},
 					},
+					filterPlugins: []string{"plugin1"},
 				},
 			},
 			args: args{
@@ -81,6 +87,7 @@ func TestStore_AddFilterResult(t *testing.T) {
 							"plugin2": PassedFilterMessage,
 						},
 					},
+					filterPlugins: []string{"plugin1", "plugin2"},
 				},
 			},
 		},
@@ -95,6 +102,7 @@ func TestStore_AddFilterResult(t *testing.T) {
 							"plugin1": PassedFilterMessage,
 						},
 					},
+					filterPlugins: []string{"plugin1"},
 				},
 			},
 			args: args{
@@ -116,6 +124,51 @@ func TestStore_AddFilterResult(t *testing.T) {
 							"plugin1": PassedFilterMessage,
 						},
 					},
+					filterPlugins: []string{"plugin1"},
+				},
+			},
+		},
+		{
+			name: "success without the plugins of other pods",
+			resultbefore: map[key]*result{
+				"default/pod0": {
+					score:      map[string]map[string]string{},
+					finalscore: map[string]map[string]string{},
+					filter: map[string]map[string]string{
+						"node1": {
+							"plugin0": PassedFilterMessage,
+						},
+					},
+					filterPlugins: []string{"plugin0"},
+				},
+			},
+			args: args{
+				namespace:  "default",
+				podName:    "pod1",
+				nodeName:   "node1",
+				pluginName: "plugin1",
+				reason:     PassedFilterMessage,
+			},
+			wantResultMap: map[key]*result{
+				"default/pod0": {
+					score:      map[string]map[string]string{},
+					finalscore: map[string]map[string]string{},
+					filter: map[string]map[string]string{
+						"node1": {
+							"plugin0": PassedFilterMessage,
+						},
+					},
+					filterPlugins: []string{"plugin0"},
+				},
+				"default/pod1": {
+					score:      map[string]map[string]string{},
+					finalscore: map[string]map[string]string{},
+					filter: map[string]map[string]string{
+						"node1": {
+							"plugin1": PassedFilterMessage,
+						},
+					},
+					filterPlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -174,6 +227,7 @@ func TestStore_AddScoreResult(t *testing.T) {
 							"plugin1": "10",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -192,6 +246,7 @@ func TestStore_AddScoreResult(t *testing.T) {
 							"plugin1": "10",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 			scorePluginWeight: map[string]int32{"plugin2": 2},
@@ -217,6 +272,7 @@ func TestStore_AddScoreResult(t *testing.T) {
 							"plugin2": "10",
 						},
 					},
+					scorePlugins: []string{"plugin1", "plugin2"},
 				},
 			},
 		},
@@ -235,6 +291,7 @@ func TestStore_AddScoreResult(t *testing.T) {
 							"plugin1": "10",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 			scorePluginWeight: map[string]int32{"plugin1": 2},
@@ -264,6 +321,7 @@ func TestStore_AddScoreResult(t *testing.T) {
 							"plugin1": "10",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -319,6 +377,7 @@ func TestStore_AddNormalizedScoreResult(t *testing.T) {
 							"plugin1": "20",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -332,6 +391,7 @@ func TestStore_AddNormalizedScoreResult(t *testing.T) {
 							"plugin1": "30",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 			scorePluginWeight: map[string]int32{"plugin2": 2},
@@ -351,6 +411,7 @@ func TestStore_AddNormalizedScoreResult(t *testing.T) {
 							"plugin2": "20",
 						},
 					},
+					scorePlugins: []string{"plugin1", "plugin2"},
 				},
 			},
 		},
@@ -364,6 +425,7 @@ func TestStore_AddNormalizedScoreResult(t *testing.T) {
 							"plugin1": "20",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 			scorePluginWeight: map[string]int32{"plugin1": 2},
@@ -385,6 +447,7 @@ func TestStore_AddNormalizedScoreResult(t *testing.T) {
 							"plugin1": "20",
 						},
 					},
+					scorePlugins: []string{"plugin1"},
 				},
 			},
 		},
@@ -595,6 +658,66 @@ func TestStore_addSchedulingResultToPod(t *testing.T) {
 			},
 			resultRemainsAfterExecFunc: false,
 		},
+		{
+			name: "success with retry when the patch is conflicted",
+			result: map[key]*result{
+				"default/pod1": {
+					score:      map[string]map[string]string{},
+					finalscore: map[string]map[string]string{},
+					filter: map[string]map[string]string{
+						"node0": {
+							"plugin1": PassedFilterMessage,
+						},
+					},
+				},
+			},
+			prepareFakeClientSetFn: func() *fake.Clientset {
+				c := fake.NewSimpleClientset()
+				c.CoreV1().Pods(namespace).Create(context.Background(), &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      podName,
+						Namespace: namespace,
+					},
+				}, metav1.CreateOptions{})
+
+				conflicted := false
+				c.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if conflicted {
+						return false, nil, nil
+					}
+					conflicted = true
+					return true, nil, apierrors.NewConflict(corev1.Resource("pods"), podName, errors.New("conflicted"))
+				})
+
+				return c
+			},
+			newObj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      podName,
+					Namespace: namespace,
+				},
+			},
+			wantpod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      podName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						annotation.FilterResultAnnotationKey: func() string {
+							r := map[string]map[string]string{
+								"node0": {
+									"plugin1": PassedFilterMessage,
+								},
+							}
+							d, _ := json.Marshal(r)
+							return string(d)
+						}(),
+						annotation.ScoreResultAnnotationKey:      "{}",
+						annotation.FinalScoreResultAnnotationKey: "{}",
+					},
+				},
+			},
+			resultRemainsAfterExecFunc: false,
+		},
 		{
 			name: "fail if client failed to update the pod",
 			result: map[key]*result{
@@ -648,7 +771,10 @@ func TestStore_addSchedulingResultToPod(t *testing.T) {
 				results: tt.result,
 				client:  c,
 			}
+			newObjBefore := tt.newObj.(*corev1.Pod).DeepCopy()
 			s.addSchedulingResultToPod(nil, tt.newObj)
+			// the object on the informer's cache must not be modified.
+			assert.Equal(t, newObjBefore, tt.newObj)
 
 			if !tt.wanterr {
 				p, _ := c.CoreV1().Pods(namespace).Get(context.Background(), podName, metav1.GetOptions{})
@@ -0,0 +1,144 @@
+package resultstore
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Verbosity is how much of the scheduling results Store records.
+type Verbosity string
+
+const (
+	// VerbosityFull records the results of all plugins on all nodes.
+	VerbosityFull Verbosity = "Full"
+	// VerbosityTopN records the results on the top-N nodes by final score,
+	// and aggregates the filtering failures on all nodes by reason.
+	VerbosityTopN Verbosity = "TopN"
+	// VerbositySummary records only the filtering failures aggregated by reason.
+	VerbositySummary Verbosity = "Summary"
+)
+
+// WithVerbosity configures how much of the scheduling results Store records.
+// The results of all plugins on all nodes grow to megabytes on large clusters.
+// topN is the number of nodes whose results are recorded with VerbosityTopN.
+// It returns an error if v is unknown or topN is negative.
+func WithVerbosity(v Verbosity, topN int) (Option, error) {
+	switch v {
+	case VerbosityFull, VerbosityTopN, VerbositySummary:
+	default:
+		return nil, xerrors.Errorf("unknown verbosity %q, must be one of %v", v, []Verbosity{VerbosityFull, VerbosityTopN, VerbositySummary})
+	}
+	if topN < 0 {
+		return nil, xerrors.Errorf("topN must not be negative, got %d", topN)
+	}
+
+	return func(s *Store) {
+		s.verbosity = v
+		s.topN = topN
+	}, nil
+}
+
+// compact trims the result according to the verbosity of Store.
+// It also returns the summary of filtering results, which is empty with VerbosityFull.
+//
+// NOTE: this function assumes lock has been acquired in caller.
+func (s *Store) compact(r *result) (*result, string) {
+	switch s.verbosity {
+	case VerbosityTopN:
+		ret := newData()
+		for _, n := range topNodes(r.finalscore, s.topN) {
+			if m, ok := r.filter[n]; ok {
+				ret.filter[n] = m
+			}
+			if m, ok := r.score[n]; ok {
+				ret.score[n] = m
+			}
+			if m, ok := r.finalscore[n]; ok {
+				ret.finalscore[n] = m
+			}
+		}
+		ret.permit = r.permit
+		ret.filterPlugins = r.filterPlugins
+		ret.scorePlugins = r.scorePlugins
+		return ret, summarizeFilterResult(r.filter)
+	case VerbositySummary:
+		// the permit results are always recorded since they don't grow with the nodes.
+		ret := newData()
+		ret.permit = r.permit
+		ret.filterPlugins = r.filterPlugins
+		ret.scorePlugins = r.scorePlugins
+		return ret, summarizeFilterResult(r.filter)
+	default:
+		return r, ""
+	}
+}
+
+// topNodes returns at most n node names in descending order of the total final score.
+func topNodes(finalscore map[string]map[string]string, n int) []string {
+	type nodeScore struct {
+		name  string
+		score int64
+	}
+	scores := make([]nodeScore, 0, len(finalscore))
+	for node, pluginScores := range finalscore {
+		var total int64
+		for _, sc := range pluginScores {
+			// finalscore is always recorded by strconv.FormatInt.
+			i, _ := strconv.ParseInt(sc, 10, 64)
+			total += i
+		}
+		scores = append(scores, nodeScore{name: node, score: total})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score != scores[j].score {
+			return scores[i].score > scores[j].score
+		}
+		return scores[i].name < scores[j].name
+	})
+
+	if n < len(scores) {
+		scores = scores[:n]
+	}
+	ret := make([]string, 0, len(scores))
+	for _, sc := range scores {
+		ret = append(ret, sc.name)
+	}
+	return ret
+}
+
+// summarizeFilterResult aggregates the filtering failures by reason.
+// The message format mirrors framework.FitError's one. e.g. "2/5000 nodes are available: 4998 node(s) were unschedulable."
+func summarizeFilterResult(filter map[string]map[string]string) string {
+	available := 0
+	reasons := map[string]int{}
+	for _, pluginResults := range filter {
+		passed := true
+		for _, reason := range pluginResults {
+			if reason == PassedFilterMessage {
+				continue
+			}
+			passed = false
+			reasons[reason]++
+		}
+		if passed {
+			available++
+		}
+	}
+
+	msg := fmt.Sprintf("%d/%d nodes are available", available, len(filter))
+	if len(reasons) == 0 {
+		return msg + "."
+	}
+
+	reasonStrings := make([]string, 0, len(reasons))
+	for k, v := range reasons {
+		reasonStrings = append(reasonStrings, fmt.Sprintf("%v %v", v, k))
+	}
+	sort.Strings(reasonStrings)
+	return fmt.Sprintf("%s: %s.", msg, strings.Join(reasonStrings, ", "))
+}
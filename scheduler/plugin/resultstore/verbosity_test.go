@@ -0,0 +1,222 @@
+package resultstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_compact(t *testing.T) {
+	t.Parallel()
+	r := &result{
+		filter: map[string]map[string]string{
+			"node0": {
+				"plugin1": PassedFilterMessage,
+			},
+			"node1": {
+				"plugin1": PassedFilterMessage,
+			},
+			"node2": {
+				"plugin1": PassedFilterMessage,
+			},
+			"node3": {
+				"plugin1": "node(s) were unschedulable",
+			},
+		},
+		score: map[string]map[string]string{
+			"node0": {
+				"plugin2": "1",
+				"plugin3": "1",
+			},
+			"node1": {
+				"plugin2": "5",
+				"plugin3": "5",
+			},
+			"node2": {
+				"plugin2": "3",
+				"plugin3": "3",
+			},
+		},
+		finalscore: map[string]map[string]string{
+			"node0": {
+				"plugin2": "1",
+				"plugin3": "1",
+			},
+			"node1": {
+				"plugin2": "5",
+				"plugin3": "5",
+			},
+			"node2": {
+				"plugin2": "3",
+				"plugin3": "3",
+			},
+		},
+	}
+	tests := []struct {
+		name        string
+		verbosity   Verbosity
+		topN        int
+		want        *result
+		wantSummary string
+	}{
+		{
+			name:      "empty verbosity records all results",
+			verbosity: "",
+			want:      r,
+		},
+		{
+			name:      "VerbosityFull records all results",
+			verbosity: VerbosityFull,
+			want:      r,
+		},
+		{
+			name:      "VerbosityTopN records results on top-N nodes",
+			verbosity: VerbosityTopN,
+			topN:      2,
+			want: &result{
+				filter: map[string]map[string]string{
+					"node1": {
+						"plugin1": PassedFilterMessage,
+					},
+					"node2": {
+						"plugin1": PassedFilterMessage,
+					},
+				},
+				score: map[string]map[string]string{
+					"node1": {
+						"plugin2": "5",
+						"plugin3": "5",
+					},
+					"node2": {
+						"plugin2": "3",
+						"plugin3": "3",
+					},
+				},
+				finalscore: map[string]map[string]string{
+					"node1": {
+						"plugin2": "5",
+						"plugin3": "5",
+					},
+					"node2": {
+						"plugin2": "3",
+						"plugin3": "3",
+					},
+				},
+			},
+			wantSummary: "3/4 nodes are available: 1 node(s) were unschedulable.",
+		},
+		{
+			name:        "VerbositySummary records no results",
+			verbosity:   VerbositySummary,
+			want:        newData(),
+			wantSummary: "3/4 nodes are available: 1 node(s) were unschedulable.",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &Store{
+				verbosity: tt.verbosity,
+				topN:      tt.topN,
+			}
+			got, summary := s.compact(r)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSummary, summary)
+		})
+	}
+}
+
+func TestWithVerbosity(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		verbosity Verbosity
+		topN      int
+		wantErr   bool
+	}{
+		{
+			name:      "VerbosityTopN with topN",
+			verbosity: VerbosityTopN,
+			topN:      3,
+		},
+		{
+			name:      "VerbositySummary ignores topN",
+			verbosity: VerbositySummary,
+		},
+		{
+			name:      "negative topN",
+			verbosity: VerbosityTopN,
+			topN:      -1,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown verbosity",
+			verbosity: "Verbose",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			opt, err := WithVerbosity(tt.verbosity, tt.topN)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			s := &Store{}
+			opt(s)
+			assert.Equal(t, tt.verbosity, s.verbosity)
+			assert.Equal(t, tt.topN, s.topN)
+		})
+	}
+}
+
+func Test_summarizeFilterResult(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		filter map[string]map[string]string
+		want   string
+	}{
+		{
+			name: "aggregate failures by reason",
+			filter: map[string]map[string]string{
+				"node0": {
+					"plugin1": "node(s) were unschedulable",
+				},
+				"node1": {
+					"plugin1": "node(s) were unschedulable",
+				},
+				"node2": {
+					"plugin1": PassedFilterMessage,
+					"plugin2": "Insufficient cpu",
+				},
+				"node3": {
+					"plugin1": PassedFilterMessage,
+					"plugin2": PassedFilterMessage,
+				},
+			},
+			want: "1/4 nodes are available: 1 Insufficient cpu, 2 node(s) were unschedulable.",
+		},
+		{
+			name: "all nodes are available",
+			filter: map[string]map[string]string{
+				"node0": {
+					"plugin1": PassedFilterMessage,
+				},
+			},
+			want: "1/1 nodes are available.",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, summarizeFilterResult(tt.filter))
+		})
+	}
+}
@@ -3,11 +3,17 @@ package scheduler
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	simerrors "github.com/sanposhiho/mini-kube-scheduler/errors"
 	"github.com/sanposhiho/mini-kube-scheduler/minisched"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/queue"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
 
 	"golang.org/x/xerrors"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/events"
@@ -17,79 +23,223 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/v1beta2"
+	frameworkplugins "k8s.io/kubernetes/pkg/scheduler/framework/plugins"
 
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler/defaultconfig"
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
 )
 
 // Service manages scheduler.
 type Service struct {
 	// function to shutdown scheduler.
-	shutdownfn func()
+	shutdownfn     func()
+	evtBroadcaster events.EventBroadcaster
+
+	// mu protects sched, shutdownfn, evtBroadcaster and currentSchedulerCfg, which are replaced on restarting scheduler.
+	mu    sync.RWMutex
+	sched *minisched.Scheduler
 
 	clientset           clientset.Interface
 	restclientCfg       *restclient.Config
 	currentSchedulerCfg *v1beta2config.KubeSchedulerConfiguration
+
+	// resultOpts configures the result store of each scheduler.
+	resultOpts []resultstore.Option
 }
 
 // NewSchedulerService starts scheduler and return *Service.
-func NewSchedulerService(client clientset.Interface, restclientCfg *restclient.Config) *Service {
-	return &Service{clientset: client, restclientCfg: restclientCfg}
+// resultOpts configures how the scheduling results are recorded.
+func NewSchedulerService(client clientset.Interface, restclientCfg *restclient.Config, resultOpts ...resultstore.Option) *Service {
+	return &Service{clientset: client, restclientCfg: restclientCfg, resultOpts: resultOpts}
 }
 
-func (s *Service) RestartScheduler(cfg *v1beta2config.KubeSchedulerConfiguration) error {
-	s.ShutdownScheduler()
+// handOverTimeout is how long to wait for the binding cycles of the current scheduler on restart.
+// The binding cycles which don't finish by then are cancelled, and the pods are scheduled by the new scheduler.
+const handOverTimeout = 15 * time.Second
 
-	if err := s.StartScheduler(cfg); err != nil {
-		return xerrors.Errorf("start scheduler: %w", err)
+// RestartScheduler restarts the scheduler with cfg.
+// The pods which the current scheduler hasn't bound yet are handed over to the new scheduler,
+// and the returned HandOverResult reports them.
+// The new scheduler starts after the binding cycles of the current scheduler finish or are cancelled,
+// so that both schedulers don't bind the same pod.
+func (s *Service) RestartScheduler(cfg *v1beta2config.KubeSchedulerConfiguration) (*minisched.HandOverResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, informerFactory, err := s.newScheduler(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("create scheduler: %w", err)
 	}
-	return nil
+
+	ret := &minisched.HandOverResult{}
+	if s.sched != nil {
+		klog.Info("hand over pods to the new scheduler...")
+		s.shutdownfn()
+		s.evtBroadcaster.Shutdown()
+		ctx, cancel := context.WithTimeout(context.Background(), handOverTimeout)
+		ret = s.sched.HandOver(ctx, next)
+		cancel()
+	}
+
+	s.runScheduler(next, informerFactory, cfg)
+
+	return ret, nil
 }
 
 // StartScheduler starts scheduler.
 func (s *Service) StartScheduler(versionedcfg *v1beta2config.KubeSchedulerConfiguration) error {
-	clientSet := s.clientset
-	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	informerFactory := scheduler.NewInformerFactory(clientSet, 0)
-	evtBroadcaster := events.NewBroadcaster(&events.EventSinkImpl{
-		Interface: clientSet.EventsV1(),
-	})
+	sched, informerFactory, err := s.newScheduler(versionedcfg)
+	if err != nil {
+		return xerrors.Errorf("create scheduler: %w", err)
+	}
 
-	evtBroadcaster.StartRecordingToSink(ctx.Done())
+	s.runScheduler(sched, informerFactory, versionedcfg)
 
-	s.currentSchedulerCfg = versionedcfg.DeepCopy()
+	return nil
+}
+
+// newScheduler creates minisched with the first profile and the extenders in versionedcfg.
+// The returned error wraps simerrors.ErrInvalidSchedulerConfig when minisched can't be created with versionedcfg.
+func (s *Service) newScheduler(versionedcfg *v1beta2config.KubeSchedulerConfiguration) (*minisched.Scheduler, informers.SharedInformerFactory, error) {
+	informerFactory := scheduler.NewInformerFactory(s.clientset, 0)
+
+	var profile *v1beta2config.KubeSchedulerProfile
+	if len(versionedcfg.Profiles) != 0 {
+		// minisched supports only one profile.
+		profile = &versionedcfg.Profiles[0]
+	}
+	if err := validatePlugins(profile); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", simerrors.ErrInvalidSchedulerConfig, err)
+	}
 
+	// the results are recorded on the pods by the store, which is created for each scheduler
+	// since it watches the pods with the informer of the scheduler.
+	store := resultstore.New(informerFactory, s.clientset, nil, s.resultOpts...)
 	sched, err := minisched.New(
-		clientSet,
+		s.clientset,
 		informerFactory,
+		profile,
+		versionedcfg.Extenders,
+		minisched.WithResultRecorder(store),
 	)
 	if err != nil {
-		cancel()
-		return fmt.Errorf("create minisched: %w", err)
+		// all errors from minisched.New come from the plugins and the extenders which can't be created with the configuration.
+		return nil, nil, fmt.Errorf("%w: create minisched: %v", simerrors.ErrInvalidSchedulerConfig, err)
+	}
+
+	return sched, informerFactory, nil
+}
+
+// validatePlugins returns an error if profile enables the plugin which neither minisched nor the upstream scheduler has.
+// The upstream plugins are accepted since they're in the default configuration, and minisched ignores the ones it doesn't implement.
+func validatePlugins(profile *v1beta2config.KubeSchedulerProfile) error {
+	if profile == nil || profile.Plugins == nil {
+		return nil
+	}
+
+	registry := minisched.NewRegistry()
+	inTreeRegistry := frameworkplugins.NewInTreeRegistry()
+	pls := profile.Plugins
+	for _, set := range []v1beta2config.PluginSet{
+		pls.QueueSort, pls.PreFilter, pls.Filter, pls.PostFilter, pls.PreScore, pls.Score,
+		pls.Reserve, pls.Permit, pls.PreBind, pls.Bind, pls.PostBind,
+	} {
+		for _, p := range set.Enabled {
+			_, ok := registry[p.Name]
+			_, inTree := inTreeRegistry[p.Name]
+			if !ok && !inTree {
+				return xerrors.Errorf("unknown plugin %q", p.Name)
+			}
+		}
 	}
+	return nil
+}
+
+// runScheduler starts informers and runs sched.
+//
+// NOTE: this function assumes lock has been acquired in caller.
+func (s *Service) runScheduler(sched *minisched.Scheduler, informerFactory informers.SharedInformerFactory, versionedcfg *v1beta2config.KubeSchedulerConfiguration) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	evtBroadcaster := events.NewBroadcaster(&events.EventSinkImpl{
+		Interface: s.clientset.EventsV1(),
+	})
+
+	evtBroadcaster.StartRecordingToSink(ctx.Done())
 
 	informerFactory.Start(ctx.Done())
 	informerFactory.WaitForCacheSync(ctx.Done())
 
 	go sched.Run(ctx)
 
+	s.sched = sched
 	s.shutdownfn = cancel
-
-	return nil
+	s.evtBroadcaster = evtBroadcaster
+	s.currentSchedulerCfg = versionedcfg.DeepCopy()
 }
 
-func (s *Service) ShutdownScheduler() {
-	if s.shutdownfn != nil {
-		klog.Info("shutdown scheduler...")
-		s.shutdownfn()
+// ShutdownScheduler stops informers and the scheduler, and waits for the binding cycles in flight until ctx is done.
+// The returned error summarizes what didn't finish in time.
+func (s *Service) ShutdownScheduler(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sched == nil {
+		return nil
 	}
+
+	klog.Info("shutdown scheduler...")
+	s.shutdownfn()
+	err := s.sched.Shutdown(ctx)
+	s.evtBroadcaster.Shutdown()
+
+	s.sched = nil
+	s.shutdownfn = nil
+	s.evtBroadcaster = nil
+
+	if err != nil {
+		return xerrors.Errorf("shutdown scheduler: %w", err)
+	}
+	return nil
 }
 
 func (s *Service) GetSchedulerConfig() *v1beta2config.KubeSchedulerConfiguration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	return s.currentSchedulerCfg
 }
 
+// PendingPods returns pods in the scheduling queue of the running scheduler.
+func (s *Service) PendingPods() *queue.PendingPods {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.sched == nil {
+		return &queue.PendingPods{}
+	}
+	return s.sched.SchedulingQueue.PendingPods()
+}
+
+// WaitingPods returns pods waiting in the permit phase of the running scheduler.
+func (s *Service) WaitingPods() []*waitingpod.WaitingPod {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ret []*waitingpod.WaitingPod
+	if s.sched == nil {
+		return ret
+	}
+	s.sched.IterateOverWaitingPods(func(wp *waitingpod.WaitingPod) {
+		ret = append(ret, wp)
+	})
+	return ret
+}
+
 // convertConfigurationForSimulator convert KubeSchedulerConfiguration to apply scheduler on simulator
 // (1) It excludes non-allowed changes. Now, we accept only changes to Profiles.Plugins field.
 // (2) It replaces filter/score default-plugins with plugins for simulator.
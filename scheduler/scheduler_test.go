@@ -1,20 +1,160 @@
 package scheduler
 
 import (
+	"context"
+	"encoding/json"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
 	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config"
 	"k8s.io/kubernetes/pkg/scheduler/apis/config/scheme"
 
+	simerrors "github.com/sanposhiho/mini-kube-scheduler/errors"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
 	"github.com/sanposhiho/mini-kube-scheduler/scheduler/defaultconfig"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/annotation"
 )
 
+func TestService_recordPermitResult(t *testing.T) {
+	t.Parallel()
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", UID: "uid1"}}
+	// NodeNumber plugin makes the pod wait for 9 seconds.
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node9"}}
+	client := fake.NewSimpleClientset(pod, node)
+	ctx := context.Background()
+
+	s := NewSchedulerService(client, nil)
+	require.NoError(t, s.StartScheduler(&v1beta2config.KubeSchedulerConfiguration{}))
+	defer func() {
+		require.NoError(t, s.ShutdownScheduler(ctx))
+	}()
+
+	var wp *waitingpod.WaitingPod
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		wps := s.WaitingPods()
+		if len(wps) == 0 {
+			return false, nil
+		}
+		wp = wps[0]
+		return true, nil
+	})
+	require.NoError(t, err)
+	wp.Reject("test", "rejected by test")
+
+	// the history of the rejected pod is recorded, though the pod is removed from the waiting pods.
+	var history []waitingpod.Decision
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		p, err := client.CoreV1().Pods("default").Get(ctx, "pod1", metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		v, ok := p.Annotations[annotation.PermitResultAnnotationKey]
+		if !ok {
+			return false, nil
+		}
+		return true, json.Unmarshal([]byte(v), &history)
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "test", history[0].Plugin)
+	assert.False(t, history[0].Allowed)
+	assert.Equal(t, "rejected by test", history[0].Message)
+}
+
+func TestService_RestartScheduler_invalidConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		profile v1beta2config.KubeSchedulerProfile
+	}{
+		{
+			name: "fail with the unknown plugin",
+			profile: v1beta2config.KubeSchedulerProfile{
+				Plugins: &v1beta2config.Plugins{
+					Filter: v1beta2config.PluginSet{Enabled: []v1beta2config.Plugin{{Name: "Unknown"}}},
+				},
+			},
+		},
+		{
+			name: "fail with the invalid plugin args",
+			profile: v1beta2config.KubeSchedulerProfile{
+				PluginConfig: []v1beta2config.PluginConfig{
+					{
+						Name: "NodeNumber",
+						Args: runtime.RawExtension{Raw: []byte(`{"matchScore":1000}`)},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := NewSchedulerService(fake.NewSimpleClientset(), nil)
+			_, err := s.RestartScheduler(&v1beta2config.KubeSchedulerConfiguration{
+				Profiles: []v1beta2config.KubeSchedulerProfile{tt.profile},
+			})
+			assert.ErrorIs(t, err, simerrors.ErrInvalidSchedulerConfig)
+			assert.Nil(t, s.GetSchedulerConfig())
+		})
+	}
+}
+
+func Test_validatePlugins(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		plugins *v1beta2config.Plugins
+		wantErr bool
+	}{
+		{
+			name: "success with the default plugins",
+		},
+		{
+			name: "success with the plugins of minisched and the upstream scheduler",
+			plugins: &v1beta2config.Plugins{
+				Filter: v1beta2config.PluginSet{Enabled: []v1beta2config.Plugin{{Name: "NodeUnschedulable"}, {Name: "NodeResourcesFit"}}},
+				Score:  v1beta2config.PluginSet{Enabled: []v1beta2config.Plugin{{Name: "NodeNumber"}}},
+			},
+		},
+		{
+			name: "success with the unknown plugin disabled",
+			plugins: &v1beta2config.Plugins{
+				Filter: v1beta2config.PluginSet{Disabled: []v1beta2config.Plugin{{Name: "Unknown"}}},
+			},
+		},
+		{
+			name: "fail with the unknown plugin",
+			plugins: &v1beta2config.Plugins{
+				PostBind: v1beta2config.PluginSet{Enabled: []v1beta2config.Plugin{{Name: "Unknown"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validatePlugins(&v1beta2config.KubeSchedulerProfile{Plugins: tt.plugins})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func Test_convertConfigurationForSimulator(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/queue"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+)
+
+// QueueService exposes pods which the scheduler hasn't bound yet.
+type QueueService interface {
+	PendingPods() *queue.PendingPods
+	WaitingPods() []*waitingpod.WaitingPod
+}
+
+// QueueHandler is handler for pods which the scheduler hasn't bound yet.
+type QueueHandler struct {
+	service QueueService
+}
+
+// NewQueueHandler initializes QueueHandler.
+func NewQueueHandler(s QueueService) *QueueHandler {
+	return &QueueHandler{service: s}
+}
+
+// ListPendingPods returns pods in the scheduling queue.
+func (h *QueueHandler) ListPendingPods(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.service.PendingPods())
+}
+
+// waitingPodResponse represents a pod waiting in the permit phase.
+type waitingPodResponse struct {
+	Pod            *v1.Pod               `json:"pod"`
+	PendingPlugins []string              `json:"pendingPlugins"`
+	History        []waitingpod.Decision `json:"history"`
+}
+
+// ListWaitingPods returns pods waiting in the permit phase.
+func (h *QueueHandler) ListWaitingPods(c echo.Context) error {
+	wps := h.service.WaitingPods()
+
+	ret := make([]waitingPodResponse, 0, len(wps))
+	for _, wp := range wps {
+		ret = append(ret, waitingPodResponse{
+			Pod:            wp.GetPod(),
+			PendingPlugins: wp.GetPendingPlugins(),
+			History:        wp.GetHistory(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, ret)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+
+	simerrors "github.com/sanposhiho/mini-kube-scheduler/errors"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched"
+)
+
+// SchedulerConfigService manages the configuration of the scheduler.
+type SchedulerConfigService interface {
+	GetSchedulerConfig() *v1beta2config.KubeSchedulerConfiguration
+	RestartScheduler(cfg *v1beta2config.KubeSchedulerConfiguration) (*minisched.HandOverResult, error)
+}
+
+// SchedulerConfigHandler is handler for the configuration of the scheduler.
+type SchedulerConfigHandler struct {
+	service SchedulerConfigService
+}
+
+// NewSchedulerConfigHandler initializes SchedulerConfigHandler.
+func NewSchedulerConfigHandler(s SchedulerConfigService) *SchedulerConfigHandler {
+	return &SchedulerConfigHandler{service: s}
+}
+
+// GetSchedulerConfig returns the current KubeSchedulerConfiguration.
+func (h *SchedulerConfigHandler) GetSchedulerConfig(c echo.Context) error {
+	cfg := h.service.GetSchedulerConfig()
+
+	return c.JSON(http.StatusOK, cfg)
+}
+
+// ApplySchedulerConfig restarts the scheduler with the requested KubeSchedulerConfiguration.
+func (h *SchedulerConfigHandler) ApplySchedulerConfig(c echo.Context) error {
+	reqSchedulerCfg := new(v1beta2config.KubeSchedulerConfiguration)
+	if err := c.Bind(reqSchedulerCfg); err != nil {
+		klog.Errorf("failed to bind scheduler config request: %+v", err)
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	result, err := h.service.RestartScheduler(reqSchedulerCfg)
+	if errors.Is(err, simerrors.ErrInvalidSchedulerConfig) {
+		klog.Errorf("invalid scheduler config request: %+v", err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		klog.Errorf("failed to restart scheduler: %+v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusAccepted, applySchedulerConfigResponse{
+		RequeuedPods:        podNames(result.QueuedPods),
+		RejectedWaitingPods: podNames(result.RejectedWaitingPods),
+		// the slice is copied so that it's encoded as an empty array instead of null.
+		CancelledBindingPods: append([]string{}, result.CancelledBindingPods...),
+	})
+}
+
+// applySchedulerConfigResponse reports the pods handed over to the restarted scheduler.
+type applySchedulerConfigResponse struct {
+	// RequeuedPods are pods moved from the scheduling queue of the previous scheduler.
+	RequeuedPods []string `json:"requeuedPods"`
+	// RejectedWaitingPods are pods rejected while waiting in the permit phase, and scheduled again.
+	RejectedWaitingPods []string `json:"rejectedWaitingPods"`
+	// CancelledBindingPods are pods whose binding cycle didn't finish in time, and scheduled again.
+	CancelledBindingPods []string `json:"cancelledBindingPods"`
+}
+
+// podNames returns "<namespace>/<name>" of each pod.
+func podNames(pods []*v1.Pod) []string {
+	ret := make([]string, 0, len(pods))
+	for _, p := range pods {
+		ret = append(ret, p.Namespace+"/"+p.Name)
+	}
+	return ret
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1beta2config "k8s.io/kube-scheduler/config/v1beta2"
+
+	simerrors "github.com/sanposhiho/mini-kube-scheduler/errors"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched"
+)
+
+type fakeSchedulerConfigService struct {
+	cfg           *v1beta2config.KubeSchedulerConfiguration
+	restartErr    error
+	restartResult *minisched.HandOverResult
+}
+
+func (s *fakeSchedulerConfigService) GetSchedulerConfig() *v1beta2config.KubeSchedulerConfiguration {
+	return s.cfg
+}
+
+func (s *fakeSchedulerConfigService) RestartScheduler(cfg *v1beta2config.KubeSchedulerConfiguration) (*minisched.HandOverResult, error) {
+	if s.restartErr != nil {
+		return nil, s.restartErr
+	}
+	s.cfg = cfg
+	if s.restartResult == nil {
+		return &minisched.HandOverResult{}, nil
+	}
+	return s.restartResult, nil
+}
+
+func TestSchedulerConfigHandler_ApplySchedulerConfig(t *testing.T) {
+	t.Parallel()
+	schedulerName := "scheduler1"
+	tests := []struct {
+		name          string
+		body          string
+		restartErr    error
+		restartResult *minisched.HandOverResult
+		wantStatus    int
+		wantCfg       *v1beta2config.KubeSchedulerConfiguration
+		wantBody      string
+	}{
+		{
+			name:       "success",
+			body:       `{"profiles":[{"schedulerName":"scheduler1"}]}`,
+			wantStatus: http.StatusAccepted,
+			wantCfg: &v1beta2config.KubeSchedulerConfiguration{
+				Profiles: []v1beta2config.KubeSchedulerProfile{
+					{SchedulerName: &schedulerName},
+				},
+			},
+			wantBody: `{"requeuedPods":[],"rejectedWaitingPods":[],"cancelledBindingPods":[]}`,
+		},
+		{
+			name: "success and report the pods handed over to the new scheduler",
+			body: `{"profiles":[{"schedulerName":"scheduler1"}]}`,
+			restartResult: &minisched.HandOverResult{
+				QueuedPods: []*v1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod1"}},
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod2"}},
+				},
+				RejectedWaitingPods: []*v1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod3"}},
+				},
+				CancelledBindingPods: []string{"default/pod4"},
+			},
+			wantStatus: http.StatusAccepted,
+			wantCfg: &v1beta2config.KubeSchedulerConfiguration{
+				Profiles: []v1beta2config.KubeSchedulerProfile{
+					{SchedulerName: &schedulerName},
+				},
+			},
+			wantBody: `{"requeuedPods":["default/pod1","default/pod2"],"rejectedWaitingPods":["default/pod3"],"cancelledBindingPods":["default/pod4"]}`,
+		},
+		{
+			name:       "fail if the body is broken",
+			body:       `{"profiles":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fail with bad request if the config is invalid",
+			body:       `{"profiles":[{"plugins":{"filter":{"enabled":[{"name":"Unknown"}]}}}]}`,
+			restartErr: fmt.Errorf("create scheduler: %w", fmt.Errorf("%w: unknown plugin \"Unknown\"", simerrors.ErrInvalidSchedulerConfig)),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fail if the scheduler failed to restart",
+			body:       `{}`,
+			restartErr: errors.New("restart failed"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &fakeSchedulerConfigService{restartErr: tt.restartErr, restartResult: tt.restartResult}
+			h := NewSchedulerConfigHandler(s)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			err := h.ApplySchedulerConfig(e.NewContext(req, rec))
+			if tt.wantStatus != http.StatusAccepted {
+				var herr *echo.HTTPError
+				if assert.ErrorAs(t, err, &herr) {
+					assert.Equal(t, tt.wantStatus, herr.Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantCfg, s.GetSchedulerConfig())
+			assert.JSONEq(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1"
+	"github.com/sanposhiho/mini-kube-scheduler/minisched/waitingpod"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/annotation"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/resultstore"
+)
+
+// SchedulingResultHandler is handler for the scheduling results recorded on pods.
+type SchedulingResultHandler struct {
+	client clientset.Interface
+	// dynamicClient is used to read the results recorded as v1alpha1.SchedulingResult.
+	// It's nil when the results are recorded on the pod annotations.
+	dynamicClient dynamic.Interface
+}
+
+// NewSchedulingResultHandler initializes SchedulingResultHandler.
+// It reads the results from v1alpha1.SchedulingResult if dynamicClient is non-nil, or from the pod annotations otherwise.
+func NewSchedulingResultHandler(client clientset.Interface, dynamicClient dynamic.Interface) *SchedulingResultHandler {
+	return &SchedulingResultHandler{client: client, dynamicClient: dynamicClient}
+}
+
+// schedulingResultResponse has the scheduling result of the pod in the same form whichever output the results are recorded on.
+type schedulingResultResponse struct {
+	// node name → plugin name → filtering result
+	Filter map[string]map[string]string `json:"filter,omitempty"`
+	// node name → plugin name → score
+	Score map[string]map[string]string `json:"score,omitempty"`
+	// node name → plugin name → final score
+	FinalScore map[string]map[string]string `json:"finalScore,omitempty"`
+	// Summary has the filtering failures aggregated by reason.
+	Summary string `json:"summary,omitempty"`
+	// Permit has the allows and the reject of the pod waiting in the permit phase.
+	Permit []waitingpod.Decision `json:"permit,omitempty"`
+}
+
+// GetSchedulingResult returns the scheduling result of the pod.
+func (h *SchedulingResultHandler) GetSchedulingResult(c echo.Context) error {
+	ctx := c.Request().Context()
+	namespace, name := c.Param("namespace"), c.Param("name")
+
+	pod, err := h.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		klog.Errorf("failed to get pod %s/%s: %+v", namespace, name, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	var ret *schedulingResultResponse
+	if h.dynamicClient != nil {
+		ret, err = h.fromSchedulingResult(ctx, pod)
+	} else {
+		ret, err = fromAnnotations(pod)
+	}
+	if err != nil {
+		klog.Errorf("failed to get scheduling result of pod %s/%s: %+v", namespace, name, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, ret)
+}
+
+// fromAnnotations reads the scheduling result recorded on the pod annotations.
+func fromAnnotations(pod *v1.Pod) (*schedulingResultResponse, error) {
+	ret := &schedulingResultResponse{
+		Summary: pod.Annotations[annotation.FilterSummaryAnnotationKey],
+	}
+	for key, dst := range map[string]interface{}{
+		annotation.FilterResultAnnotationKey:     &ret.Filter,
+		annotation.ScoreResultAnnotationKey:      &ret.Score,
+		annotation.FinalScoreResultAnnotationKey: &ret.FinalScore,
+		annotation.PermitResultAnnotationKey:     &ret.Permit,
+	} {
+		v, ok := pod.Annotations[key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal([]byte(v), dst); err != nil {
+			return nil, xerrors.Errorf("decode annotation %s: %w", key, err)
+		}
+	}
+	return ret, nil
+}
+
+// fromSchedulingResult reads the scheduling result recorded as v1alpha1.SchedulingResult.
+// The result is empty if SchedulingResult doesn't exist, or it belongs to another pod with the same name.
+func (h *SchedulingResultHandler) fromSchedulingResult(ctx context.Context, pod *v1.Pod) (*schedulingResultResponse, error) {
+	ret := &schedulingResultResponse{}
+	obj, err := h.dynamicClient.Resource(v1alpha1.SchedulingResultsResource).Namespace(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ret, nil
+		}
+		return nil, xerrors.Errorf("get SchedulingResult: %w", err)
+	}
+	sr := &v1alpha1.SchedulingResult{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), sr); err != nil {
+		return nil, xerrors.Errorf("convert unstructured to SchedulingResult: %w", err)
+	}
+	if sr.Spec.PodRef.UID != pod.UID {
+		return ret, nil
+	}
+
+	set := func(m *map[string]map[string]string, node, plugin, v string) {
+		if *m == nil {
+			*m = map[string]map[string]string{}
+		}
+		if (*m)[node] == nil {
+			(*m)[node] = map[string]string{}
+		}
+		(*m)[node][plugin] = v
+	}
+	for _, n := range sr.Spec.Nodes {
+		for _, f := range n.Filter {
+			reason := resultstore.PassedFilterMessage
+			if !f.Passed {
+				reason = f.Reason
+			}
+			set(&ret.Filter, n.NodeName, f.Plugin, reason)
+		}
+		for _, s := range n.Score {
+			set(&ret.Score, n.NodeName, s.Plugin, strconv.FormatInt(s.Score, 10))
+			set(&ret.FinalScore, n.NodeName, s.Plugin, strconv.FormatInt(s.FinalScore, 10))
+		}
+	}
+	ret.Summary = sr.Spec.Summary
+	for _, p := range sr.Spec.Permit {
+		ret.Permit = append(ret.Permit, waitingpod.Decision{
+			Plugin:  p.Plugin,
+			Allowed: p.Allowed,
+			Message: p.Message,
+			Time:    p.Time.Time,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/sanposhiho/mini-kube-scheduler/apis/v1alpha1"
+	"github.com/sanposhiho/mini-kube-scheduler/scheduler/plugin/annotation"
+)
+
+func TestSchedulingResultHandler_GetSchedulingResult(t *testing.T) {
+	t.Parallel()
+	permitTime := metav1.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	schedulingResult := &v1alpha1.SchedulingResult{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"},
+		Spec: v1alpha1.SchedulingResultSpec{
+			PodRef:        v1alpha1.PodReference{Namespace: "default", Name: "pod1", UID: "uid1"},
+			FilterPlugins: []string{"plugin1"},
+			ScorePlugins:  []string{"plugin2"},
+			Nodes: []v1alpha1.NodeResult{
+				{
+					NodeName: "node0",
+					Filter:   []v1alpha1.FilterResult{{Plugin: "plugin1", Passed: true}},
+					Score:    []v1alpha1.ScoreResult{{Plugin: "plugin2", Score: 10, FinalScore: 20}},
+				},
+				{
+					NodeName: "node1",
+					Filter:   []v1alpha1.FilterResult{{Plugin: "plugin1", Reason: "node(s) were unschedulable"}},
+				},
+			},
+			Permit: []v1alpha1.PermitDecision{{Plugin: "plugin3", Allowed: true, Time: permitTime}},
+		},
+	}
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		// schedulingResults is non-nil when the results are recorded as SchedulingResult.
+		schedulingResults []runtime.Object
+		wantStatus        int
+		wantBody          string
+	}{
+		{
+			name: "success",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotation.FilterResultAnnotationKey:     `{"node0":{"plugin1":"passed"}}`,
+						annotation.ScoreResultAnnotationKey:      `{"node0":{"plugin2":"10"}}`,
+						annotation.FinalScoreResultAnnotationKey: `{"node0":{"plugin2":"20"}}`,
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"filter":{"node0":{"plugin1":"passed"}},"score":{"node0":{"plugin2":"10"}},"finalScore":{"node0":{"plugin2":"20"}}}`,
+		},
+		{
+			name: "success with summary only",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotation.FilterSummaryAnnotationKey: "0/1 nodes are available: 1 node(s) were unschedulable.",
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"summary":"0/1 nodes are available: 1 node(s) were unschedulable."}`,
+		},
+		{
+			name: "success with permit history",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotation.PermitResultAnnotationKey: `[{"plugin":"plugin3","allowed":false,"message":"rejected","time":"2021-09-01T00:00:00Z"}]`,
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"permit":[{"plugin":"plugin3","allowed":false,"message":"rejected","time":"2021-09-01T00:00:00Z"}]}`,
+		},
+		{
+			name: "success with SchedulingResult",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					UID:       "uid1",
+					// the annotations aren't read when the results are recorded as SchedulingResult.
+					Annotations: map[string]string{
+						annotation.FilterSummaryAnnotationKey: "0/1 nodes are available: 1 node(s) were unschedulable.",
+					},
+				},
+			},
+			schedulingResults: []runtime.Object{schedulingResult},
+			wantStatus:        http.StatusOK,
+			wantBody: `{
+				"filter":{"node0":{"plugin1":"passed"},"node1":{"plugin1":"node(s) were unschedulable"}},
+				"score":{"node0":{"plugin2":"10"}},
+				"finalScore":{"node0":{"plugin2":"20"}},
+				"permit":[{"plugin":"plugin3","allowed":true,"time":"2021-09-01T00:00:00Z"}]
+			}`,
+		},
+		{
+			name: "empty if SchedulingResult doesn't exist",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					UID:       "uid1",
+				},
+			},
+			schedulingResults: []runtime.Object{},
+			wantStatus:        http.StatusOK,
+			wantBody:          `{}`,
+		},
+		{
+			name: "empty if SchedulingResult belongs to the previous pod with the same name",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					UID:       "uid2",
+				},
+			},
+			schedulingResults: []runtime.Object{schedulingResult},
+			wantStatus:        http.StatusOK,
+			wantBody:          `{}`,
+		},
+		{
+			name: "fail if pod doesn't exist",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod2",
+					Namespace: "default",
+				},
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "fail if annotation is broken",
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						annotation.FilterResultAnnotationKey: `{"node0":`,
+					},
+				},
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := fake.NewSimpleClientset()
+			if _, err := c.CoreV1().Pods(tt.pod.Namespace).Create(context.Background(), tt.pod, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			var dc dynamic.Interface
+			if tt.schedulingResults != nil {
+				scheme := runtime.NewScheme()
+				if err := v1alpha1.AddToScheme(scheme); err != nil {
+					t.Fatal(err)
+				}
+				dc = dynamicfake.NewSimpleDynamicClient(scheme, tt.schedulingResults...)
+			}
+			h := NewSchedulingResultHandler(c, dc)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("namespace", "name")
+			ctx.SetParamValues("default", "pod1")
+
+			err := h.GetSchedulingResult(ctx)
+			if tt.wantStatus != http.StatusOK {
+				var herr *echo.HTTPError
+				if assert.ErrorAs(t, err, &herr) {
+					assert.Equal(t, tt.wantStatus, herr.Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.JSONEq(t, tt.wantBody, rec.Body.String())
+		})
+	}
+}
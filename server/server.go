@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/sanposhiho/mini-kube-scheduler/config"
+	"github.com/sanposhiho/mini-kube-scheduler/server/handler"
+)
+
+// SimulatorServer is the HTTP server which exposes the scheduler's state and results.
+type SimulatorServer struct {
+	e *echo.Echo
+}
+
+// SchedulerService is the scheduler which SimulatorServer exposes.
+type SchedulerService interface {
+	handler.SchedulerConfigService
+	handler.QueueService
+}
+
+// NewSimulatorServer initializes SimulatorServer.
+// dynamicClient is used to read the scheduling results recorded as v1alpha1.SchedulingResult.
+// It's nil when the scheduling results are recorded on the pod annotations.
+func NewSimulatorServer(cfg *config.Config, sched SchedulerService, client clientset.Interface, dynamicClient dynamic.Interface) *SimulatorServer {
+	e := echo.New()
+	e.HideBanner = true
+
+	e.Use(middleware.Logger())
+	e.Use(middleware.Recover())
+	if cfg.FrontendURL != "" {
+		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+			AllowOrigins: []string{cfg.FrontendURL},
+			AllowMethods: []string{http.MethodGet, http.MethodPost},
+		}))
+	}
+
+	schedulerConfigHandler := handler.NewSchedulerConfigHandler(sched)
+	queueHandler := handler.NewQueueHandler(sched)
+	schedulingResultHandler := handler.NewSchedulingResultHandler(client, dynamicClient)
+
+	v1 := e.Group("/api/v1")
+	v1.GET("/schedulerconfiguration", schedulerConfigHandler.GetSchedulerConfig)
+	v1.POST("/schedulerconfiguration", schedulerConfigHandler.ApplySchedulerConfig)
+	v1.GET("/queue", queueHandler.ListPendingPods)
+	v1.GET("/waitingpods", queueHandler.ListWaitingPods)
+	v1.GET("/namespaces/:namespace/pods/:name/schedulingresult", schedulingResultHandler.GetSchedulingResult)
+
+	return &SimulatorServer{e: e}
+}
+
+// Start starts SimulatorServer on the port in background.
+// It returns the function to shutdown SimulatorServer.
+func (s *SimulatorServer) Start(port int) func() {
+	e := s.e
+
+	go func() {
+		if err := e.Start(":" + strconv.Itoa(port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("failed to start simulator server: %+v", err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(ctx); err != nil {
+			klog.Errorf("failed to shutdown simulator server: %+v", err)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 )
 
 const (
@@ -16,11 +17,20 @@ const (
 
 // RetryWithExponentialBackOff is the utility for retrying the given function with exponential backoff.
 func RetryWithExponentialBackOff(fn wait.ConditionFunc) error {
-	backoff := wait.Backoff{
+	return wait.ExponentialBackoff(newBackoff(), fn)
+}
+
+// RetryOnErrorWithExponentialBackOff retries the given function with exponential backoff as long as retriable returns true for the error.
+// It gives up after retryBackoffSteps attempts and returns the last error.
+func RetryOnErrorWithExponentialBackOff(retriable func(error) bool, fn func() error) error {
+	return retry.OnError(newBackoff(), retriable, fn)
+}
+
+func newBackoff() wait.Backoff {
+	return wait.Backoff{
 		Duration: retryBackoffInitialDuration,
 		Factor:   retryBackoffFactor,
 		Jitter:   retryBackoffJitter,
 		Steps:    retryBackoffSteps,
 	}
-	return wait.ExponentialBackoff(backoff, fn)
 }
@@ -0,0 +1,192 @@
+package workloadcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/controller-manager/pkg/informerfactory"
+	"k8s.io/kubernetes/pkg/controller/daemon"
+	"k8s.io/kubernetes/pkg/controller/deployment"
+	"k8s.io/kubernetes/pkg/controller/garbagecollector"
+	"k8s.io/kubernetes/pkg/controller/job"
+	"k8s.io/kubernetes/pkg/controller/replicaset"
+	"k8s.io/kubernetes/pkg/controller/statefulset"
+)
+
+// The names of the controllers.
+const (
+	ReplicaSet       = "replicaset"
+	Deployment       = "deployment"
+	Job              = "job"
+	StatefulSet      = "statefulset"
+	DaemonSet        = "daemonset"
+	GarbageCollector = "garbagecollector"
+)
+
+const (
+	workers = 5
+	// burstReplicas is the number of pods the ReplicaSet controller creates or deletes at once, the same as kube-controller-manager's default.
+	burstReplicas = 500
+	// discoveryPeriod is how often the garbage collector discovers the resources with owners.
+	discoveryPeriod = 30 * time.Second
+)
+
+// AllControllers is the names of all controllers.
+var AllControllers = []string{ReplicaSet, Deployment, Job, StatefulSet, DaemonSet, GarbageCollector}
+
+// controllerContext has the clients and the informers shared by the controllers.
+type controllerContext struct {
+	client          clientset.Interface
+	metadataClient  metadata.Interface
+	informerFactory informers.SharedInformerFactory
+	// objectOrMetadataInformerFactory gives the informers of any resources for the garbage collector.
+	objectOrMetadataInformerFactory informerfactory.InformerFactory
+	// informersStarted is closed when the informers are started.
+	informersStarted chan struct{}
+	stop             <-chan struct{}
+}
+
+type startFunc func(c *controllerContext) error
+
+var startFuncs = map[string]startFunc{
+	ReplicaSet:       startReplicaSetController,
+	Deployment:       startDeploymentController,
+	Job:              startJobController,
+	StatefulSet:      startStatefulSetController,
+	DaemonSet:        startDaemonSetController,
+	GarbageCollector: startGarbageCollector,
+}
+
+// StartWorkloadControllers starts the upstream workload controllers named controllers, like kube-controller-manager does.
+// The names must be in AllControllers.
+func StartWorkloadControllers(client clientset.Interface, restclientCfg *restclient.Config, controllers []string) (
+	func(), // function to shutdown workload controllers
+	error,
+) {
+	for _, name := range controllers {
+		if _, ok := startFuncs[name]; !ok {
+			return nil, fmt.Errorf("unknown controller %q, must be one of %v", name, AllControllers)
+		}
+	}
+
+	metadataClient, err := metadata.NewForConfig(restclientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("create metadata client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	metadataInformers := metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+	c := &controllerContext{
+		client:                          client,
+		metadataClient:                  metadataClient,
+		informerFactory:                 informerFactory,
+		objectOrMetadataInformerFactory: informerfactory.NewInformerFactory(informerFactory, metadataInformers),
+		informersStarted:                make(chan struct{}),
+		stop:                            ctx.Done(),
+	}
+	for _, name := range controllers {
+		if err := startFuncs[name](c); err != nil {
+			cancel()
+			return nil, fmt.Errorf("start %s controller: %w", name, err)
+		}
+	}
+
+	// the informers requested by the controllers are started.
+	informerFactory.Start(ctx.Done())
+	metadataInformers.Start(ctx.Done())
+	close(c.informersStarted)
+
+	return cancel, nil
+}
+
+func startReplicaSetController(c *controllerContext) error {
+	go replicaset.NewReplicaSetController(
+		c.informerFactory.Apps().V1().ReplicaSets(),
+		c.informerFactory.Core().V1().Pods(),
+		c.client,
+		burstReplicas,
+	).Run(workers, c.stop)
+	return nil
+}
+
+func startDeploymentController(c *controllerContext) error {
+	dc, err := deployment.NewDeploymentController(
+		c.informerFactory.Apps().V1().Deployments(),
+		c.informerFactory.Apps().V1().ReplicaSets(),
+		c.informerFactory.Core().V1().Pods(),
+		c.client,
+	)
+	if err != nil {
+		return fmt.Errorf("construct deployment controller: %w", err)
+	}
+	go dc.Run(workers, c.stop)
+	return nil
+}
+
+func startJobController(c *controllerContext) error {
+	go job.NewController(
+		c.informerFactory.Core().V1().Pods(),
+		c.informerFactory.Batch().V1().Jobs(),
+		c.client,
+	).Run(workers, c.stop)
+	return nil
+}
+
+func startStatefulSetController(c *controllerContext) error {
+	go statefulset.NewStatefulSetController(
+		c.informerFactory.Core().V1().Pods(),
+		c.informerFactory.Apps().V1().StatefulSets(),
+		c.informerFactory.Core().V1().PersistentVolumeClaims(),
+		c.informerFactory.Apps().V1().ControllerRevisions(),
+		c.client,
+	).Run(workers, c.stop)
+	return nil
+}
+
+func startDaemonSetController(c *controllerContext) error {
+	dsc, err := daemon.NewDaemonSetsController(
+		c.informerFactory.Apps().V1().DaemonSets(),
+		c.informerFactory.Apps().V1().ControllerRevisions(),
+		c.informerFactory.Core().V1().Pods(),
+		c.informerFactory.Core().V1().Nodes(),
+		c.client,
+		flowcontrol.NewBackOff(1*time.Second, 15*time.Minute),
+	)
+	if err != nil {
+		return fmt.Errorf("construct daemonset controller: %w", err)
+	}
+	go dsc.Run(workers, c.stop)
+	return nil
+}
+
+// startGarbageCollector starts the garbage collector, which deletes the objects whose owners are deleted,
+// e.g. the ReplicaSets of the deleted Deployment.
+func startGarbageCollector(c *controllerContext) error {
+	// the mapper is reset by Sync when the resources change.
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.client.Discovery()))
+	gc, err := garbagecollector.NewGarbageCollector(
+		c.client,
+		c.metadataClient,
+		restMapper,
+		garbagecollector.DefaultIgnoredResources(),
+		c.objectOrMetadataInformerFactory,
+		c.informersStarted,
+	)
+	if err != nil {
+		return fmt.Errorf("construct garbage collector: %w", err)
+	}
+	go gc.Run(workers, c.stop)
+	// the resources with owners may be added later, e.g. with CustomResourceDefinitions.
+	go gc.Sync(c.client.Discovery(), discoveryPeriod, c.stop)
+	return nil
+}
@@ -0,0 +1,88 @@
+package workloadcontroller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+
+	"github.com/sanposhiho/mini-kube-scheduler/k8sapiserver"
+)
+
+func TestStartWorkloadControllers(t *testing.T) {
+	t.Parallel()
+	cfg, apiShutdown, err := k8sapiserver.StartAPIServer(k8sapiserver.Options{})
+	require.NoError(t, err)
+	defer apiShutdown()
+	client := clientset.NewForConfigOrDie(cfg)
+	ctx := context.Background()
+
+	shutdown, err := StartWorkloadControllers(client, cfg, AllControllers)
+	require.NoError(t, err)
+	defer shutdown()
+
+	labels := map[string]string{"app": "app1"}
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "container1", Image: "k8s.gcr.io/pause:3.5"}}},
+	}
+	podsOwnedBy := func(kind string) int {
+		pods, err := client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return 0
+		}
+		n := 0
+		for _, p := range pods.Items {
+			for _, o := range p.OwnerReferences {
+				if o.Kind == kind {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	_, err = client.AppsV1().Deployments("default").Create(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment1"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32Ptr(2),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: template,
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return podsOwnedBy("ReplicaSet") == 2 }, 10*time.Second, 100*time.Millisecond)
+
+	jobTemplate := *template.DeepCopy()
+	jobTemplate.Labels = nil
+	jobTemplate.Spec.RestartPolicy = v1.RestartPolicyNever
+	_, err = client.BatchV1().Jobs("default").Create(ctx, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "job1"},
+		Spec:       batchv1.JobSpec{Parallelism: pointer.Int32Ptr(3), Completions: pointer.Int32Ptr(3), Template: jobTemplate},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return podsOwnedBy("Job") == 3 }, 10*time.Second, 100*time.Millisecond)
+
+	// the garbage collector deletes the ReplicaSet of the deleted Deployment.
+	propagation := metav1.DeletePropagationBackground
+	require.NoError(t, client.AppsV1().Deployments("default").Delete(ctx, "deployment1", metav1.DeleteOptions{PropagationPolicy: &propagation}))
+	assert.Eventually(t, func() bool {
+		rss, err := client.AppsV1().ReplicaSets("default").List(ctx, metav1.ListOptions{})
+		return err == nil && len(rss.Items) == 0
+	}, 30*time.Second, 100*time.Millisecond)
+}
+
+func TestStartWorkloadControllers_unknownController(t *testing.T) {
+	t.Parallel()
+	_, err := StartWorkloadControllers(nil, nil, []string{ReplicaSet, "cronjob"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"cronjob"`)
+}